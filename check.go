@@ -0,0 +1,363 @@
+package goexp
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/svstanev/goexp/types"
+)
+
+// TypeError is a type mismatch found by Check. Pos and End are the span (in
+// runes) of the nearest operator enclosing the node; both are -1 if unknown.
+type TypeError struct {
+	Pos, End int
+	Message  string
+}
+
+func (e TypeError) Error() string {
+	if e.Pos < 0 {
+		return fmt.Sprintf("Type Error: %s", e.Message)
+	}
+	return fmt.Sprintf("Type Error at pos %d: %s", e.Pos, e.Message)
+}
+
+// TypeErrors are all the mismatches found by Check, in the order of evaluation
+type TypeErrors []TypeError
+
+func (errs TypeErrors) Error() string {
+	msgs := make([]string, len(errs))
+	for i, err := range errs {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+/*
+Check infers the type of the expression from the types of the names and
+methods declared by the schema and returns it along with TypeErrors listing
+every operation, name or call that would fail when evaluated.
+
+The result types of the operators are those of the operations in the types
+package, e.g. Integer + Float is Float and String + Integer is String. Nodes
+that have a type error are Any, so the mismatches do not cascade. Use
+AssignableTo to require a type, e.g. that a filter is Boolean:
+
+	t, err := goexp.Check(expr, schema)
+	if err == nil && !t.AssignableTo(types.BooleanType) {
+		...
+	}
+*/
+func Check(expr Expr, schema *types.Schema) (*types.Type, error) {
+	c := &checker{root: schema.Type()}
+	t := c.check(expr)
+	if len(c.errors) > 0 {
+		return t, c.errors
+	}
+	return t, nil
+}
+
+type checker struct {
+	root *types.Type
+	// span is the nearest operator enclosing the node being checked
+	span   Token
+	errors TypeErrors
+}
+
+func (c *checker) check(expr Expr) *types.Type {
+	res, _ := expr.Accept(c, nil)
+	if t, ok := res.(*types.Type); ok {
+		return t
+	}
+	return types.AnyType
+}
+
+// within makes op the span of the errors until the returned func is called
+func (c *checker) within(op Token) func() {
+	outer := c.span
+	c.span = op
+	return func() { c.span = outer }
+}
+
+func (c *checker) errorf(format string, args ...interface{}) (interface{}, error) {
+	err := TypeError{Pos: -1, End: -1, Message: fmt.Sprintf(format, args...)}
+	if c.span.Lexeme != "" {
+		err.Pos = c.span.Pos
+		err.End = c.span.Pos + utf8.RuneCountInString(c.span.Lexeme)
+	}
+	c.errors = append(c.errors, err)
+	return types.AnyType, nil
+}
+
+func (c *checker) VisitStringLiteralExpr(e StringLiteralExpr, context VisitorContext) (interface{}, error) {
+	return types.StringType, nil
+}
+
+func (c *checker) VisitIntegerLiteralExpr(e IntegerLiteralExpr, context VisitorContext) (interface{}, error) {
+	return types.IntegerType, nil
+}
+
+func (c *checker) VisitFloatLiteralExpr(e FloatLiteralExpr, context VisitorContext) (interface{}, error) {
+	return types.FloatType, nil
+}
+
+func (c *checker) VisitBooleanLiteralExpr(e BooleanLiteralExpr, context VisitorContext) (interface{}, error) {
+	return types.BooleanType, nil
+}
+
+func (c *checker) VisitNilLiteralExpr(e NilLiteralExpr, context VisitorContext) (interface{}, error) {
+	return types.NilType, nil
+}
+
+func (c *checker) VisitGroupingExpr(e GroupingExpr, context VisitorContext) (interface{}, error) {
+	return c.check(e.Expr), nil
+}
+
+// VisitBinaryExpr applies the operator to samples of the operand types; the
+// operation is valid if it succeeds for all samples, or for some if either
+// operand is Any
+func (c *checker) VisitBinaryExpr(e BinaryExpr, context VisitorContext) (interface{}, error) {
+	defer c.within(e.Operator)()
+	x := c.check(e.Left)
+	y := c.check(e.Right)
+
+	var results []*types.Type
+	failed := false
+	for _, a := range x.Samples() {
+		for _, b := range y.Samples() {
+			if v, err := binaryOp(a, b, e.Operator); err == nil {
+				results = append(results, types.TypeOf(v))
+			} else {
+				failed = true
+			}
+		}
+	}
+	if len(results) == 0 || failed && x.Kind != types.AnyKind && y.Kind != types.AnyKind {
+		return c.errorf(`Operation "%s" not supported for %s and %s`, ops[e.Operator.Type], x, y)
+	}
+	return types.Union(results...), nil
+}
+
+func (c *checker) VisitUnaryExpr(e UnaryExpr, context VisitorContext) (interface{}, error) {
+	defer c.within(e.Operator)()
+	x := c.check(e.Value)
+
+	var results []*types.Type
+	failed := false
+	for _, a := range x.Samples() {
+		if v, err := unaryOp(a, e.Operator); err == nil {
+			results = append(results, types.TypeOf(v))
+		} else {
+			failed = true
+		}
+	}
+	if len(results) == 0 || failed && x.Kind != types.AnyKind {
+		return c.errorf(`Operation "%s" not supported for %s`, ops[e.Operator.Type], x)
+	}
+	return types.Union(results...), nil
+}
+
+func (c *checker) VisitIdentifierExpr(e IdentifierExpr, context VisitorContext) (interface{}, error) {
+	recv := c.root
+	if e.Expr != nil {
+		recv = c.check(e.Expr)
+	}
+	if t, ok := recv.Field(e.Name); ok {
+		return t, nil
+	}
+	switch {
+	case e.Expr == nil:
+		return c.errorf("%s not defined", e.Name)
+	case recv.Kind == types.ObjectKind:
+		return c.errorf("%s has no field %s", recv, e.Name)
+	default:
+		return c.errorf("Cannot access %s of %s", e.Name, recv)
+	}
+}
+
+// VisitLambdaExpr checks the body of the lambda with its parameter of type Any;
+// lambdas themselves are Any as well
+func (c *checker) VisitLambdaExpr(e LambdaExpr, context VisitorContext) (interface{}, error) {
+	c.checkScoped(e.Param, types.AnyType, e.Body)
+	return types.AnyType, nil
+}
+
+// checkScoped checks the expression with the root extended by the name of the given type
+func (c *checker) checkScoped(name string, t *types.Type, expr Expr) *types.Type {
+	fields := map[string]*types.Type{name: t}
+	for n, t := range c.root.Fields {
+		if n != name {
+			fields[n] = t
+		}
+	}
+	outer := c.root
+	c.root = types.ObjectOf(fields, outer.Methods)
+	defer func() { c.root = outer }()
+
+	return c.check(expr)
+}
+
+// VisitLetExpr checks the body with the name of the type of the value
+func (c *checker) VisitLetExpr(e LetExpr, context VisitorContext) (interface{}, error) {
+	value := c.check(e.Value)
+	return c.checkScoped(e.Name, value, e.Body), nil
+}
+
+// VisitTemplateExpr checks that the parts can be converted to strings
+func (c *checker) VisitTemplateExpr(e TemplateExpr, context VisitorContext) (interface{}, error) {
+	for _, part := range e.Parts {
+		if t := c.check(part); t.Kind == types.ObjectKind || t.Kind == types.MapKind {
+			c.errorf("Cannot convert %s to String", t)
+		}
+	}
+	return types.StringType, nil
+}
+
+func (c *checker) VisitNamedArgExpr(e NamedArgExpr, context VisitorContext) (interface{}, error) {
+	c.check(e.Value)
+	return c.errorf("Unexpected named argument %s outside of a call", e.Name)
+}
+
+func (c *checker) VisitCallExpr(e CallExpr, context VisitorContext) (interface{}, error) {
+	id, ok := e.Name.(IdentifierExpr)
+	if !ok {
+		return c.errorf("Expected IdentifierExpr")
+	}
+
+	recv := c.root
+	if id.Expr != nil {
+		recv = c.check(id.Expr)
+	}
+	var args []*types.Type
+	var named []namedType
+	for _, arg := range e.Args {
+		if n, ok := arg.(NamedArgExpr); ok {
+			named = append(named, namedType{n.Name, c.check(n.Value)})
+		} else {
+			args = append(args, c.check(arg))
+		}
+	}
+
+	if recv.Kind == types.AnyKind {
+		return types.AnyType, nil
+	}
+	if recv.Kind != types.ObjectKind {
+		// x.m(args) is m(x, args) for values that are not objects
+		args = append([]*types.Type{recv}, args...)
+		recv = c.root
+	}
+	m, ok := recv.Method(id.Name)
+	if !ok {
+		return c.errorf("Method not found %s", id.Name)
+	}
+
+	if len(m.Overloads) > 0 {
+		return c.checkOverloads(id.Name, m, args, named)
+	}
+	positional := len(args)
+	args, err := bindTypes(m, args, named)
+	if err != nil {
+		return c.errorf("%s of %s", err, id.Name)
+	}
+	if !m.Accepts(len(args)) {
+		n := len(m.Params)
+		switch {
+		case m.Variadic:
+			return c.errorf("Invalid number of arguments for %s: expected at least %d but got %d", id.Name, m.MinArgs(), len(args))
+		case m.Optional > 0:
+			return c.errorf("Invalid number of arguments for %s: expected %d to %d but got %d", id.Name, m.MinArgs(), n, len(args))
+		}
+		return c.errorf("Invalid number of arguments for %s: expected %d but got %d", id.Name, n, len(args))
+	}
+	for i, arg := range args {
+		if p := m.Param(i); !arg.AssignableTo(p) {
+			if i >= positional {
+				c.errorf("Cannot use %s as %s in argument %s of %s", arg, p, m.Names[i], id.Name)
+			} else {
+				c.errorf("Cannot use %s as %s in argument %d of %s", arg, p, i+1, id.Name)
+			}
+		}
+	}
+	return m.Result(), nil
+}
+
+// checkOverloads returns the result of the first signature of the method that accepts the arguments
+func (c *checker) checkOverloads(name string, m *types.Signature, args []*types.Type, named []namedType) (interface{}, error) {
+	for _, sig := range append([]*types.Signature{m}, m.Overloads...) {
+		if bound, err := bindTypes(sig, args, named); err == nil && accepts(sig, bound) {
+			return sig.Result(), nil
+		}
+	}
+	names := make([]string, 0, len(args)+len(named))
+	for _, arg := range args {
+		names = append(names, arg.String())
+	}
+	for _, arg := range named {
+		names = append(names, arg.name+": "+arg.t.String())
+	}
+	return c.errorf("No overload of %s accepts (%s)", name, strings.Join(names, ", "))
+}
+
+type namedType struct {
+	name string
+	t    *types.Type
+}
+
+// bindTypes returns the types of the positional arguments with the named
+// arguments in the positions of their parameters; the omitted optional
+// parameters before them get their declared types
+func bindTypes(sig *types.Signature, args []*types.Type, named []namedType) ([]*types.Type, error) {
+	if len(named) == 0 {
+		return args, nil
+	}
+	if len(sig.Names) == 0 {
+		// the parameters are unknown
+		return args, nil
+	}
+
+	n := len(sig.Params)
+	if sig.Variadic {
+		n--
+	}
+	res := append([]*types.Type(nil), args...)
+	for _, arg := range named {
+		i := -1
+		for j := 0; j < n && j < len(sig.Names); j++ {
+			if sig.Names[j] == arg.name {
+				i = j
+			}
+		}
+		switch {
+		case i < 0:
+			return nil, fmt.Errorf("Unknown argument %s", arg.name)
+		case i < len(res) && res[i] != nil:
+			return nil, fmt.Errorf("Duplicate argument %s", arg.name)
+		}
+		for len(res) <= i {
+			res = append(res, nil)
+		}
+		res[i] = arg.t
+	}
+	for i, t := range res {
+		switch {
+		case t != nil:
+		case i >= sig.MinArgs():
+			res[i] = sig.Param(i)
+		default:
+			return nil, fmt.Errorf("Missing argument %s", sig.Names[i])
+		}
+	}
+	return res, nil
+}
+
+func accepts(sig *types.Signature, args []*types.Type) bool {
+	if !sig.Accepts(len(args)) {
+		return false
+	}
+	for i, arg := range args {
+		if !arg.AssignableTo(sig.Param(i)) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,153 @@
+package goexp
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/svstanev/goexp/types"
+)
+
+func TestCheck(t *testing.T) {
+	address := types.ObjectOf(map[string]*types.Type{"city": types.StringType}, nil)
+	schema := &types.Schema{
+		Names: map[string]*types.Type{
+			"age":   types.IntegerType,
+			"score": types.FloatType,
+			"name":  types.StringType,
+			"admin": types.BooleanType,
+			"born":  types.DateType,
+			"data":  types.AnyType,
+			"tags":  types.ListOf(types.StringType),
+			"users": types.ListOf(types.ObjectOf(map[string]*types.Type{"address": address}, nil)),
+			"attrs": types.MapOf(types.IntegerType),
+			"user": types.ObjectOf(map[string]*types.Type{
+				"address": address,
+			}, map[string]*types.Signature{
+				"hasRole": {Params: []*types.Type{types.StringType}, Returns: types.BooleanType},
+			}),
+		},
+		Methods: map[string]*types.Signature{
+			"max": {Params: []*types.Type{types.NumberType, types.NumberType}, Variadic: true, Returns: types.NumberType},
+			"len": {Params: []*types.Type{types.StringType}, Returns: types.IntegerType},
+		},
+	}
+
+	tests := []struct {
+		expr     string
+		expected string
+		errs     TypeErrors
+	}{
+		{"1 + 2", "Integer", nil},
+		{"age + score", "Float", nil},
+		{"age / 2", "Integer", nil},
+		{"name + age", "String", nil},
+		{"age > 18 && admin", "Boolean", nil},
+		{"!(score >= 1.5)", "Boolean", nil},
+		{"-age", "Integer", nil},
+		{"born + 1", "Date", nil},
+		{"nil", "Nil", nil},
+		{"name == nil", "Boolean", nil},
+		{"user.address.city + '!'", "String", nil},
+		{"attrs.width * 2", "Integer", nil},
+		{"max(age, score, 1)", "Number", nil},
+		{"max(age) + 1", "Number", nil},
+		{"user.hasRole('admin') || admin", "Boolean", nil},
+		{"len(user.address.city)", "Integer", nil},
+		{"data.foo.bar(1) + 1", "Any", nil},
+		{"data > 1", "Boolean", nil},
+		{"data + 1", "Any", nil},
+		{"users.address.city", "List<String>", nil},
+		{"let n = name.len(); let age = n > 3; age && admin", "Boolean", nil},
+		{"len(name => name + 1)", "Integer", nil},
+		{"`${name} is ${age}`.len()", "Integer", nil},
+
+		{"'abc' - 1", "Any", TypeErrors{{6, 7, `Operation "-" not supported for String and Integer`}}},
+		{"age > 'x'", "Any", TypeErrors{{4, 5, `Operation ">" not supported for Integer and String`}}},
+		{"admin + 1 > 2", "Boolean", TypeErrors{{6, 7, `Operation "+" not supported for Boolean and Integer`}}},
+		{"-name", "Any", TypeErrors{{0, 1, `Operation "-" not supported for String`}}},
+		{"born > born", "Boolean", nil},
+		{"born - born", "Duration", nil},
+		{"born * 2", "Any", TypeErrors{{5, 6, `Operation "*" not supported for Date and Integer`}}},
+		{"tags + 1", "Any", TypeErrors{{5, 6, `Operation "+" not supported for List<String> and Integer`}}},
+		{"weight > 1", "Boolean", TypeErrors{{7, 8, "weight not defined"}}},
+		{"user.address.zip", "Any", TypeErrors{{-1, -1, "Object{city} has no field zip"}}},
+		{"tags.first", "Any", TypeErrors{{-1, -1, "Cannot access first of List<String>"}}},
+		{"let n = age; n - name", "Any", TypeErrors{{15, 16, `Operation "-" not supported for Integer and String`}}},
+		{"`${user.address}` + `${age - name}`", "String", TypeErrors{
+			{18, 19, "Cannot convert Object{city} to String"},
+			{27, 28, `Operation "-" not supported for Integer and String`},
+		}},
+		{"users.zip", "Any", TypeErrors{{-1, -1, "Cannot access zip of List<Object{address}>"}}},
+		{"max(1, x => name - x)", "Number", TypeErrors{{17, 18, `Operation "-" not supported for String and Any`}}},
+		{"min(1, 2)", "Any", TypeErrors{{-1, -1, "Method not found min"}}},
+		{"name.len()", "Integer", nil},
+		{"user.address.city.len() > 0", "Boolean", nil},
+		{"age.len()", "Integer", TypeErrors{{-1, -1, "Cannot use Integer as String in argument 1 of len"}}},
+		{"name.len(1)", "Any", TypeErrors{{-1, -1, "Invalid number of arguments for len: expected 1 but got 2"}}},
+		{"user.len()", "Any", TypeErrors{{-1, -1, "Method not found len"}}},
+		{"len(name, 1)", "Any", TypeErrors{{-1, -1, "Invalid number of arguments for len: expected 1 but got 2"}}},
+		{"max()", "Any", TypeErrors{{-1, -1, "Invalid number of arguments for max: expected at least 1 but got 0"}}},
+		{"1 + len(age)", "Integer", TypeErrors{{2, 3, "Cannot use Integer as String in argument 1 of len"}}},
+		{"max(1, 'a', admin)", "Number", TypeErrors{
+			{-1, -1, "Cannot use String as Number in argument 2 of max"},
+			{-1, -1, "Cannot use Boolean as Number in argument 3 of max"},
+		}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.expr, func(t *testing.T) {
+			expr, err := Parse(test.expr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			res, err := Check(expr, schema)
+			if res.String() != test.expected {
+				t.Errorf("Expected type %s but got %s", test.expected, res)
+			}
+			var errs TypeErrors
+			if err != nil {
+				errs = err.(TypeErrors)
+			}
+			if !reflect.DeepEqual(errs, test.errs) {
+				t.Errorf("Expected errors %#v but got %#v", test.errs, errs)
+			}
+		})
+	}
+}
+
+func TestCheckFilter(t *testing.T) {
+	schema := &types.Schema{Names: map[string]*types.Type{"age": types.IntegerType}}
+	tests := []struct {
+		expr    string
+		boolean bool
+	}{
+		{"age > 18", true},
+		{"age + 18", false},
+		{"(age)", false},
+	}
+
+	for _, test := range tests {
+		expr, _ := Parse(test.expr)
+		res, err := Check(expr, schema)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.AssignableTo(types.BooleanType) != test.boolean {
+			t.Errorf("%s: expected Boolean %v but got %s", test.expr, test.boolean, res)
+		}
+	}
+}
+
+func TestCheckErrorPosition(t *testing.T) {
+	expr, _ := Parse("1 + (2 * 'x')")
+	_, err := Check(expr, nil)
+	if err == nil {
+		t.Fatal("Expected an error")
+	}
+	if pos, ok := ErrorPosition(err); !ok || pos != 7 {
+		t.Errorf("Expected position 7 but got %d", pos)
+	}
+	if s := err.Error(); s != `Type Error at pos 7: Operation "*" not supported for Integer and String` {
+		t.Errorf("Unexpected error %s", s)
+	}
+}
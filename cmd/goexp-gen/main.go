@@ -0,0 +1,84 @@
+/*
+Command goexp-gen generates Go functions from the rules in a file.
+
+Usage:
+
+	goexp-gen -in rules.gx -out rules.go -pkg rules -schema age=int64,name=string
+
+Every line of the input file is a rule in the form "Name = expression"; empty
+lines and lines starting with # are ignored. It's meant to be used with go:generate:
+
+	//go:generate goexp-gen -in rules.gx -out rules_gen.go -pkg rules -schema age=int64
+*/
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/svstanev/goexp/codegen"
+)
+
+func main() {
+	in := flag.String("in", "", "the file with the rules")
+	out := flag.String("out", "", "the generated Go file (default stdout)")
+	pkg := flag.String("pkg", os.Getenv("GOPACKAGE"), "the package of the generated file")
+	schema := flag.String("schema", "", "the comma separated input names and their Go types, e.g. age=int64,name=string")
+	flag.Parse()
+
+	if err := run(*in, *out, *pkg, *schema); err != nil {
+		fmt.Fprintln(os.Stderr, "goexp-gen:", err)
+		os.Exit(1)
+	}
+}
+
+func run(in, out, pkg, schema string) error {
+	if in == "" || pkg == "" {
+		return fmt.Errorf("-in and -pkg are required")
+	}
+
+	s, err := parseSchema(schema)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Open(in)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	rules, err := codegen.ParseRules(f)
+	if err != nil {
+		return fmt.Errorf("%s: %v", in, err)
+	}
+
+	src, err := codegen.Generate(pkg, rules, s)
+	if err != nil {
+		return err
+	}
+
+	if out == "" {
+		_, err = os.Stdout.Write(src)
+		return err
+	}
+	return ioutil.WriteFile(out, src, 0644)
+}
+
+func parseSchema(s string) (codegen.Schema, error) {
+	schema := make(codegen.Schema)
+	if s == "" {
+		return schema, nil
+	}
+	for _, field := range strings.Split(s, ",") {
+		parts := strings.SplitN(field, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("Invalid schema field %q, expected name=type", field)
+		}
+		schema[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+	}
+	return schema, nil
+}
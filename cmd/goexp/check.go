@@ -0,0 +1,159 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/svstanev/goexp"
+)
+
+// policy restricts the names and methods an expression may use
+type policy struct {
+	names   []string
+	methods map[string][]int
+}
+
+// parsePolicy parses the comma separated allowed names ("a", "user.name", "user.*")
+// and methods ("len", "max/2"); nil slices allow everything
+func parsePolicy(names, methods string) (*policy, error) {
+	p := &policy{}
+	if names != "" {
+		for _, name := range strings.Split(names, ",") {
+			p.names = append(p.names, strings.TrimSpace(name))
+		}
+	}
+	if methods != "" {
+		p.methods = map[string][]int{}
+		for _, m := range strings.Split(methods, ",") {
+			parts := strings.SplitN(strings.TrimSpace(m), "/", 2)
+			arity := -1
+			if len(parts) == 2 {
+				n, err := strconv.Atoi(parts[1])
+				if err != nil || n < 0 {
+					return nil, fmt.Errorf("invalid method %q, expected name or name/arity", m)
+				}
+				arity = n
+			}
+			p.methods[parts[0]] = append(p.methods[parts[0]], arity)
+		}
+	}
+	return p, nil
+}
+
+func (p *policy) allowsName(name string) bool {
+	if p.names == nil {
+		return true
+	}
+	for _, allowed := range p.names {
+		if name == allowed {
+			return true
+		}
+		if strings.HasSuffix(allowed, ".*") && strings.HasPrefix(name, strings.TrimSuffix(allowed, "*")) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *policy) allowsMethod(m goexp.MethodDep) bool {
+	if p.methods == nil {
+		return true
+	}
+	for _, arity := range p.methods[m.Name] {
+		if arity < 0 || arity == m.Arity {
+			return true
+		}
+	}
+	return false
+}
+
+// violations returns the names and methods of the expression the policy doesn't allow
+func (p *policy) violations(expr goexp.Expr) []string {
+	var res []string
+	deps := goexp.Dependencies(expr)
+	for _, name := range deps.Names {
+		if !p.allowsName(name) {
+			res = append(res, fmt.Sprintf("name %s is not allowed", name))
+		}
+	}
+	for _, m := range deps.Methods {
+		if !p.allowsMethod(m) {
+			res = append(res, fmt.Sprintf("method %s/%d is not allowed", m.Name, m.Arity))
+		}
+	}
+	return res
+}
+
+type checkError struct {
+	Kind    string `json:"kind"`
+	Message string `json:"message"`
+	Pos     *int   `json:"pos,omitempty"`
+}
+
+type checkResult struct {
+	Expr   string       `json:"expr"`
+	OK     bool         `json:"ok"`
+	Errors []checkError `json:"errors,omitempty"`
+}
+
+func runCheck(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("check", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	names := fs.String("names", "", "the comma separated allowed `names`, e.g. age,user.*")
+	methods := fs.String("methods", "", "the comma separated allowed `methods` with optional arity, e.g. len,max/2")
+	asJSON := fs.Bool("json", false, "write the results as JSON")
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return exitOK
+		}
+		return exitUsage
+	}
+	p, err := parsePolicy(*names, *methods)
+	if err != nil {
+		fmt.Fprintln(stderr, "goexp check:", err)
+		return exitUsage
+	}
+
+	syntax, violations := false, false
+	err = readInputs(fs.Args(), stdin, func(in input) {
+		if in.skip {
+			return
+		}
+
+		res := checkResult{Expr: in.source}
+		if expr, err := goexp.Parse(in.source); err != nil {
+			s := newSyntaxError(err)
+			res.Errors = append(res.Errors, checkError{Kind: "syntax", Message: s.Message, Pos: s.Pos})
+			syntax = true
+		} else {
+			for _, v := range p.violations(expr) {
+				res.Errors = append(res.Errors, checkError{Kind: "policy", Message: v})
+				violations = true
+			}
+		}
+		res.OK = len(res.Errors) == 0
+
+		if *asJSON {
+			writeJSON(stdout, res)
+			return
+		}
+		for _, e := range res.Errors {
+			fmt.Fprintf(stdout, "%s: %s error: %s\n", in.label, e.Kind, e.Message)
+		}
+	})
+	if err != nil {
+		fmt.Fprintln(stderr, "goexp check:", err)
+		return exitError
+	}
+
+	switch {
+	case syntax:
+		return exitSyntax
+	case violations:
+		return exitPolicy
+	}
+	return exitOK
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/svstanev/goexp"
+)
+
+type evalResult struct {
+	Value   interface{}        `json:"value"`
+	Error   string             `json:"error,omitempty"`
+	Syntax  *syntaxError       `json:"syntax,omitempty"`
+	Explain *goexp.ExplainTree `json:"explain,omitempty"`
+}
+
+func runEval(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("eval", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	var varList varFlags
+	fs.Var(&varList, "var", "a variable as `name=value`, may be repeated")
+	varsFile := fs.String("vars", "", "a JSON `file` with the variables, - for the standard input")
+	each := fs.Bool("each", false, "evaluate for every line of JSON variables in the standard input")
+	explain := fs.Bool("explain", false, "explain the result")
+	asJSON := fs.Bool("json", false, "write the result as JSON")
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return exitOK
+		}
+		return exitUsage
+	}
+	if fs.NArg() != 1 {
+		fmt.Fprintln(stderr, "goexp eval: expected a single expression")
+		return exitUsage
+	}
+
+	stdinUses := 0
+	source := fs.Arg(0)
+	for _, used := range []bool{source == "-", *varsFile == "-", *each} {
+		if used {
+			stdinUses++
+		}
+	}
+	if stdinUses > 1 {
+		fmt.Fprintln(stderr, "goexp eval: the standard input can be used only once")
+		return exitUsage
+	}
+
+	if source == "-" {
+		data, err := ioutil.ReadAll(stdin)
+		if err != nil {
+			fmt.Fprintln(stderr, "goexp eval:", err)
+			return exitError
+		}
+		source = string(data)
+	}
+
+	expr, err := goexp.Parse(source)
+	if err != nil {
+		if *asJSON {
+			writeJSON(stdout, evalResult{Error: err.Error(), Syntax: newSyntaxError(err)})
+		} else {
+			fmt.Fprintln(stderr, "goexp eval:", err)
+		}
+		return exitSyntax
+	}
+
+	base, err := loadVars(*varsFile, varList, stdin)
+	if err != nil {
+		fmt.Fprintln(stderr, "goexp eval:", err)
+		return exitUsage
+	}
+
+	e := &evaluation{expr: expr, explain: *explain, json: *asJSON, stdout: stdout, stderr: stderr}
+	if !*each {
+		return e.run(base)
+	}
+
+	code := exitOK
+	scanner := bufio.NewScanner(stdin)
+	scanner.Buffer(nil, 1<<24)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		v, err := decodeVars([]byte(line))
+		if err == nil {
+			all := vars{}
+			all.merge(base)
+			all.merge(v)
+			if c := e.run(all); c != exitOK {
+				code = c
+			}
+			continue
+		}
+		e.fail(err)
+		code = exitError
+	}
+	if err := scanner.Err(); err != nil {
+		fmt.Fprintln(stderr, "goexp eval:", err)
+		return exitError
+	}
+	return code
+}
+
+// loadVars returns the variables of the JSON file overridden by the --var flags
+func loadVars(file string, flags varFlags, stdin io.Reader) (vars, error) {
+	res := vars{}
+	if file != "" {
+		var r io.Reader = stdin
+		if file != "-" {
+			f, err := os.Open(file)
+			if err != nil {
+				return nil, err
+			}
+			defer f.Close()
+			r = f
+		}
+		v, err := readVars(r)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", file, err)
+		}
+		res.merge(v)
+	}
+
+	v, err := parseVarFlags(flags)
+	if err != nil {
+		return nil, err
+	}
+	res.merge(v)
+	return res, nil
+}
+
+type evaluation struct {
+	expr    goexp.Expr
+	explain bool
+	json    bool
+	stdout  io.Writer
+	stderr  io.Writer
+}
+
+func (e *evaluation) run(v vars) int {
+	ctx := newContext(v)
+	var res interface{}
+	var tree *goexp.ExplainTree
+	var err error
+	if e.explain {
+		res, tree, err = goexp.Explain(e.expr, ctx)
+	} else {
+		res, err = goexp.Eval(e.expr, ctx)
+	}
+
+	switch {
+	case e.json:
+		r := evalResult{Explain: tree}
+		if err != nil {
+			r.Error = err.Error()
+		} else {
+			r.Value = toJSON(res)
+		}
+		writeJSON(e.stdout, r)
+	case tree != nil:
+		fmt.Fprint(e.stdout, tree)
+	case err == nil:
+		fmt.Fprintln(e.stdout, formatValue(res))
+	}
+
+	if err != nil {
+		if !e.json {
+			fmt.Fprintln(e.stderr, "goexp eval:", err)
+		}
+		return exitError
+	}
+	return exitOK
+}
+
+func (e *evaluation) fail(err error) {
+	if e.json {
+		writeJSON(e.stdout, evalResult{Error: err.Error()})
+	} else {
+		fmt.Fprintln(e.stderr, "goexp eval:", err)
+	}
+}
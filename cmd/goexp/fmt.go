@@ -0,0 +1,65 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/svstanev/goexp"
+)
+
+type fmtResult struct {
+	Expr      string       `json:"expr"`
+	Formatted string       `json:"formatted,omitempty"`
+	Error     string       `json:"error,omitempty"`
+	Syntax    *syntaxError `json:"syntax,omitempty"`
+}
+
+func runFmt(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("fmt", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	asJSON := fs.Bool("json", false, "write the results as JSON")
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return exitOK
+		}
+		return exitUsage
+	}
+
+	code := exitOK
+	err := readInputs(fs.Args(), stdin, func(in input) {
+		if in.skip {
+			// keep comments and empty lines so files can be reformatted in place
+			if !*asJSON {
+				fmt.Fprintln(stdout, in.source)
+			}
+			return
+		}
+
+		res := fmtResult{Expr: in.source}
+		expr, err := goexp.Parse(in.source)
+		if err == nil {
+			res.Formatted, err = goexp.Print(expr)
+		}
+		if err != nil {
+			res.Error = err.Error()
+			res.Syntax = newSyntaxError(err)
+			code = exitSyntax
+		}
+
+		switch {
+		case *asJSON:
+			writeJSON(stdout, res)
+		case err != nil:
+			fmt.Fprintf(stderr, "goexp fmt: %s: %v\n", in.label, err)
+			fmt.Fprintln(stdout, in.source)
+		default:
+			fmt.Fprintln(stdout, res.Formatted)
+		}
+	})
+	if err != nil {
+		fmt.Fprintln(stderr, "goexp fmt:", err)
+		return exitError
+	}
+	return code
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// input is an expression given as an argument or a line of the standard input
+type input struct {
+	source string
+	label  string
+	// skip is set for empty lines and comments
+	skip bool
+}
+
+// readInputs calls fn for every argument or, if there are none, for every line of stdin
+func readInputs(args []string, stdin io.Reader, fn func(input)) error {
+	if len(args) > 0 {
+		for i, arg := range args {
+			fn(input{source: arg, label: fmt.Sprintf("argument %d", i+1)})
+		}
+		return nil
+	}
+
+	scanner := bufio.NewScanner(stdin)
+	scanner.Buffer(nil, 1<<24)
+	for n := 1; scanner.Scan(); n++ {
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+		fn(input{
+			source: line,
+			label:  fmt.Sprintf("line %d", n),
+			skip:   trimmed == "" || strings.HasPrefix(trimmed, "#"),
+		})
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// errInterrupted is returned by readLine when the line is cancelled with Ctrl-C
+var errInterrupted = errors.New("interrupted")
+
+// lineReader reads the lines of the REPL
+type lineReader interface {
+	readLine(prompt string) (string, error)
+}
+
+// plainReader reads lines without editing, e.g. from a pipe
+type plainReader struct {
+	r      *bufio.Reader
+	w      io.Writer
+	prompt bool
+}
+
+func (p *plainReader) readLine(prompt string) (string, error) {
+	if p.prompt {
+		fmt.Fprint(p.w, prompt)
+	}
+	line, err := p.r.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// lineEditor reads lines from a terminal in raw mode supporting the cursor keys,
+// the history and the usual Emacs bindings (Ctrl-A, Ctrl-E, Ctrl-K, Ctrl-U, ...)
+type lineEditor struct {
+	r       *bufio.Reader
+	w       io.Writer
+	history *history
+}
+
+func newLineEditor(r io.Reader, w io.Writer, h *history) *lineEditor {
+	return &lineEditor{r: bufio.NewReader(r), w: w, history: h}
+}
+
+const (
+	keyCtrlA     = 1
+	keyCtrlB     = 2
+	keyCtrlC     = 3
+	keyCtrlD     = 4
+	keyCtrlE     = 5
+	keyCtrlF     = 6
+	keyBackspace = 8
+	keyCtrlK     = 11
+	keyEnter     = 13
+	keyCtrlN     = 14
+	keyCtrlP     = 16
+	keyCtrlU     = 21
+	keyEscape    = 27
+	keyDelete    = 127
+)
+
+func (e *lineEditor) readLine(prompt string) (string, error) {
+	var line []rune
+	pos := 0
+	// index into the history while browsing it; the edited line is kept aside
+	index := e.history.len()
+	var saved []rune
+
+	redraw := func() {
+		fmt.Fprintf(e.w, "\r%s%s\x1b[K", prompt, string(line))
+		if n := len(line) - pos; n > 0 {
+			fmt.Fprintf(e.w, "\x1b[%dD", n)
+		}
+	}
+	browse := func(i int) {
+		if i < 0 || i > e.history.len() || i == index {
+			return
+		}
+		if index == e.history.len() {
+			saved = line
+		}
+		index = i
+		if i == e.history.len() {
+			line = saved
+		} else {
+			line = []rune(e.history.get(i))
+		}
+		pos = len(line)
+		redraw()
+	}
+
+	redraw()
+	for {
+		c, _, err := e.r.ReadRune()
+		if err != nil {
+			return "", err
+		}
+
+		switch c {
+		case keyEnter, '\n':
+			fmt.Fprint(e.w, "\r\n")
+			return string(line), nil
+		case keyCtrlC:
+			fmt.Fprint(e.w, "^C\r\n")
+			return "", errInterrupted
+		case keyCtrlD:
+			if len(line) == 0 {
+				fmt.Fprint(e.w, "\r\n")
+				return "", io.EOF
+			}
+			if pos < len(line) {
+				line = append(line[:pos], line[pos+1:]...)
+			}
+		case keyBackspace, keyDelete:
+			if pos > 0 {
+				line = append(line[:pos-1], line[pos:]...)
+				pos--
+			}
+		case keyCtrlA:
+			pos = 0
+		case keyCtrlE:
+			pos = len(line)
+		case keyCtrlB:
+			if pos > 0 {
+				pos--
+			}
+		case keyCtrlF:
+			if pos < len(line) {
+				pos++
+			}
+		case keyCtrlK:
+			line = line[:pos]
+		case keyCtrlU:
+			line = line[pos:]
+			pos = 0
+		case keyCtrlP:
+			browse(index - 1)
+		case keyCtrlN:
+			browse(index + 1)
+		case keyEscape:
+			key, err := e.readEscape()
+			if err != nil {
+				return "", err
+			}
+			switch key {
+			case "A":
+				browse(index - 1)
+			case "B":
+				browse(index + 1)
+			case "C":
+				if pos < len(line) {
+					pos++
+				}
+			case "D":
+				if pos > 0 {
+					pos--
+				}
+			case "H", "1~":
+				pos = 0
+			case "F", "4~":
+				pos = len(line)
+			case "3~":
+				if pos < len(line) {
+					line = append(line[:pos], line[pos+1:]...)
+				}
+			}
+		default:
+			if c < ' ' {
+				continue
+			}
+			line = append(line[:pos], append([]rune{c}, line[pos:]...)...)
+			pos++
+		}
+		redraw()
+	}
+}
+
+// readEscape reads the rest of an escape sequence, e.g. "[A", and returns the part after the bracket
+func (e *lineEditor) readEscape() (string, error) {
+	c, _, err := e.r.ReadRune()
+	if err != nil || c != '[' && c != 'O' {
+		return "", err
+	}
+	var key []rune
+	for {
+		c, _, err := e.r.ReadRune()
+		if err != nil {
+			return "", err
+		}
+		key = append(key, c)
+		if c >= 'A' && c <= 'Z' || c == '~' {
+			return string(key), nil
+		}
+	}
+}
+
+// history holds the lines entered in the REPL
+type history struct {
+	lines []string
+	max   int
+}
+
+func (h *history) len() int {
+	return len(h.lines)
+}
+
+func (h *history) get(i int) string {
+	return h.lines[i]
+}
+
+func (h *history) add(line string) {
+	if strings.TrimSpace(line) == "" || len(h.lines) > 0 && h.lines[len(h.lines)-1] == line {
+		return
+	}
+	h.lines = append(h.lines, line)
+	if h.max > 0 && len(h.lines) > h.max {
+		h.lines = h.lines[len(h.lines)-h.max:]
+	}
+}
+
+func (h *history) load(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		h.add(scanner.Text())
+	}
+	return scanner.Err()
+}
+
+func (h *history) save(w io.Writer) error {
+	for _, line := range h.lines {
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/svstanev/goexp/lsp"
+	"github.com/svstanev/goexp/types"
+)
+
+func runLsp(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("lsp", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	schemaFile := fs.String("schema", "", "a JSON `file` declaring the names and methods of the rules")
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return exitOK
+		}
+		return exitUsage
+	}
+
+	var schema *types.Schema
+	if *schemaFile != "" {
+		var err error
+		if schema, err = lsp.LoadSchema(*schemaFile); err != nil {
+			fmt.Fprintln(stderr, "goexp lsp:", err)
+			return exitUsage
+		}
+	}
+	if err := lsp.NewServer(schema).Serve(stdin, stdout); err != nil {
+		fmt.Fprintln(stderr, "goexp lsp:", err)
+		return exitError
+	}
+	return exitOK
+}
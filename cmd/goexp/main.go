@@ -0,0 +1,115 @@
+/*
+Command goexp evaluates, formats and checks goexp expressions.
+
+Usage:
+
+	goexp eval [-var name=value]... [-vars file.json] [-each] [-explain] [-json] expression
+	goexp repl [-vars file.json] [-history file]
+	goexp fmt [-json] [expression...]
+	goexp check [-names a,b.c] [-methods f,g/2] [-json] [expression...]
+	goexp lsp [-schema schema.json]
+
+The expression of eval may be "-" to read it from the standard input. fmt and
+check read one expression per line from the standard input when no expressions
+are given; empty lines and lines starting with # are skipped.
+
+Variables given with -var are constant expressions (e.g. 16, 2.5, true, 'abc')
+or strings otherwise; dotted names (user.name=John) create nested contexts.
+The -vars file is a JSON object whose nested objects become nested contexts;
+"-" reads it from the standard input. With -each eval reads a JSON object of
+variables per line from the standard input and evaluates the expression for
+each of them.
+
+lsp runs a language server for .gx rule files over the standard input and
+output, see package github.com/svstanev/goexp/lsp.
+
+With -json the results are written as JSON objects, one per line:
+
+	$ echo '{"age": 16}' | goexp eval -vars - -json 'age > 18'
+	{"value":false}
+
+Exit codes: 0 on success, 1 on evaluation or I/O errors, 2 on invalid usage,
+3 on syntax errors and 4 on policy violations.
+*/
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/svstanev/goexp"
+)
+
+const (
+	exitOK = iota
+	exitError
+	exitUsage
+	exitSyntax
+	exitPolicy
+)
+
+const usage = `Usage: goexp <command> [arguments]
+
+Commands:
+	eval    evaluate an expression
+	repl    evaluate expressions interactively
+	fmt     print expressions in canonical form
+	check   report syntax errors and policy violations
+	lsp     run the language server for .gx files
+
+Run "goexp <command> -h" for the arguments of a command.
+`
+
+type command func(args []string, stdin io.Reader, stdout, stderr io.Writer) int
+
+var commands = map[string]command{
+	"eval":  runEval,
+	"repl":  runRepl,
+	"fmt":   runFmt,
+	"check": runCheck,
+	"lsp":   runLsp,
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return exitUsage
+	}
+	cmd, ok := commands[args[0]]
+	if !ok {
+		if args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+			fmt.Fprint(stdout, usage)
+			return exitOK
+		}
+		fmt.Fprintf(stderr, "goexp: unknown command %q\n%s", args[0], usage)
+		return exitUsage
+	}
+	return cmd(args[1:], stdin, stdout, stderr)
+}
+
+// writeJSON writes the value as a single line of JSON
+func writeJSON(w io.Writer, value interface{}) {
+	e := json.NewEncoder(w)
+	e.SetEscapeHTML(false)
+	e.Encode(value)
+}
+
+// syntaxError is the JSON of a syntax error
+type syntaxError struct {
+	Message string `json:"message"`
+	Pos     *int   `json:"pos,omitempty"`
+}
+
+func newSyntaxError(err error) *syntaxError {
+	res := &syntaxError{Message: err.Error()}
+	if pos, ok := goexp.ErrorPosition(err); ok {
+		res.Pos = &pos
+	}
+	return res
+}
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func runCommand(stdin string, args ...string) (string, string, int) {
+	var stdout, stderr bytes.Buffer
+	code := run(args, strings.NewReader(stdin), &stdout, &stderr)
+	return stdout.String(), stderr.String(), code
+}
+
+func TestEval(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goexp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	varsFile := filepath.Join(dir, "vars.json")
+	if err := ioutil.WriteFile(varsFile, []byte(`{"age": 16, "user": {"name": "John", "score": 1.5}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		stdin  string
+		args   []string
+		stdout string
+		code   int
+	}{
+		{"", []string{"eval", "1 + 2"}, "3\n", exitOK},
+		{"", []string{"eval", "-var", "a=2", "-var", "b=-1.5", "--var", "s=abc", "-var", "t='x y'", "a * b + 1 == -2.0 && s + t == 'abcx y'"}, "true\n", exitOK},
+		{"", []string{"eval", "-var", "user.name=Ann", "-var", "user.admin=true", "user.admin && user.name == 'Ann'"}, "true\n", exitOK},
+		{"", []string{"eval", "-vars", varsFile, "age > 18 || user.score > 1"}, "true\n", exitOK},
+		{"", []string{"eval", "-vars", varsFile, "-var", "age=20", "-var", "user.score=0", "age + user.score"}, "20\n", exitOK},
+		{"", []string{"eval", "-vars", varsFile, "user"}, `{"name":"John","score":1.5}` + "\n", exitOK},
+		{`{"x": "a"}`, []string{"eval", "-vars", "-", "-json", "x + 'b'"}, `{"value":"ab"}` + "\n", exitOK},
+		{"2 ** 10", []string{"eval", "-json", "-"}, `{"value":1024}` + "\n", exitOK},
+		{"", []string{"eval", "-json", "x"}, `{"value":null,"error":"x not defined"}` + "\n", exitError},
+		{"", []string{"eval", "-json", "1 +"}, `{"value":null,"error":"Parse Error at pos 3: Unexpected end of expression","syntax":{"message":"Parse Error at pos 3: Unexpected end of expression","pos":3}}` + "\n", exitSyntax},
+		{"", []string{"eval", "x"}, "", exitError},
+		{"", []string{"eval"}, "", exitUsage},
+		{"", []string{"eval", "-vars", "-", "-"}, "", exitUsage},
+		{"", []string{"eval", "-var", "a", "a"}, "", exitUsage},
+		{
+			"{\"a\": 1}\n\n{\"a\": 2, \"b\": {\"c\": true}}\nnot json\n",
+			[]string{"eval", "-each", "-json", "-var", "k=10", "a * k"},
+			`{"value":10}` + "\n" + `{"value":20}` + "\n" + `{"value":null,"error":"invalid character 'o' in literal null (expecting 'u')"}` + "\n",
+			exitError,
+		},
+		{"", []string{"eval", "-explain", "-var", "age=16", "age > 18"}, "age > 18 → false (age = 16)\n", exitOK},
+		{
+			"", []string{"eval", "-explain", "-json", "-var", "age=16", "age > 18"},
+			`{"value":false,"explain":{"type":"BinaryExpr","expr":"age > 18","value":false,"children":[` +
+				`{"type":"IdentifierExpr","expr":"age","value":16},{"type":"IntegerLiteralExpr","expr":"18","value":18}]}}` + "\n",
+			exitOK,
+		},
+	}
+
+	for _, test := range tests {
+		stdout, stderr, code := runCommand(test.stdin, test.args...)
+		if stdout != test.stdout || code != test.code {
+			t.Errorf("%v: expected %q, %d but got %q, %d (%s)", test.args, test.stdout, test.code, stdout, code, stderr)
+		}
+	}
+}
+
+func TestFmt(t *testing.T) {
+	stdout, stderr, code := runCommand("# rules\n1+2 *3\n\n  (a) and not b\n1 +\n", "fmt")
+	expected := "# rules\n1 + 2 * 3\n\na && !b\n1 +\n"
+	if stdout != expected || code != exitSyntax || !strings.Contains(stderr, "line 5") {
+		t.Errorf("Expected %q but got %q, %d, %q", expected, stdout, code, stderr)
+	}
+
+	stdout, _, code = runCommand("", "fmt", "-json", "((x))", "'a'")
+	expected = `{"expr":"((x))","formatted":"x"}` + "\n" + `{"expr":"'a'","formatted":"\"a\""}` + "\n"
+	if stdout != expected || code != exitOK {
+		t.Errorf("Expected %q but got %q, %d", expected, stdout, code)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		stdin  string
+		args   []string
+		stdout string
+		code   int
+	}{
+		{"", []string{"check", "a + b", "f(1)"}, "", exitOK},
+		{"a +\n# comment\nb\n", []string{"check"}, "line 1: syntax error: Parse Error at pos 3: Unexpected end of expression\n", exitSyntax},
+		{
+			"", []string{"check", "-names", "age,user.*", "-methods", "len/1,max", "age > 1 && user.name == x", "len(a, b) + max(1, 2, 3)"},
+			"argument 1: policy error: name x is not allowed\n" +
+				"argument 2: policy error: name a is not allowed\n" +
+				"argument 2: policy error: name b is not allowed\n" +
+				"argument 2: policy error: method len/2 is not allowed\n",
+			exitPolicy,
+		},
+		{
+			"", []string{"check", "-json", "-names", "a", "a", "b", "a +"},
+			`{"expr":"a","ok":true}` + "\n" +
+				`{"expr":"b","ok":false,"errors":[{"kind":"policy","message":"name b is not allowed"}]}` + "\n" +
+				`{"expr":"a +","ok":false,"errors":[{"kind":"syntax","message":"Parse Error at pos 3: Unexpected end of expression","pos":3}]}` + "\n",
+			exitSyntax,
+		},
+		{"", []string{"check", "-names", "user.*", "-methods", "len/1", "user.name.len()", "user.name.len(1)"}, "argument 2: policy error: method len/2 is not allowed\n", exitPolicy},
+		{"", []string{"check", "-methods", "f/x", "1"}, "", exitUsage},
+	}
+
+	for _, test := range tests {
+		stdout, stderr, code := runCommand(test.stdin, test.args...)
+		if stdout != test.stdout || code != test.code {
+			t.Errorf("%v: expected %q, %d but got %q, %d (%s)", test.args, test.stdout, test.code, stdout, code, stderr)
+		}
+	}
+}
+
+func TestRun(t *testing.T) {
+	if _, _, code := runCommand(""); code != exitUsage {
+		t.Errorf("Expected %d but got %d", exitUsage, code)
+	}
+	if _, stderr, code := runCommand("", "bogus"); code != exitUsage || !strings.Contains(stderr, "unknown command") {
+		t.Errorf("Expected %d but got %d, %q", exitUsage, code, stderr)
+	}
+	if stdout, _, code := runCommand("", "help"); code != exitOK || !strings.Contains(stdout, "Commands:") {
+		t.Errorf("Expected the usage but got %d, %q", code, stdout)
+	}
+}
+
+func TestRepl(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goexp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	historyFile := filepath.Join(dir, "history")
+
+	input := strings.Join([]string{
+		":let x = 1 + 2",
+		"x * 2",
+		":let 1 = 2",
+		":ast 1 + 2 * x",
+		":tokens a >= 1",
+		":explain x > 5",
+		":vars",
+		"1 +",
+		":bogus",
+		":quit",
+		"not evaluated",
+	}, "\n")
+	stdout, _, code := runCommand(input, "repl", "-history", historyFile)
+	expected := strings.Join([]string{
+		"x = 3",
+		"6",
+		`invalid name "1"`,
+		"(+ 1 (* 2 x))",
+		`Identifier   "a"        @0`,
+		`GreaterEqual ">="       @2`,
+		`Integer      "1"        @5`,
+		`EOF          ""         @6`,
+		"x > 5 → false (x = 3)",
+		"x = 3",
+		"error: Parse Error at pos 3: Unexpected end of expression",
+		"unknown command :bogus, try :help",
+		"",
+	}, "\n")
+	if stdout != expected || code != exitOK {
+		t.Errorf("Expected\n%s\nbut got\n%s", expected, stdout)
+	}
+
+	data, err := ioutil.ReadFile(historyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Split(strings.TrimSpace(string(data)), "\n"); len(lines) != 10 || lines[0] != ":let x = 1 + 2" {
+		t.Errorf("Unexpected history %q", lines)
+	}
+}
+
+func TestLineEditor(t *testing.T) {
+	h := &history{}
+	h.add("first")
+	h.add("second")
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"abc\r", "abc"},
+		{"ac\x1b[Db\r", "abc"},
+		{"bc\x01a\x05d\r", "abcd"},
+		{"abx\x7fc\r", "abc"},
+		{"abcdef\x1b[D\x1b[D\x0b\r", "abcd"},
+		{"xyzabc\x1b[D\x1b[D\x1b[D\x15\r", "abc"},
+		{"\x1b[A\r", "second"},
+		{"\x1b[A\x1b[A\x1b[A\x1b[B\r", "second"},
+		{"new\x1b[A\x1b[B\r", "new"},
+		{"\x10\x10!\r", "first!"},
+		{"ab\x01\x1b[3~\r", "b"},
+		{"añc\x1b[D\x7f\r", "ac"},
+	}
+
+	for _, test := range tests {
+		var out bytes.Buffer
+		e := newLineEditor(strings.NewReader(test.input), &out, h)
+		line, err := e.readLine("> ")
+		if err != nil || line != test.expected {
+			t.Errorf("%q: expected %q but got %q, %v", test.input, test.expected, line, err)
+		}
+	}
+
+	e := newLineEditor(strings.NewReader("abc\x03\x04"), ioutil.Discard, h)
+	if _, err := e.readLine("> "); err != errInterrupted {
+		t.Errorf("Expected %v but got %v", errInterrupted, err)
+	}
+	if _, err := e.readLine("> "); err == nil {
+		t.Errorf("Expected EOF")
+	}
+}
+
+func TestLsp(t *testing.T) {
+	var input strings.Builder
+	for _, msg := range []string{
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}`,
+		`{"jsonrpc":"2.0","method":"textDocument/didOpen","params":{"textDocument":{"uri":"file:///a.gx","text":"a = 1 +"}}}`,
+		`{"jsonrpc":"2.0","method":"exit"}`,
+	} {
+		fmt.Fprintf(&input, "Content-Length: %d\r\n\r\n%s", len(msg), msg)
+	}
+	stdout, stderr, code := runCommand(input.String(), "lsp")
+	if code != exitOK || !strings.Contains(stdout, `"capabilities"`) || !strings.Contains(stdout, "Unexpected end of expression") {
+		t.Errorf("Unexpected output %q, %d, %q", stdout, code, stderr)
+	}
+
+	if _, _, code := runCommand("", "lsp", "-schema", "missing.json"); code != exitUsage {
+		t.Errorf("Expected %d but got %d", exitUsage, code)
+	}
+}
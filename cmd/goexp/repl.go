@@ -0,0 +1,228 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/svstanev/goexp"
+)
+
+const replHelp = `Enter an expression to evaluate it or one of the commands:
+	:ast <expr>           print the tree of the expression
+	:tokens <expr>        print the tokens of the expression
+	:explain <expr>       explain the result of the expression
+	:let <name> = <expr>  evaluate the expression and assign it to the name
+	:vars                 print the names and their values
+	:history              print the history
+	:help                 print this help
+	:quit                 exit
+`
+
+func runRepl(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("repl", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	varsFile := fs.String("vars", "", "a JSON `file` with the variables")
+	historyFile := fs.String("history", defaultHistoryFile(), "the history `file`, empty to disable")
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return exitOK
+		}
+		return exitUsage
+	}
+	if fs.NArg() > 0 || *varsFile == "-" {
+		fmt.Fprintln(stderr, "goexp repl: unexpected arguments")
+		return exitUsage
+	}
+
+	v, err := loadVars(*varsFile, nil, stdin)
+	if err != nil {
+		fmt.Fprintln(stderr, "goexp repl:", err)
+		return exitUsage
+	}
+
+	h := &history{max: 1000}
+	if *historyFile != "" {
+		if f, err := os.Open(*historyFile); err == nil {
+			h.load(f)
+			f.Close()
+		}
+	}
+
+	var reader lineReader
+	out := stdout
+	if f, ok := stdin.(*os.File); ok && isTerminal(int(f.Fd())) {
+		if restore, err := makeRaw(int(f.Fd())); err == nil {
+			defer restore()
+			reader = newLineEditor(stdin, stdout, h)
+			// the terminal doesn't translate the line feeds in raw mode
+			out = crlfWriter{stdout}
+		} else {
+			reader = &plainReader{r: bufio.NewReader(stdin), w: stdout, prompt: true}
+		}
+	} else {
+		reader = &plainReader{r: bufio.NewReader(stdin), w: stdout}
+	}
+
+	r := &repl{context: newContext(v), history: h, stdout: out}
+	for !r.quit {
+		line, err := reader.readLine("> ")
+		if err == errInterrupted {
+			continue
+		}
+		if err != nil {
+			break
+		}
+		h.add(line)
+		r.exec(line)
+	}
+
+	if *historyFile != "" {
+		if f, err := os.Create(*historyFile); err == nil {
+			h.save(f)
+			f.Close()
+		}
+	}
+	return exitOK
+}
+
+func defaultHistoryFile() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".goexp_history")
+}
+
+// crlfWriter translates "\n" to "\r\n" for terminals in raw mode
+type crlfWriter struct {
+	w io.Writer
+}
+
+func (c crlfWriter) Write(p []byte) (int, error) {
+	if _, err := io.WriteString(c.w, strings.Replace(string(p), "\n", "\r\n", -1)); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+type repl struct {
+	context goexp.EvalContext
+	history *history
+	stdout  io.Writer
+	quit    bool
+}
+
+// exec executes a line of the REPL and writes the result or the error
+func (r *repl) exec(line string) {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return
+	}
+	if !strings.HasPrefix(line, ":") {
+		r.eval(line)
+		return
+	}
+
+	cmd, arg := line, ""
+	if i := strings.IndexAny(line, " \t"); i >= 0 {
+		cmd, arg = line[:i], strings.TrimSpace(line[i+1:])
+	}
+	switch cmd {
+	case ":ast":
+		if expr, ok := r.parse(arg); ok {
+			fmt.Fprintln(r.stdout, goexp.DumpSExpr(expr))
+		}
+	case ":tokens":
+		tokens, err := goexp.Tokenize(arg)
+		if err != nil {
+			r.error(err)
+			return
+		}
+		for _, t := range tokens {
+			fmt.Fprintf(r.stdout, "%-12s %-10q @%d\n", t.Type, t.Lexeme, t.Pos)
+		}
+	case ":explain":
+		if expr, ok := r.parse(arg); ok {
+			_, tree, _ := goexp.Explain(expr, r.context)
+			fmt.Fprint(r.stdout, tree)
+		}
+	case ":let":
+		r.let(arg)
+	case ":vars":
+		for _, name := range r.context.Names() {
+			if v, ok := r.context.ResolveName(name); ok {
+				if value, err := v.Value(); err == nil {
+					fmt.Fprintf(r.stdout, "%s = %s\n", name, formatValue(value))
+				} else {
+					fmt.Fprintf(r.stdout, "%s = error: %v\n", name, err)
+				}
+			}
+		}
+	case ":history":
+		for i := 0; i < r.history.len(); i++ {
+			fmt.Fprintf(r.stdout, "%4d  %s\n", i+1, r.history.get(i))
+		}
+	case ":help":
+		fmt.Fprint(r.stdout, replHelp)
+	case ":quit", ":q":
+		r.quit = true
+	default:
+		fmt.Fprintf(r.stdout, "unknown command %s, try :help\n", cmd)
+	}
+}
+
+func (r *repl) eval(source string) {
+	expr, ok := r.parse(source)
+	if !ok {
+		return
+	}
+	res, err := goexp.Eval(expr, r.context)
+	if err != nil {
+		r.error(err)
+		return
+	}
+	fmt.Fprintln(r.stdout, formatValue(res))
+}
+
+// let evaluates "name = expr" and assigns the value to the name
+func (r *repl) let(arg string) {
+	parts := strings.SplitN(arg, "=", 2)
+	if len(parts) != 2 {
+		fmt.Fprintln(r.stdout, "usage: :let <name> = <expr>")
+		return
+	}
+	name := strings.TrimSpace(parts[0])
+	if id, err := goexp.Parse(name); err != nil || id != (goexp.IdentifierExpr{Name: name}) {
+		fmt.Fprintf(r.stdout, "invalid name %q\n", name)
+		return
+	}
+	expr, ok := r.parse(parts[1])
+	if !ok {
+		return
+	}
+	value, err := goexp.Eval(expr, r.context)
+	if err != nil {
+		r.error(err)
+		return
+	}
+	r.context.SetName(name, value)
+	fmt.Fprintf(r.stdout, "%s = %s\n", name, formatValue(value))
+}
+
+func (r *repl) parse(source string) (goexp.Expr, bool) {
+	expr, err := goexp.Parse(source)
+	if err != nil {
+		r.error(err)
+		return nil, false
+	}
+	return expr, true
+}
+
+func (r *repl) error(err error) {
+	fmt.Fprintln(r.stdout, "error:", err)
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/svstanev/goexp"
+	"github.com/svstanev/goexp/types"
+)
+
+// vars holds the variables of an evaluation; nested objects are child contexts
+type vars map[string]interface{}
+
+// varFlags collects the repeated --var k=v flags
+type varFlags []string
+
+func (f *varFlags) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *varFlags) Set(s string) error {
+	if !strings.Contains(s, "=") {
+		return fmt.Errorf("expected name=value")
+	}
+	*f = append(*f, s)
+	return nil
+}
+
+// set sets the variable with the given dotted path creating the nested objects
+func (v vars) set(path string, value interface{}) error {
+	names := strings.Split(path, ".")
+	m := v
+	for i, name := range names[:len(names)-1] {
+		child, ok := m[name].(vars)
+		if !ok {
+			if _, exists := m[name]; exists {
+				return fmt.Errorf("%s is not an object", strings.Join(names[:i+1], "."))
+			}
+			child = vars{}
+			m[name] = child
+		}
+		m = child
+	}
+	m[names[len(names)-1]] = value
+	return nil
+}
+
+// merge copies the variables of other into v, merging the nested objects
+func (v vars) merge(other vars) {
+	for name, value := range other {
+		if o, ok := value.(vars); ok {
+			if m, ok := v[name].(vars); ok {
+				m.merge(o)
+				continue
+			}
+		}
+		v[name] = value
+	}
+}
+
+// parseVarFlags returns the variables of --var flags; values that are not constant expressions are strings
+func parseVarFlags(flags []string) (vars, error) {
+	v := vars{}
+	for _, f := range flags {
+		parts := strings.SplitN(f, "=", 2)
+		if err := v.set(strings.TrimSpace(parts[0]), parseValue(parts[1])); err != nil {
+			return nil, fmt.Errorf("--var %s: %v", f, err)
+		}
+	}
+	return v, nil
+}
+
+func parseValue(s string) interface{} {
+	if expr, err := goexp.Parse(s); err == nil {
+		switch e := goexp.Optimize(expr).(type) {
+		case goexp.IntegerLiteralExpr:
+			return types.NewInteger(e.Value)
+		case goexp.FloatLiteralExpr:
+			return types.NewFloat(e.Value)
+		case goexp.BooleanLiteralExpr:
+			return types.NewBoolean(e.Value)
+		case goexp.StringLiteralExpr:
+			return types.NewString(e.Value)
+		case goexp.NilLiteralExpr:
+			return types.Null()
+		}
+	}
+	return types.NewString(s)
+}
+
+// readVars decodes a JSON object of variables
+func readVars(r io.Reader) (vars, error) {
+	d := json.NewDecoder(r)
+	d.UseNumber()
+	var data interface{}
+	if err := d.Decode(&data); err != nil {
+		return nil, err
+	}
+	return jsonVars(data)
+}
+
+func decodeVars(data []byte) (vars, error) {
+	return readVars(bytes.NewReader(data))
+}
+
+func jsonVars(data interface{}) (vars, error) {
+	obj, ok := data.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("expected a JSON object with the variables")
+	}
+	v := vars{}
+	for name, value := range obj {
+		var err error
+		if v[name], err = fromJSON(value); err != nil {
+			return nil, fmt.Errorf("%s: %v", name, err)
+		}
+	}
+	return v, nil
+}
+
+func fromJSON(value interface{}) (interface{}, error) {
+	switch x := value.(type) {
+	case nil:
+		return types.Null(), nil
+	case bool:
+		return types.NewBoolean(x), nil
+	case string:
+		return types.NewString(x), nil
+	case json.Number:
+		if n, err := strconv.ParseInt(string(x), 10, 64); err == nil {
+			return types.NewInteger(n), nil
+		}
+		f, err := x.Float64()
+		if err != nil {
+			return nil, err
+		}
+		return types.NewFloat(f), nil
+	case map[string]interface{}:
+		return jsonVars(x)
+	}
+	return nil, fmt.Errorf("unsupported JSON value %T", value)
+}
+
+// newContext returns a context with the variables; nested objects become child contexts
+func newContext(v vars) goexp.EvalContext {
+	ctx := goexp.NewEvalContext(nil)
+	for name, value := range v {
+		if child, ok := value.(vars); ok {
+			value = newContext(child)
+		}
+		ctx.AddName(name, value)
+	}
+	return ctx
+}
+
+// toJSON returns the value as a Go value that can be encoded as JSON
+func toJSON(value interface{}) interface{} {
+	switch x := value.(type) {
+	case types.String:
+		return string(x)
+	case types.Integer:
+		return int64(x)
+	case types.Float:
+		if math.IsInf(float64(x), 0) || math.IsNaN(float64(x)) {
+			return fmt.Sprintf("%v", float64(x))
+		}
+		return float64(x)
+	case types.Boolean:
+		return bool(x)
+	case bool, string, int64, float64:
+		return x
+	case goexp.Context:
+		return contextJSON(x)
+	}
+	if types.IsNull(value) {
+		return nil
+	}
+	return fmt.Sprintf("%v", value)
+}
+
+func contextJSON(ctx goexp.Context) interface{} {
+	lister, ok := ctx.(interface{ Names() []string })
+	if !ok {
+		return fmt.Sprintf("%v", ctx)
+	}
+	res := map[string]interface{}{}
+	for _, name := range lister.Names() {
+		if v, ok := ctx.ResolveName(name); ok {
+			if value, err := v.Value(); err == nil {
+				res[name] = toJSON(value)
+			}
+		}
+	}
+	return res
+}
+
+// formatValue returns the value as it would be written in an expression
+func formatValue(value interface{}) string {
+	var expr goexp.Expr
+	switch x := value.(type) {
+	case types.String:
+		expr = goexp.StringLiteralExpr{Value: string(x)}
+	case types.Integer:
+		expr = goexp.IntegerLiteralExpr{Value: int64(x)}
+	case types.Float:
+		expr = goexp.FloatLiteralExpr{Value: float64(x)}
+	case types.Boolean:
+		expr = goexp.BooleanLiteralExpr{Value: bool(x)}
+	case bool:
+		expr = goexp.BooleanLiteralExpr{Value: x}
+	case goexp.Context:
+		data, _ := json.Marshal(toJSON(x))
+		return string(data)
+	default:
+		if types.IsNull(value) {
+			expr = goexp.NilLiteralExpr{}
+		}
+	}
+	if expr != nil {
+		if s, err := goexp.Print(expr); err == nil {
+			return s
+		}
+	}
+	return fmt.Sprintf("%v", value)
+}
@@ -0,0 +1,412 @@
+/*
+Package codegen generates Go functions from goexp expressions.
+
+Given the Go types of the input names, every rule is compiled to a typed
+function with the same semantics as goexp.Eval:
+
+	type Input struct {
+		Age  int64
+		Name string
+	}
+
+	func IsAdult(in *Input) (bool, error)
+
+The supported types are int64 (types.Integer), float64 (types.Float),
+string (types.String) and bool (types.Boolean). Method calls and member
+access are not supported.
+*/
+package codegen
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"go/format"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/svstanev/goexp"
+)
+
+// Schema maps the input names to their Go types
+type Schema map[string]string
+
+// Rule is an expression compiled to a function with the given name
+type Rule struct {
+	Name string
+	Expr goexp.Expr
+}
+
+var goTypes = map[string]bool{
+	"int64":   true,
+	"float64": true,
+	"string":  true,
+	"bool":    true,
+}
+
+var ruleLine = regexp.MustCompile(`^\s*([A-Za-z_][A-Za-z0-9_]*)\s*=([^=].*)$`)
+
+/*
+ParseRules reads rules in the form
+
+	# comment
+	IsAdult = age >= 18
+
+Empty lines and lines starting with # are ignored.
+*/
+func ParseRules(r io.Reader) ([]Rule, error) {
+	var rules []Rule
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		m := ruleLine.FindStringSubmatch(line)
+		if m == nil {
+			return nil, fmt.Errorf("Line %d: expected <name> = <expression>", n)
+		}
+		expr, err := goexp.Parse(m[2])
+		if err != nil {
+			return nil, fmt.Errorf("Line %d: %v", n, err)
+		}
+		rules = append(rules, Rule{m[1], expr})
+	}
+	return rules, scanner.Err()
+}
+
+// Generate returns the formatted source of a Go file in package pkg with
+// the Input struct of the schema and a function for every rule
+func Generate(pkg string, rules []Rule, schema Schema) ([]byte, error) {
+	g := &generator{schema: schema, fields: make(map[string]string)}
+	if err := g.checkSchema(); err != nil {
+		return nil, err
+	}
+
+	var funcs bytes.Buffer
+	for _, rule := range rules {
+		if !token(rule.Name) || !unicode.IsUpper([]rune(rule.Name)[0]) {
+			return nil, fmt.Errorf("Invalid function name %s", rule.Name)
+		}
+		body, err := g.rule(rule.Expr)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", rule.Name, err)
+		}
+		fmt.Fprintf(&funcs, "\nfunc %s(in *Input) (bool, error) {\n%s}\n", rule.Name, body)
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "// Code generated by goexp-gen. DO NOT EDIT.\n\npackage %s\n\n", pkg)
+	if len(g.imports) > 0 {
+		b.WriteString("import (\n")
+		for _, imp := range sortedKeys(g.imports) {
+			fmt.Fprintf(&b, "\t%q\n", imp)
+		}
+		b.WriteString(")\n\n")
+	}
+	if g.divides {
+		b.WriteString("var errDivisionByZero = errors.New(\"Division by zero\")\n\n")
+	}
+
+	b.WriteString("// Input holds the values of the names used by the rules\ntype Input struct {\n")
+	for _, name := range sortedKeys(schema) {
+		fmt.Fprintf(&b, "\t%s %s\n", g.fields[name], schema[name])
+	}
+	b.WriteString("}\n")
+	b.Write(funcs.Bytes())
+
+	return format.Source(b.Bytes())
+}
+
+type generator struct {
+	schema  Schema
+	fields  map[string]string
+	imports map[string]bool
+	divides bool
+
+	// the statements of the current rule and the number of temporary variables
+	stmts bytes.Buffer
+	temps int
+}
+
+func (g *generator) checkSchema() error {
+	names := make(map[string]string)
+	for _, name := range sortedKeys(g.schema) {
+		if !token(name) {
+			return fmt.Errorf("Invalid name %s", name)
+		}
+		if !goTypes[g.schema[name]] {
+			return fmt.Errorf("Unsupported type %s of %s", g.schema[name], name)
+		}
+		field := exportedName(name)
+		if other, ok := names[field]; ok {
+			return fmt.Errorf("Names %s and %s map to the same field %s", other, name, field)
+		}
+		names[field] = name
+		g.fields[name] = field
+	}
+	return nil
+}
+
+func (g *generator) use(pkg string) {
+	if g.imports == nil {
+		g.imports = make(map[string]bool)
+	}
+	g.imports[pkg] = true
+}
+
+// temp stores the value in a temporary variable so it's evaluated once and in order
+func (g *generator) temp(value string) string {
+	g.temps++
+	name := fmt.Sprintf("t%d", g.temps)
+	fmt.Fprintf(&g.stmts, "%s := %s\n", name, value)
+	return name
+}
+
+func (g *generator) rule(expr goexp.Expr) (string, error) {
+	g.stmts.Reset()
+	g.temps = 0
+
+	v, err := g.gen(expr)
+	if err != nil {
+		return "", err
+	}
+	if v.typ != "bool" {
+		return "", fmt.Errorf("Expected a bool expression but got %s", v.typ)
+	}
+	return g.stmts.String() + "return " + v.code + ", nil\n", nil
+}
+
+// value is a generated Go expression and its type; typ is "nil" for the nil literal
+type value struct {
+	code string
+	typ  string
+}
+
+func (g *generator) gen(expr goexp.Expr) (value, error) {
+	switch e := expr.(type) {
+	case goexp.StringLiteralExpr:
+		return value{strconv.Quote(e.Value), "string"}, nil
+	case goexp.IntegerLiteralExpr:
+		// numbers are stored in variables so the Go compiler doesn't treat
+		// overflows and divisions by zero of constant expressions as errors
+		return value{g.temp(fmt.Sprintf("int64(%d)", e.Value)), "int64"}, nil
+	case goexp.FloatLiteralExpr:
+		return value{g.temp(fmt.Sprintf("float64(%s)", strconv.FormatFloat(e.Value, 'g', -1, 64))), "float64"}, nil
+	case goexp.BooleanLiteralExpr:
+		return value{strconv.FormatBool(e.Value), "bool"}, nil
+	case goexp.NilLiteralExpr:
+		return value{"nil", "nil"}, nil
+	case goexp.GroupingExpr:
+		return g.gen(e.Expr)
+	case goexp.IdentifierExpr:
+		if e.Expr != nil {
+			return value{}, fmt.Errorf("Member access is not supported: %s", printExpr(e))
+		}
+		typ, ok := g.schema[e.Name]
+		if !ok {
+			return value{}, fmt.Errorf("%s not defined", e.Name)
+		}
+		return value{"in." + g.fields[e.Name], typ}, nil
+	case goexp.UnaryExpr:
+		return g.unary(e)
+	case goexp.BinaryExpr:
+		return g.binary(e)
+	default:
+		return value{}, fmt.Errorf("Unsupported expression %s", printExpr(expr))
+	}
+}
+
+func (g *generator) unary(e goexp.UnaryExpr) (value, error) {
+	x, err := g.gen(e.Value)
+	if err != nil {
+		return value{}, err
+	}
+	switch {
+	case e.Operator.Type == goexp.Not && x.typ == "bool":
+		return value{"!(" + x.code + ")", "bool"}, nil
+	case e.Operator.Type == goexp.Sub && (x.typ == "int64" || x.typ == "float64"):
+		return value{"-(" + x.code + ")", x.typ}, nil
+	default:
+		return value{}, fmt.Errorf("Operation %q not supported for %s", e.Operator.Lexeme, x.typ)
+	}
+}
+
+func numeric(typ string) bool {
+	return typ == "int64" || typ == "float64"
+}
+
+// promote converts the operands to float64 if either of them is a float64
+func promote(x, y value) (value, value, string) {
+	if x.typ == "float64" || y.typ == "float64" {
+		if x.typ == "int64" {
+			x = value{"float64(" + x.code + ")", "float64"}
+		}
+		if y.typ == "int64" {
+			y = value{"float64(" + y.code + ")", "float64"}
+		}
+		return x, y, "float64"
+	}
+	return x, y, x.typ
+}
+
+var goOperators = map[goexp.TokenType]string{
+	goexp.Add:          "+",
+	goexp.Sub:          "-",
+	goexp.Mul:          "*",
+	goexp.Div:          "/",
+	goexp.Less:         "<",
+	goexp.LessEqual:    "<=",
+	goexp.Greater:      ">",
+	goexp.GreaterEqual: ">=",
+	goexp.Equal:        "==",
+	goexp.NotEqual:     "!=",
+	goexp.And:          "&&",
+	goexp.Or:           "||",
+}
+
+func (g *generator) binary(e goexp.BinaryExpr) (value, error) {
+	x, err := g.gen(e.Left)
+	if err != nil {
+		return value{}, err
+	}
+	y, err := g.gen(e.Right)
+	if err != nil {
+		return value{}, err
+	}
+
+	op := e.Operator.Type
+	unsupported := fmt.Errorf("Operation %q not supported for %s and %s", goOperators[op], x.typ, y.typ)
+
+	switch op {
+	case goexp.And, goexp.Or:
+		if x.typ != "bool" || y.typ != "bool" {
+			return value{}, unsupported
+		}
+		// both operands are evaluated like in goexp.Eval
+		return value{fmt.Sprintf("%s %s %s", g.temp(x.code), goOperators[op], g.temp(y.code)), "bool"}, nil
+
+	case goexp.Equal, goexp.NotEqual:
+		if x.typ == "nil" || y.typ == "nil" {
+			// only nil equals nil
+			for _, v := range []value{x, y} {
+				if v.typ != "nil" {
+					fmt.Fprintf(&g.stmts, "_ = %s\n", v.code)
+				}
+			}
+			equal := x.typ == y.typ
+			return value{strconv.FormatBool(equal == (op == goexp.Equal)), "bool"}, nil
+		}
+		if numeric(x.typ) && numeric(y.typ) {
+			x, y, _ = promote(x, y)
+		} else if x.typ != y.typ {
+			return value{}, unsupported
+		}
+		return value{fmt.Sprintf("(%s %s %s)", x.code, goOperators[op], y.code), "bool"}, nil
+
+	case goexp.Less, goexp.LessEqual, goexp.Greater, goexp.GreaterEqual:
+		if numeric(x.typ) && numeric(y.typ) {
+			x, y, _ = promote(x, y)
+		} else if x.typ != "string" || y.typ != "string" {
+			return value{}, unsupported
+		}
+		return value{fmt.Sprintf("(%s %s %s)", x.code, goOperators[op], y.code), "bool"}, nil
+
+	case goexp.Add:
+		if x.typ == "string" && y.typ == "string" {
+			return value{fmt.Sprintf("(%s + %s)", x.code, y.code), "string"}, nil
+		}
+		fallthrough
+
+	case goexp.Sub, goexp.Mul:
+		if !numeric(x.typ) || !numeric(y.typ) {
+			return value{}, unsupported
+		}
+		x, y, typ := promote(x, y)
+		return value{fmt.Sprintf("(%s %s %s)", x.code, goOperators[op], y.code), typ}, nil
+
+	case goexp.Div, goexp.Modulo:
+		if !numeric(x.typ) || !numeric(y.typ) {
+			return value{}, unsupported
+		}
+		x, y, typ := promote(x, y)
+		if typ == "float64" {
+			if op == goexp.Modulo {
+				g.use("math")
+				return value{fmt.Sprintf("math.Mod(%s, %s)", x.code, y.code), typ}, nil
+			}
+			return value{fmt.Sprintf("(%s / %s)", x.code, y.code), typ}, nil
+		}
+		g.use("errors")
+		g.divides = true
+		left, right := g.temp(x.code), g.temp(y.code)
+		fmt.Fprintf(&g.stmts, "if %s == 0 {\nreturn false, errDivisionByZero\n}\n", right)
+		sign := "/"
+		if op == goexp.Modulo {
+			sign = "%"
+		}
+		return value{fmt.Sprintf("(%s %s %s)", left, sign, right), typ}, nil
+
+	case goexp.Power:
+		if !numeric(x.typ) || !numeric(y.typ) {
+			return value{}, unsupported
+		}
+		g.use("math")
+		if x.typ == "int64" && y.typ == "int64" {
+			return value{fmt.Sprintf("int64(math.Pow(float64(%s), float64(%s)))", x.code, y.code), "int64"}, nil
+		}
+		x, y, _ = promote(x, y)
+		return value{fmt.Sprintf("math.Pow(%s, %s)", x.code, y.code), "float64"}, nil
+	}
+
+	return value{}, unsupported
+}
+
+func token(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, c := range name {
+		if !(c == '_' || unicode.IsLetter(c) || i > 0 && unicode.IsDigit(c)) {
+			return false
+		}
+	}
+	return true
+}
+
+func exportedName(name string) string {
+	r := []rune(name)
+	if r[0] == '_' {
+		return "X" + name
+	}
+	r[0] = unicode.ToUpper(r[0])
+	return string(r)
+}
+
+func sortedKeys(m interface{}) []string {
+	var keys []string
+	switch m := m.(type) {
+	case Schema:
+		for k := range m {
+			keys = append(keys, k)
+		}
+	case map[string]bool:
+		for k := range m {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func printExpr(expr goexp.Expr) string {
+	s, err := goexp.Print(expr)
+	if err != nil {
+		return fmt.Sprintf("%T", expr)
+	}
+	return s
+}
@@ -0,0 +1,175 @@
+package codegen
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/svstanev/goexp"
+	"github.com/svstanev/goexp/types"
+)
+
+const testRules = `
+# rules compiled and compared with goexp.Eval
+IsAdult = age >= 18
+Named = name == "Bob" || name + "!" > "C"
+Scores = score * 2 > age - 1.5 && -score < 10
+Division = age / divisor >= 2
+Modulo = age % divisor == 1 || score % 2.5 > 1
+Power = age ** 2 > 400 && score ** 0.5 < 3
+Flags = !active == (age != 20) && active != false
+Nil = name != nil && !(score == nil)
+Mixed = (age + score) / 2 <= 30.25 == (1 < 2)
+`
+
+var testSchema = Schema{
+	"age":     "int64",
+	"divisor": "int64",
+	"name":    "string",
+	"score":   "float64",
+	"active":  "bool",
+}
+
+type testInput struct {
+	Age     int64
+	Divisor int64
+	Name    string
+	Score   float64
+	Active  bool
+}
+
+var testInputs = []testInput{
+	{18, 1, "Bob", 4.5, true},
+	{17, 0, "Alice", 1, false},
+	{20, 3, "Dave", 12.75, true},
+	{-5, -2, "", 0, false},
+	{41, 7, "Zed", 9, true},
+}
+
+type testResult struct {
+	Value bool
+	Err   string
+}
+
+func TestGenerate(t *testing.T) {
+	if testing.Short() {
+		t.Skip("compiles the generated code")
+	}
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go is not available")
+	}
+
+	rules, err := ParseRules(strings.NewReader(testRules))
+	if err != nil {
+		t.Fatal(err)
+	}
+	src, err := Generate("main", rules, testSchema)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a program that prints the results of the rules for every input as JSON
+	var main bytes.Buffer
+	main.WriteString("package main\n\nimport (\n\"encoding/json\"\n\"os\"\n)\n\nfunc main() {\n")
+	main.WriteString("type result struct {\nValue bool\nErr string\n}\n")
+	main.WriteString("var results [][]result\n")
+	main.WriteString("for _, in := range []Input{\n")
+	for _, in := range testInputs {
+		fmt.Fprintf(&main, "{Age: %d, Divisor: %d, Name: %q, Score: %#v, Active: %t},\n", in.Age, in.Divisor, in.Name, in.Score, in.Active)
+	}
+	main.WriteString("} {\nin := in\nvar res []result\n")
+	for _, rule := range rules {
+		fmt.Fprintf(&main, "if v, err := %s(&in); err != nil {\nres = append(res, result{Err: err.Error()})\n} else {\nres = append(res, result{Value: v})\n}\n", rule.Name)
+	}
+	main.WriteString("results = append(results, res)\n}\njson.NewEncoder(os.Stdout).Encode(results)\n}\n")
+
+	dir, err := ioutil.TempDir("", "goexp-codegen")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	files := map[string]string{
+		"go.mod":    "module rules\n",
+		"rules.go":  string(src),
+		"driver.go": main.String(),
+	}
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cmd := exec.Command("go", "run", ".")
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("%v\n%s\n%s", err, out, src)
+	}
+
+	var results [][]testResult
+	if err := json.Unmarshal(out, &results); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, in := range testInputs {
+		ctx := goexp.NewEvalContext(nil)
+		ctx.AddName("age", types.Integer(in.Age))
+		ctx.AddName("divisor", types.Integer(in.Divisor))
+		ctx.AddName("name", types.String(in.Name))
+		ctx.AddName("score", types.Float(in.Score))
+		ctx.AddName("active", types.Boolean(in.Active))
+
+		for j, rule := range rules {
+			var expected testResult
+			res, err := goexp.Eval(rule.Expr, ctx)
+			if err != nil {
+				expected.Err = err.Error()
+			} else {
+				expected.Value = bool(res.(types.Boolean))
+			}
+			if results[i][j] != expected {
+				t.Errorf("%s with %+v: expected %+v but got %+v", rule.Name, in, expected, results[i][j])
+			}
+		}
+	}
+}
+
+func TestGenerateErrors(t *testing.T) {
+	tests := []struct {
+		rule string
+		err  string
+	}{
+		{"R = age + 1", "R: Expected a bool expression but got int64"},
+		{"R = unknown > 1", "R: unknown not defined"},
+		{"R = name > 1", `R: Operation ">" not supported for string and int64`},
+		{"R = max(age, 1) > 1", "R: Unsupported expression max(age, 1)"},
+		{"R = user.age > 1", "R: Member access is not supported: user.age"},
+		{"r = true", "Invalid function name r"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.rule, func(t *testing.T) {
+			rules, err := ParseRules(strings.NewReader(test.rule))
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = Generate("rules", rules, testSchema)
+			if err == nil || err.Error() != test.err {
+				t.Errorf(`Expected "%s" error but got "%v"`, test.err, err)
+			}
+		})
+	}
+
+	if _, err := ParseRules(strings.NewReader("not a rule")); err == nil {
+		t.Error("Expected a parse error")
+	}
+	if _, err := Generate("rules", nil, Schema{"x": "int"}); err == nil {
+		t.Error("Expected an unsupported type error")
+	}
+}
@@ -0,0 +1,485 @@
+package goexp
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/svstanev/goexp/types"
+)
+
+type Var interface {
+	Value() (interface{}, error)
+}
+
+type varx struct {
+	value interface{}
+}
+
+func (v varx) Value() (interface{}, error) {
+	return v.value, nil
+}
+
+type lazyVar struct {
+	once  sync.Once
+	fn    func() (interface{}, error)
+	value interface{}
+	err   error
+}
+
+func (v *lazyVar) Value() (interface{}, error) {
+	v.once.Do(func() {
+		v.value, v.err = v.fn()
+	})
+	return v.value, v.err
+}
+
+type computedVar struct {
+	name    string
+	expr    Expr
+	context Context
+
+	// path is the computed names being evaluated when the name was resolved
+	path []string
+}
+
+// Value evaluates the expression; a cycle is detected when the name is
+// resolved again during its own evaluation
+func (v *computedVar) Value() (interface{}, error) {
+	for _, n := range v.path {
+		if n == v.name {
+			return nil, fmt.Errorf("Cycle detected: %s", strings.Join(append(v.path, v.name), " -> "))
+		}
+	}
+	path := append(v.path[:len(v.path):len(v.path)], v.name)
+	return Eval(v.expr, computingContext{v.context, path})
+}
+
+// computingContext is the context a computed name is evaluated against; the
+// computed names it resolves carry the path of the names being evaluated
+type computingContext struct {
+	Context
+	path []string
+}
+
+func (c computingContext) ResolveName(name string) (Var, bool) {
+	x, ok := c.Context.ResolveName(name)
+	if v, computed := x.(*computedVar); computed {
+		return &computedVar{v.name, v.expr, v.context, c.path}, true
+	}
+	return x, ok
+}
+
+type Method interface {
+	Invoke(args []interface{}) (interface{}, error)
+}
+
+// PureMethod is implemented by methods whose result depends only on their
+// arguments; calls to pure methods with constant arguments may be folded by Optimize
+type PureMethod interface {
+	Method
+	Pure() bool
+}
+
+// NamedArg is an argument passed by the name of the parameter, e.g. digits: 2
+type NamedArg struct {
+	Name  string
+	Value interface{}
+}
+
+// NamedMethod is implemented by methods that accept named arguments after
+// the positional ones
+type NamedMethod interface {
+	Method
+	InvokeNamed(args []interface{}, named []NamedArg) (interface{}, error)
+}
+
+type methodx struct {
+	fn   interface{}
+	pure bool
+}
+
+func (m methodx) Pure() bool {
+	return m.pure
+}
+
+// Invoke calls the function with the given arguments. The arguments must be
+// assignable to the function's parameters. If the last result of the function
+// is an error, it is returned as the error of the call.
+func (m methodx) Invoke(args []interface{}) (interface{}, error) {
+	fn := reflect.ValueOf(m.fn)
+	if fn.Kind() != reflect.Func {
+		return nil, fmt.Errorf("Cannot invoke %T", m.fn)
+	}
+
+	ft := fn.Type()
+	n := ft.NumIn()
+	if !ft.IsVariadic() && len(args) == n-1 && isOptions(ft.In(n-1)) {
+		// the options are omitted
+		args = append(args[:len(args):len(args)], reflect.Zero(ft.In(n-1)).Interface())
+	}
+	if ft.IsVariadic() && len(args) < n-1 {
+		return nil, fmt.Errorf("Invalid number of arguments: expected at least %d but got %d", n-1, len(args))
+	}
+	if !ft.IsVariadic() && len(args) != n {
+		return nil, fmt.Errorf("Invalid number of arguments: expected %d but got %d", n, len(args))
+	}
+
+	in := make([]reflect.Value, len(args))
+	for i, value := range args {
+		var t reflect.Type
+		if ft.IsVariadic() && i >= n-1 {
+			t = ft.In(n - 1).Elem()
+		} else {
+			t = ft.In(i)
+		}
+
+		if value == nil {
+			switch t.Kind() {
+			case reflect.Interface, reflect.Ptr, reflect.Map, reflect.Slice, reflect.Func:
+				in[i] = reflect.Zero(t)
+				continue
+			}
+			return nil, fmt.Errorf("Cannot use nil as %s in argument %d", t, i+1)
+		}
+
+		v := reflect.ValueOf(value)
+		if !v.Type().AssignableTo(t) {
+			return nil, fmt.Errorf("Cannot use %T as %s in argument %d", value, t, i+1)
+		}
+		in[i] = v
+	}
+
+	res := fn.Call(in)
+	if len(res) == 0 {
+		return nil, nil
+	}
+	if last := res[len(res)-1]; ft.Out(len(res)-1) == errorType {
+		if !last.IsNil() {
+			return nil, last.Interface().(error)
+		}
+		if len(res) == 1 {
+			return nil, nil
+		}
+	}
+	return res[0].Interface(), nil
+}
+
+/*
+InvokeNamed calls a function whose last parameter is a struct of options, e.g.
+
+	func(value interface{}, opts struct{ Locale string }) (interface{}, error)
+
+The named arguments set the fields of the struct, matched by their goexp tag
+or by their name starting with a lower case letter, e.g. locale for Locale;
+the other fields keep their zero values. Options are structs without methods,
+so that e.g. a types.Date parameter is not mistaken for them.
+*/
+func (m methodx) InvokeNamed(args []interface{}, named []NamedArg) (interface{}, error) {
+	ft := reflect.TypeOf(m.fn)
+	if ft == nil || ft.Kind() != reflect.Func || ft.IsVariadic() || ft.NumIn() == 0 || !isOptions(ft.In(ft.NumIn()-1)) {
+		return nil, fmt.Errorf("Cannot use named arguments with %T", m.fn)
+	}
+
+	opts := reflect.New(ft.In(ft.NumIn() - 1)).Elem()
+	seen := make(map[string]bool)
+	for _, arg := range named {
+		if seen[arg.Name] {
+			return nil, fmt.Errorf("Duplicate argument %s", arg.Name)
+		}
+		seen[arg.Name] = true
+
+		field, ok := optionField(opts.Type(), arg.Name)
+		if !ok {
+			return nil, fmt.Errorf("Unknown argument %s", arg.Name)
+		}
+		v, ok := convertOption(arg.Value, field.Type)
+		if !ok {
+			return nil, fmt.Errorf("Cannot use %T as %s in argument %s", arg.Value, field.Type, arg.Name)
+		}
+		opts.FieldByIndex(field.Index).Set(v)
+	}
+	return m.Invoke(append(args[:len(args):len(args)], opts.Interface()))
+}
+
+func isOptions(t reflect.Type) bool {
+	return t.Kind() == reflect.Struct && t.NumMethod() == 0
+}
+
+// optionField returns the exported field of the options for the named argument
+func optionField(t reflect.Type, name string) (reflect.StructField, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+		tag := f.Tag.Get("goexp")
+		if tag == name || tag == "" && strings.ToLower(f.Name[:1])+f.Name[1:] == name {
+			return f, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+// convertOption converts the value of a named argument to the type of the
+// field; values are converted between types of the same kind, e.g. Integer and int
+func convertOption(value interface{}, t reflect.Type) (reflect.Value, bool) {
+	if value == nil || types.IsNull(value) {
+		return reflect.Zero(t), t.Kind() == reflect.Interface || t.Kind() == reflect.Ptr
+	}
+	v := reflect.ValueOf(value)
+	if v.Type().AssignableTo(t) {
+		return v, true
+	}
+	if kindOf(v.Kind()) == kindOf(t.Kind()) && v.Type().ConvertibleTo(t) {
+		return v.Convert(t), true
+	}
+	return reflect.Value{}, false
+}
+
+// kindOf groups the kinds of the integer and floating point types
+func kindOf(k reflect.Kind) reflect.Kind {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return reflect.Int
+	case reflect.Float32, reflect.Float64:
+		return reflect.Float64
+	}
+	return k
+}
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+type Context interface {
+	ResolveName(name string) (Var, bool)
+	ResolveMethod(name string) (Method, bool)
+}
+
+/*
+EvalContext is a Context that can be modified.
+
+All methods are safe for concurrent use, so names may be set or removed while
+expressions are being evaluated against the context. An evaluation sees each
+name as it is at the moment the name is resolved; use Snapshot to evaluate
+against a consistent view.
+*/
+type EvalContext interface {
+	Context
+	AddName(name string, value interface{}) error
+	AddVar(name string, v Var) error
+	AddLazy(name string, fn func() (interface{}, error)) error
+	AddComputed(name string, expression string) error
+	AddMethod(name string, fn interface{}) error
+	AddPureMethod(name string, fn interface{}) error
+
+	// Define returns a builder of a method with a declared signature
+	Define(name string) *MethodBuilder
+
+	SetName(name string, value interface{})
+	RemoveName(name string) bool
+	RemoveMethod(name string) bool
+
+	// Names returns the sorted names visible in the context, including
+	// the ones defined by its parents
+	Names() []string
+
+	// Snapshot returns a copy of the context that shares the parent.
+	// The copy is cheap; the names and methods are only copied when either
+	// the context or the snapshot is modified.
+	Snapshot() EvalContext
+}
+
+type context struct {
+	mu      sync.RWMutex
+	parent  Context
+	vars    map[string]Var
+	methods map[string]Method
+
+	// shared is set when vars and methods are referenced by a snapshot
+	// and must be copied before being modified
+	shared bool
+}
+
+func NewEvalContext(parent Context) EvalContext {
+	return &context{
+		parent:  parent,
+		vars:    make(map[string]Var),
+		methods: make(map[string]Method),
+	}
+}
+
+func (ctx *context) AddName(name string, value interface{}) error {
+	return ctx.AddVar(name, varx{value})
+}
+
+// AddVar adds a name whose value is provided by the given Var
+func (ctx *context) AddVar(name string, v Var) error {
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+
+	if _, present := ctx.vars[name]; present {
+		return fmt.Errorf("Var %s already exists", name)
+	}
+	ctx.copyOnWrite()
+	ctx.vars[name] = v
+	return nil
+}
+
+// AddLazy adds a name whose value is computed by fn the first time it is
+// resolved; the result (and error) is then reused for the lifetime of the context
+func (ctx *context) AddLazy(name string, fn func() (interface{}, error)) error {
+	return ctx.AddVar(name, &lazyVar{fn: fn})
+}
+
+// AddComputed adds a name whose value is the result of the given expression,
+// evaluated against the context every time the name is resolved
+func (ctx *context) AddComputed(name string, expression string) error {
+	expr, err := Parse(expression)
+	if err != nil {
+		return err
+	}
+	return ctx.AddVar(name, &computedVar{name: name, expr: expr, context: ctx})
+}
+
+func (ctx *context) AddMethod(name string, fn interface{}) error {
+	return ctx.addMethod(name, methodx{fn: fn})
+}
+
+// AddPureMethod adds a method whose result depends only on its arguments
+func (ctx *context) AddPureMethod(name string, fn interface{}) error {
+	return ctx.addMethod(name, methodx{fn: fn, pure: true})
+}
+
+func (ctx *context) addMethod(name string, m Method) error {
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+
+	if _, present := ctx.methods[name]; present {
+		return fmt.Errorf("Method %s already exists", name)
+	}
+	ctx.copyOnWrite()
+	ctx.methods[name] = m
+	return nil
+}
+
+// SetName adds the name or replaces its value if it already exists
+func (ctx *context) SetName(name string, value interface{}) {
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+
+	ctx.copyOnWrite()
+	ctx.vars[name] = varx{value}
+}
+
+// RemoveName removes the name from the context; names defined by the parent are not affected
+func (ctx *context) RemoveName(name string) bool {
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+
+	if _, present := ctx.vars[name]; !present {
+		return false
+	}
+	ctx.copyOnWrite()
+	delete(ctx.vars, name)
+	return true
+}
+
+// RemoveMethod removes the method from the context; methods defined by the parent are not affected
+func (ctx *context) RemoveMethod(name string) bool {
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+
+	if _, present := ctx.methods[name]; !present {
+		return false
+	}
+	ctx.copyOnWrite()
+	delete(ctx.methods, name)
+	return true
+}
+
+func (ctx *context) Names() []string {
+	seen := make(map[string]bool)
+	if p, ok := ctx.parent.(interface{ Names() []string }); ok {
+		for _, name := range p.Names() {
+			seen[name] = true
+		}
+	}
+
+	ctx.mu.RLock()
+	for name := range ctx.vars {
+		seen[name] = true
+	}
+	ctx.mu.RUnlock()
+
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (ctx *context) Snapshot() EvalContext {
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+
+	ctx.shared = true
+	return &context{
+		parent:  ctx.parent,
+		vars:    ctx.vars,
+		methods: ctx.methods,
+		shared:  true,
+	}
+}
+
+// copyOnWrite copies the maps shared with a snapshot; must be called with the write lock held
+func (ctx *context) copyOnWrite() {
+	if !ctx.shared {
+		return
+	}
+
+	vars := make(map[string]Var, len(ctx.vars))
+	for k, v := range ctx.vars {
+		vars[k] = v
+	}
+	methods := make(map[string]Method, len(ctx.methods))
+	for k, m := range ctx.methods {
+		methods[k] = m
+	}
+
+	ctx.vars, ctx.methods, ctx.shared = vars, methods, false
+}
+
+func (ctx *context) ResolveName(name string) (Var, bool) {
+	ctx.mu.RLock()
+	x, ok := ctx.vars[name]
+	ctx.mu.RUnlock()
+
+	if ok {
+		if c, computed := x.(*computedVar); computed && c.context != Context(ctx) {
+			// computed names are evaluated against the snapshot they are resolved from
+			x = &computedVar{name: c.name, expr: c.expr, context: ctx}
+		}
+		return x, true
+	}
+	if ctx.parent != nil {
+		return ctx.parent.ResolveName(name)
+	}
+	return nil, false
+}
+
+func (ctx *context) ResolveMethod(name string) (Method, bool) {
+	ctx.mu.RLock()
+	m, ok := ctx.methods[name]
+	ctx.mu.RUnlock()
+
+	if ok {
+		return m, true
+	}
+	if ctx.parent != nil {
+		return ctx.parent.ResolveMethod(name)
+	}
+	return nil, false
+}
@@ -0,0 +1,266 @@
+package goexp
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/svstanev/goexp/types"
+)
+
+func TestAddLazy(t *testing.T) {
+	calls := 0
+	ctx := NewEvalContext(nil)
+	ctx.AddLazy("x", func() (interface{}, error) {
+		calls++
+		return types.Integer(21), nil
+	})
+
+	res, err := EvalString("x + x", ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res != types.Integer(42) {
+		t.Errorf("Expected 42 but got %v", res)
+	}
+	if calls != 1 {
+		t.Errorf("Expected the value to be computed once but was computed %d times", calls)
+	}
+}
+
+func TestAddComputed(t *testing.T) {
+	ctx := NewEvalContext(nil)
+	ctx.AddName("price", types.Integer(10))
+	ctx.AddName("qty", types.Integer(3))
+	ctx.AddComputed("total", "price * qty")
+	ctx.AddComputed("double", "total * 2")
+	ctx.AddComputed("a", "b + 1")
+	ctx.AddComputed("b", "c + 1")
+	ctx.AddComputed("c", "a + 1")
+	ctx.AddComputed("self", "self")
+	ctx.AddComputed("loop", "let v = loop; v")
+
+	tests := []struct {
+		expr   string
+		result interface{}
+		err    string
+	}{
+		{"total", types.Integer(30), ""},
+		{"double + 1", types.Integer(61), ""},
+		{"a", nil, "Cycle detected: a -> b -> c -> a"},
+		{"c", nil, "Cycle detected: c -> a -> b -> c"},
+		{"self", nil, "Cycle detected: self -> self"},
+		{"loop", nil, "Cycle detected: loop -> loop"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.expr, func(t *testing.T) {
+			res, err := EvalString(test.expr, ctx)
+			if test.err != "" {
+				if err == nil || err.Error() != test.err {
+					t.Fatalf(`Expected "%s" error but got "%v"`, test.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res != test.result {
+				t.Errorf("Expected %v but got %v", test.result, res)
+			}
+		})
+	}
+
+	if err := ctx.AddComputed("bad", "1 +"); err == nil {
+		t.Error("Expected parse error")
+	}
+}
+
+// TestComputedDiamond checks that resolving computed names costs no more
+// than evaluating them: every level refers to both names of the next one, but
+// only one of the references is evaluated
+func TestComputedDiamond(t *testing.T) {
+	diamond := func(last string) EvalContext {
+		ctx := NewEvalContext(nil)
+		ctx.AddMethod("first", func(x interface{}, f *Lambda) interface{} { return x })
+		for i := 0; i < 64; i++ {
+			expr := fmt.Sprintf("first(a%d, x => b%d)", i+1, i+1)
+			ctx.AddComputed(fmt.Sprintf("a%d", i), expr)
+			ctx.AddComputed(fmt.Sprintf("b%d", i), expr)
+		}
+		ctx.AddComputed("a64", last)
+		ctx.AddComputed("b64", "true")
+		return ctx
+	}
+
+	res, err := EvalString("a0", diamond("true"))
+	if err != nil || res != types.Boolean(true) {
+		t.Errorf("Expected true but got %v, %v", res, err)
+	}
+	_, err = EvalString("a0", diamond("a0"))
+	if err == nil || !strings.HasPrefix(err.Error(), "Cycle detected: a0 -> a1 -> ") || !strings.HasSuffix(err.Error(), " -> a64 -> a0") {
+		t.Errorf("Expected a cycle but got %v", err)
+	}
+}
+
+func TestSetAndRemove(t *testing.T) {
+	parent := NewEvalContext(nil)
+	parent.AddName("x", types.Integer(1))
+
+	ctx := NewEvalContext(parent)
+	ctx.SetName("y", types.Integer(2))
+	ctx.SetName("y", types.Integer(3))
+	ctx.SetName("x", types.Integer(10))
+	ctx.AddMethod("one", func() types.Integer { return 1 })
+
+	if names, expected := ctx.Names(), []string{"x", "y"}; !reflect.DeepEqual(names, expected) {
+		t.Errorf("Expected %v but got %v", expected, names)
+	}
+
+	if res, err := EvalString("x + y + one()", ctx); err != nil || res != types.Integer(14) {
+		t.Errorf("Expected 14 but got %v (%v)", res, err)
+	}
+
+	if !ctx.RemoveName("x") {
+		t.Error("Expected x to be removed")
+	}
+	if ctx.RemoveName("x") {
+		t.Error("Expected x to be removed only once")
+	}
+	if res, err := EvalString("x + y", ctx); err != nil || res != types.Integer(4) {
+		t.Errorf("Expected the parent x to be used but got %v (%v)", res, err)
+	}
+
+	if !ctx.RemoveMethod("one") || ctx.RemoveMethod("one") {
+		t.Error("Expected one to be removed exactly once")
+	}
+	if _, err := EvalString("one()", ctx); err == nil {
+		t.Error("Expected an error after the method is removed")
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	ctx := NewEvalContext(nil)
+	ctx.AddName("price", types.Integer(10))
+	ctx.AddComputed("total", "price * 2")
+
+	snapshot := ctx.Snapshot()
+	snapshot.SetName("price", types.Integer(20))
+	snapshot.SetName("extra", types.Integer(1))
+	ctx.SetName("tax", types.Integer(5))
+
+	tests := []struct {
+		ctx    Context
+		expr   string
+		result interface{}
+	}{
+		{ctx, "total", types.Integer(20)},
+		{snapshot, "total", types.Integer(40)},
+		{snapshot, "extra", types.Integer(1)},
+		{ctx, "tax", types.Integer(5)},
+	}
+	for _, test := range tests {
+		res, err := EvalString(test.expr, test.ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res != test.result {
+			t.Errorf("%s: Expected %v but got %v", test.expr, test.result, res)
+		}
+	}
+
+	if _, err := EvalString("extra", ctx); err == nil {
+		t.Error("Expected the snapshot changes not to leak into the context")
+	}
+	if _, err := EvalString("tax", snapshot); err == nil {
+		t.Error("Expected the context changes not to leak into the snapshot")
+	}
+}
+
+func TestConcurrentAccess(t *testing.T) {
+	ctx := NewEvalContext(nil)
+	ctx.AddName("x", types.Integer(0))
+	ctx.AddLazy("lazy", func() (interface{}, error) {
+		return types.Integer(1), nil
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				name := fmt.Sprintf("n%d", i)
+				ctx.SetName("x", types.Integer(j))
+				ctx.SetName(name, types.Integer(j))
+				if _, err := EvalString("x + lazy", ctx); err != nil {
+					t.Error(err)
+					return
+				}
+				snapshot := ctx.Snapshot()
+				snapshot.SetName(name, types.Integer(-1))
+				if res, err := EvalString(name, snapshot); err != nil || res != types.Integer(-1) {
+					t.Errorf("Expected -1 but got %v (%v)", res, err)
+					return
+				}
+				ctx.RemoveName(name)
+				ctx.Names()
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestMethodInvoke(t *testing.T) {
+	ctx := NewEvalContext(nil)
+	ctx.AddMethod("inc", func(n types.Integer) types.Integer { return n + 1 })
+	ctx.AddMethod("sum", func(values ...types.Integer) (types.Integer, error) {
+		var res types.Integer
+		for _, n := range values {
+			res += n
+		}
+		return res, nil
+	})
+	ctx.AddMethod("join", func(sep types.String, values ...types.String) types.String { return sep })
+	ctx.AddMethod("fail", func() (interface{}, error) { return nil, fmt.Errorf("failed") })
+	ctx.AddMethod("check", func(v interface{}) error { return nil })
+	ctx.AddName("notAFunc", 1)
+	ctx.AddMethod("notAFunc", 1)
+
+	tests := []struct {
+		expr   string
+		result interface{}
+		err    string
+	}{
+		{"inc(1)", types.Integer(2), ""},
+		{"sum()", types.Integer(0), ""},
+		{"sum(1, 2, 3)", types.Integer(6), ""},
+		{"check(nil)", nil, ""},
+		{"inc()", nil, "Invalid number of arguments: expected 1 but got 0"},
+		{"join()", nil, "Invalid number of arguments: expected at least 1 but got 0"},
+		{"inc('a')", nil, "Cannot use types.String as types.Integer in argument 1"},
+		{"sum(1, 'a')", nil, "Cannot use types.String as types.Integer in argument 2"},
+		{"fail()", nil, "failed"},
+		{"notAFunc()", nil, "Cannot invoke int"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.expr, func(t *testing.T) {
+			res, err := EvalString(test.expr, ctx)
+			if test.err != "" {
+				if err == nil || err.Error() != test.err {
+					t.Fatalf(`Expected "%s" error but got "%v"`, test.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res != test.result {
+				t.Errorf("Expected %v but got %v", test.result, res)
+			}
+		})
+	}
+}
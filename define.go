@@ -0,0 +1,511 @@
+package goexp
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/svstanev/goexp/types"
+)
+
+// ParamDef is a declared parameter of a method
+type ParamDef struct {
+	Name string
+	Type *types.Type
+
+	// Optional parameters may be omitted; their Default is passed instead
+	Optional bool
+	Default  interface{}
+}
+
+// MethodDef is the declared signature of a method added with Define
+type MethodDef struct {
+	Name   string
+	Params []ParamDef
+
+	// Variadic is set if the last parameter may be repeated any number of times
+	Variadic bool
+	Returns  *types.Type
+	Doc      string
+	Pure     bool
+}
+
+// String returns the signature of the method, e.g. "round(x Number, digits Integer = 0) Float"
+func (d *MethodDef) String() string {
+	params := make([]string, len(d.Params))
+	for i, p := range d.Params {
+		t := p.Type.String()
+		if d.Variadic && i == len(d.Params)-1 {
+			t = "..." + t
+		}
+		params[i] = p.Name + " " + t
+		if p.Optional {
+			params[i] += " = " + formatDefault(p.Default)
+		}
+	}
+	return fmt.Sprintf("%s(%s) %s", d.Name, strings.Join(params, ", "), d.Returns)
+}
+
+// Signature returns the signature of the method for Check
+func (d *MethodDef) Signature() *types.Signature {
+	s := &types.Signature{Params: make([]*types.Type, len(d.Params)), Names: make([]string, len(d.Params)), Variadic: d.Variadic, Returns: d.Returns}
+	for i, p := range d.Params {
+		s.Params[i], s.Names[i] = p.Type, p.Name
+		if p.Optional {
+			s.Optional++
+		}
+	}
+	return s
+}
+
+func formatDefault(value interface{}) string {
+	if lit, ok := valueLiteral(value); ok {
+		if s, err := Print(lit); err == nil {
+			return s
+		}
+	}
+	return fmt.Sprint(value)
+}
+
+// DefinedMethod is implemented by the methods added with Define. A method may
+// have several definitions (overloads) that differ by the types of their parameters.
+type DefinedMethod interface {
+	Method
+	Definitions() []*MethodDef
+}
+
+/*
+MethodBuilder declares the signature of a method before adding it to a context:
+
+	err := ctx.Define("round").
+		Param("x", types.NumberType).
+		Param("digits", types.IntegerType).Optional(0).
+		Returns(types.FloatType).
+		Doc("Rounds x to the given number of decimal digits").
+		Impl(func(x interface{}, digits types.Integer) (interface{}, error) { ... })
+
+Impl validates the function against the declared signature and adds the
+method. Defining a method with the same name again adds an overload; calls
+are dispatched to the first overload whose parameters accept the types of the
+arguments, e.g. to a String or a Number version of a method.
+*/
+type MethodBuilder struct {
+	ctx *context
+	def *MethodDef
+	err error
+}
+
+// Define returns a builder of the method name
+func (ctx *context) Define(name string) *MethodBuilder {
+	b := &MethodBuilder{ctx: ctx, def: &MethodDef{Name: name, Returns: types.AnyType}}
+	if !isIdentifier(name) {
+		b.err = fmt.Errorf("Invalid method name %q", name)
+	}
+	return b
+}
+
+func (b *MethodBuilder) fail(format string, args ...interface{}) *MethodBuilder {
+	if b.err == nil {
+		b.err = fmt.Errorf(format, args...)
+	}
+	return b
+}
+
+// last returns the last declared parameter
+func (b *MethodBuilder) last() *ParamDef {
+	if len(b.def.Params) == 0 {
+		return nil
+	}
+	return &b.def.Params[len(b.def.Params)-1]
+}
+
+// Param declares the next parameter of the method
+func (b *MethodBuilder) Param(name string, t *types.Type) *MethodBuilder {
+	d := b.def
+	switch {
+	case !isIdentifier(name):
+		return b.fail("Invalid parameter name %q of %s", name, d.Name)
+	case t == nil:
+		return b.fail("Missing type of parameter %s of %s", name, d.Name)
+	case d.Variadic:
+		return b.fail("Parameter %s of %s follows the variadic parameter", name, d.Name)
+	case b.last() != nil && b.last().Optional:
+		return b.fail("Required parameter %s of %s follows an optional parameter", name, d.Name)
+	}
+	for _, p := range d.Params {
+		if p.Name == name {
+			return b.fail("Duplicate parameter %s of %s", name, d.Name)
+		}
+	}
+	d.Params = append(d.Params, ParamDef{Name: name, Type: t})
+	return b
+}
+
+// Optional makes the last parameter optional; value is passed if the argument is omitted
+func (b *MethodBuilder) Optional(value interface{}) *MethodBuilder {
+	p := b.last()
+	switch {
+	case p == nil:
+		return b.fail("Optional value of %s without a parameter", b.def.Name)
+	case b.def.Variadic:
+		return b.fail("Variadic parameter %s of %s cannot be optional", p.Name, b.def.Name)
+	}
+	v := types.FromGo(value)
+	if !types.TypeOf(v).AssignableTo(p.Type) {
+		return b.fail("Cannot use %s as %s in the default value of parameter %s of %s", types.TypeOf(v), p.Type, p.Name, b.def.Name)
+	}
+	p.Optional, p.Default = true, v
+	return b
+}
+
+// Variadic makes the last parameter variadic, i.e. it may be repeated any number of times
+func (b *MethodBuilder) Variadic() *MethodBuilder {
+	p := b.last()
+	switch {
+	case p == nil:
+		return b.fail("Variadic %s without a parameter", b.def.Name)
+	case p.Optional:
+		return b.fail("Variadic parameter %s of %s cannot be optional", p.Name, b.def.Name)
+	}
+	b.def.Variadic = true
+	return b
+}
+
+// Returns declares the type of the result of the method; it is Any by default
+func (b *MethodBuilder) Returns(t *types.Type) *MethodBuilder {
+	if t == nil {
+		return b.fail("Missing result type of %s", b.def.Name)
+	}
+	b.def.Returns = t
+	return b
+}
+
+// Doc sets the documentation of the method
+func (b *MethodBuilder) Doc(doc string) *MethodBuilder {
+	b.def.Doc = doc
+	return b
+}
+
+// Pure declares that the result of the method depends only on its arguments
+func (b *MethodBuilder) Pure() *MethodBuilder {
+	b.def.Pure = true
+	return b
+}
+
+// Impl validates the function against the declared signature and adds the method to the context
+func (b *MethodBuilder) Impl(fn interface{}) error {
+	if b.err != nil {
+		return b.err
+	}
+	if err := validateImpl(b.def, fn); err != nil {
+		return err
+	}
+	return b.ctx.define(overload{b.def, methodx{fn: fn, pure: b.def.Pure}})
+}
+
+// validateImpl checks that the function accepts the declared parameters and returns the declared result
+func validateImpl(d *MethodDef, fn interface{}) error {
+	ft := reflect.TypeOf(fn)
+	if ft == nil || ft.Kind() != reflect.Func {
+		return fmt.Errorf("Invalid implementation of %s: expected a function but got %T", d.Name, fn)
+	}
+	if ft.NumIn() != len(d.Params) || ft.IsVariadic() != d.Variadic {
+		return fmt.Errorf("Invalid implementation of %s: %s does not match the parameters", d.Name, ft)
+	}
+	for i, p := range d.Params {
+		in := ft.In(i)
+		if d.Variadic && i == len(d.Params)-1 {
+			in = in.Elem()
+		}
+		if !acceptsType(in, p.Type) {
+			return fmt.Errorf("Invalid implementation of %s: cannot use %s as %s in parameter %s", d.Name, p.Type, in, p.Name)
+		}
+	}
+
+	switch {
+	case ft.NumOut() == 2 && ft.Out(1) == errorType:
+	case ft.NumOut() == 1 && ft.Out(0) != errorType:
+	default:
+		return fmt.Errorf("Invalid implementation of %s: expected a result and an optional error but got %s", d.Name, ft)
+	}
+	if out := ft.Out(0); out.Kind() != reflect.Interface {
+		if t := types.TypeOf(reflect.Zero(out).Interface()); !t.AssignableTo(d.Returns) {
+			return fmt.Errorf("Invalid implementation of %s: cannot use %s as %s in the result", d.Name, t, d.Returns)
+		}
+	}
+	return nil
+}
+
+// goTypes are the Go types of the values of the scalar types
+var goTypes = map[types.Kind]reflect.Type{
+	types.IntegerKind:  reflect.TypeOf(types.Integer(0)),
+	types.FloatKind:    reflect.TypeOf(types.Float(0)),
+	types.StringKind:   reflect.TypeOf(types.String("")),
+	types.BooleanKind:  reflect.TypeOf(types.Boolean(false)),
+	types.DateKind:     reflect.TypeOf(types.Date{}),
+	types.DurationKind: reflect.TypeOf(types.Duration(0)),
+}
+
+// acceptsType reports whether all values of type t may be passed to a Go parameter of type in
+func acceptsType(in reflect.Type, t *types.Type) bool {
+	if in.Kind() == reflect.Interface {
+		return true
+	}
+	gt, ok := goTypes[t.Kind]
+	return ok && gt.AssignableTo(in)
+}
+
+type overload struct {
+	def *MethodDef
+	fn  methodx
+}
+
+// bind returns the arguments of the function for the given arguments, or
+// false if the overload does not accept them
+func (o overload) bind(args []interface{}) ([]interface{}, bool) {
+	d := o.def
+	if !d.Signature().Accepts(len(args)) {
+		return nil, false
+	}
+	for i, arg := range args {
+		if !typeOf(arg).AssignableTo(o.param(i).Type) {
+			return nil, false
+		}
+	}
+	for i := len(args); i < len(d.Params); i++ {
+		if d.Params[i].Optional {
+			args = append(args, d.Params[i].Default)
+		}
+	}
+	return args, true
+}
+
+// typeOf returns the type of the value of an argument; Go slices and arrays are lists
+func typeOf(value interface{}) *types.Type {
+	if v := reflect.ValueOf(value); v.Kind() == reflect.Slice || v.Kind() == reflect.Array {
+		return types.ListOf(types.AnyType)
+	}
+	return types.TypeOf(value)
+}
+
+// param returns the parameter of the i-th argument
+func (o overload) param(i int) ParamDef {
+	if i >= len(o.def.Params) {
+		i = len(o.def.Params) - 1
+	}
+	return o.def.Params[i]
+}
+
+// definedMethod is a method added with Define; it dispatches the calls to its overloads
+type definedMethod struct {
+	name      string
+	overloads []overload
+}
+
+func (m *definedMethod) Definitions() []*MethodDef {
+	defs := make([]*MethodDef, len(m.overloads))
+	for i, o := range m.overloads {
+		defs[i] = o.def
+	}
+	return defs
+}
+
+// Pure returns true if all overloads of the method are pure
+func (m *definedMethod) Pure() bool {
+	for _, o := range m.overloads {
+		if !o.def.Pure {
+			return false
+		}
+	}
+	return true
+}
+
+func (m *definedMethod) Invoke(args []interface{}) (interface{}, error) {
+	return m.InvokeNamed(args, nil)
+}
+
+// InvokeNamed binds the named arguments to the parameters of the same names
+// and calls the first overload that accepts the arguments
+func (m *definedMethod) InvokeNamed(args []interface{}, named []NamedArg) (interface{}, error) {
+	for _, o := range m.overloads {
+		if in, _, err := o.bindNamed(args, named); err == nil {
+			if in, ok := o.bind(in); ok {
+				return o.fn.Invoke(in)
+			}
+		}
+	}
+	if len(m.overloads) > 1 {
+		argTypes := make([]string, 0, len(args)+len(named))
+		for _, arg := range args {
+			argTypes = append(argTypes, typeOf(arg).String())
+		}
+		for _, arg := range named {
+			argTypes = append(argTypes, arg.Name+": "+typeOf(arg.Value).String())
+		}
+		return nil, fmt.Errorf("No overload of %s accepts (%s)", m.name, strings.Join(argTypes, ", "))
+	}
+
+	o := m.overloads[0]
+	d := o.def
+	args, names, err := o.bindNamed(args, named)
+	if err != nil {
+		return nil, fmt.Errorf("%s of %s", err, m.name)
+	}
+	if sig := d.Signature(); !sig.Accepts(len(args)) {
+		switch {
+		case d.Variadic:
+			return nil, fmt.Errorf("Invalid number of arguments for %s: expected at least %d but got %d", m.name, sig.MinArgs(), len(args))
+		case sig.Optional > 0:
+			return nil, fmt.Errorf("Invalid number of arguments for %s: expected %d to %d but got %d", m.name, sig.MinArgs(), len(d.Params), len(args))
+		}
+		return nil, fmt.Errorf("Invalid number of arguments for %s: expected %d but got %d", m.name, len(d.Params), len(args))
+	}
+	for i, arg := range args {
+		if p := o.param(i); !typeOf(arg).AssignableTo(p.Type) {
+			if names[i] {
+				return nil, fmt.Errorf("Cannot use %s as %s in argument %s of %s", typeOf(arg), p.Type, p.Name, m.name)
+			}
+			return nil, fmt.Errorf("Cannot use %s as %s in argument %d of %s", typeOf(arg), p.Type, i+1, m.name)
+		}
+	}
+	return nil, fmt.Errorf("Invalid arguments for %s", m.name)
+}
+
+// bindNamed returns the positional arguments with the named arguments in the
+// positions of their parameters and the defaults of the omitted optional
+// parameters before them; names reports which of the arguments were named.
+// The variadic parameter cannot be named.
+func (o overload) bindNamed(args []interface{}, named []NamedArg) (res []interface{}, names []bool, err error) {
+	names = make([]bool, len(args))
+	if len(named) == 0 {
+		return args, names, nil
+	}
+
+	params := o.def.Params
+	if o.def.Variadic {
+		params = params[:len(params)-1]
+	}
+	res = append([]interface{}(nil), args...)
+	set := make([]bool, len(params))
+	for i := 0; i < len(args) && i < len(params); i++ {
+		set[i] = true
+	}
+	last := len(args) - 1
+	for _, arg := range named {
+		i := -1
+		for j, p := range params {
+			if p.Name == arg.Name {
+				i = j
+			}
+		}
+		switch {
+		case i < 0:
+			return nil, nil, fmt.Errorf("Unknown argument %s", arg.Name)
+		case set[i]:
+			return nil, nil, fmt.Errorf("Duplicate argument %s", arg.Name)
+		}
+		for len(res) <= i {
+			res = append(res, nil)
+			names = append(names, false)
+		}
+		res[i], names[i], set[i] = arg.Value, true, true
+		if i > last {
+			last = i
+		}
+	}
+	for i := 0; i <= last && i < len(params); i++ {
+		switch {
+		case set[i]:
+		case params[i].Optional:
+			res[i] = params[i].Default
+		default:
+			return nil, nil, fmt.Errorf("Missing argument %s", params[i].Name)
+		}
+	}
+	return res, names, nil
+}
+
+// define adds the method or an overload of a method added with Define
+func (ctx *context) define(o overload) error {
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+
+	name := o.def.Name
+	m := &definedMethod{name: name, overloads: []overload{o}}
+	if existing, present := ctx.methods[name]; present {
+		d, ok := existing.(*definedMethod)
+		if !ok {
+			return fmt.Errorf("Method %s already exists", name)
+		}
+		for _, other := range d.overloads {
+			if sameParams(other.def, o.def) {
+				return fmt.Errorf("Method %s already defined as %s", name, other.def)
+			}
+		}
+		m.overloads = append(append([]overload(nil), d.overloads...), o)
+	}
+	ctx.copyOnWrite()
+	ctx.methods[name] = m
+	return nil
+}
+
+// sameParams reports whether the methods have the same types of parameters
+func sameParams(a, b *MethodDef) bool {
+	if len(a.Params) != len(b.Params) || a.Variadic != b.Variadic {
+		return false
+	}
+	for i := range a.Params {
+		if a.Params[i].Type.String() != b.Params[i].Type.String() {
+			return false
+		}
+	}
+	return true
+}
+
+/*
+SchemaOf returns the schema of the names and methods of the context and its
+parents for Check. The types of the names are the types of their current
+values; lazy and computed names are Any. The signatures of the methods added
+with Define are the declared ones and their docs are the docs of the schema;
+other methods accept any arguments and return Any.
+*/
+func SchemaOf(ctx Context) *types.Schema {
+	s := &types.Schema{Names: map[string]*types.Type{}, Methods: map[string]*types.Signature{}, Docs: map[string]string{}}
+	var chain []*context
+	for c, ok := ctx.(*context); ok; c, ok = c.parent.(*context) {
+		chain = append(chain, c)
+	}
+	// the names and methods of the children shadow those of the parents
+	for i := len(chain) - 1; i >= 0; i-- {
+		c := chain[i]
+		c.mu.RLock()
+		for name, v := range c.vars {
+			s.Names[name] = types.AnyType
+			if x, ok := v.(varx); ok {
+				s.Names[name] = types.TypeOf(x.value)
+			}
+		}
+		for name, m := range c.methods {
+			s.Methods[name] = methodSignature(m)
+			delete(s.Docs, name)
+			if dm, ok := m.(DefinedMethod); ok && dm.Definitions()[0].Doc != "" {
+				s.Docs[name] = dm.Definitions()[0].Doc
+			}
+		}
+		c.mu.RUnlock()
+	}
+	return s
+}
+
+func methodSignature(m Method) *types.Signature {
+	dm, ok := m.(DefinedMethod)
+	if !ok {
+		return &types.Signature{Params: []*types.Type{types.AnyType}, Variadic: true}
+	}
+	defs := dm.Definitions()
+	s := defs[0].Signature()
+	for _, d := range defs[1:] {
+		s.Overloads = append(s.Overloads, d.Signature())
+	}
+	return s
+}
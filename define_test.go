@@ -0,0 +1,281 @@
+package goexp
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/svstanev/goexp/types"
+)
+
+func round(x interface{}, digits types.Integer) (interface{}, error) {
+	var f float64
+	switch v := x.(type) {
+	case types.Integer:
+		f = float64(v)
+	case types.Float:
+		f = float64(v)
+	default:
+		return nil, fmt.Errorf("Invalid argument for round: %v", x)
+	}
+	p := math.Pow(10, float64(digits))
+	return types.Float(math.Round(f*p) / p), nil
+}
+
+func defineContext(t *testing.T) EvalContext {
+	ctx := NewEvalContext(nil)
+	err := ctx.Define("round").
+		Param("x", types.NumberType).
+		Param("digits", types.IntegerType).Optional(0).
+		Returns(types.FloatType).
+		Doc("Rounds x to the given number of decimal digits").
+		Pure().
+		Impl(round)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ctx.Define("size").Param("s", types.StringType).Returns(types.IntegerType).Impl(func(s types.String) types.Integer {
+		return types.Integer(len(s))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ctx.Define("size").Param("list", types.ListOf(types.AnyType)).Returns(types.IntegerType).Impl(func(list interface{}) types.Integer {
+		return types.Integer(reflect.ValueOf(list).Len())
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ctx.Define("concat").Param("parts", types.StringType).Variadic().Returns(types.StringType).Impl(func(parts ...types.String) types.String {
+		var b strings.Builder
+		for _, p := range parts {
+			b.WriteString(string(p))
+		}
+		return types.String(b.String())
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ctx.Define("since").Param("d", types.DateType).Optional(epoch).Returns(types.DateType).Impl(func(d types.Date) types.Date {
+		return d
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx.AddName("items", []int{1, 2, 3})
+	return ctx
+}
+
+var epoch = time.Date(1970, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func TestDefine(t *testing.T) {
+	tests := []struct {
+		expr     string
+		expected interface{}
+	}{
+		{"round(2.567)", types.Float(3)},
+		{"round(2.567, 2)", types.Float(2.57)},
+		{"round(2)", types.Float(2)},
+		{"size('abc')", types.Integer(3)},
+		{"size(items)", types.Integer(3)},
+		{"concat()", types.String("")},
+		{"concat('a', 'b', 'c')", types.String("abc")},
+		{"round(2.567, digits: 2)", types.Float(2.57)},
+		{"round(digits: 1, x: 2.25)", types.Float(2.3)},
+		{"size(list: items)", types.Integer(3)},
+		{"size(s: 'ab')", types.Integer(2)},
+		{"since()", types.Date(epoch)},
+	}
+
+	ctx := defineContext(t)
+	for _, test := range tests {
+		res, err := EvalString(test.expr, ctx)
+		if err != nil {
+			t.Errorf("%s: %v", test.expr, err)
+			continue
+		}
+		if !reflect.DeepEqual(res, test.expected) {
+			t.Errorf("%s: expected %v but got %v", test.expr, test.expected, res)
+		}
+	}
+}
+
+func TestDefineCallErrors(t *testing.T) {
+	tests := []struct {
+		expr string
+		err  string
+	}{
+		{"round()", "Invalid number of arguments for round: expected 1 to 2 but got 0"},
+		{"round(1, 2, 3)", "Invalid number of arguments for round: expected 1 to 2 but got 3"},
+		{"round('a')", "Cannot use String as Number in argument 1 of round"},
+		{"round(1.5, 1.5)", "Cannot use Float as Integer in argument 2 of round"},
+		{"size(1)", "No overload of size accepts (Integer)"},
+		{"size('a', 'b')", "No overload of size accepts (String, String)"},
+		{"concat('a', 1)", "Cannot use Integer as String in argument 2 of concat"},
+		{"round(1, places: 2)", "Unknown argument places of round"},
+		{"round(1, x: 2)", "Duplicate argument x of round"},
+		{"round(digits: 2)", "Missing argument x of round"},
+		{"round(1, digits: 'a')", "Cannot use String as Integer in argument digits of round"},
+		{"concat(parts: 'a')", "Unknown argument parts of concat"},
+		{"size(s: items)", "No overload of size accepts (s: List<Any>)"},
+	}
+
+	ctx := defineContext(t)
+	for _, test := range tests {
+		res, err := EvalString(test.expr, ctx)
+		if err == nil {
+			t.Errorf("%s: expected error %q but got %v", test.expr, test.err, res)
+			continue
+		}
+		if err.Error() != test.err {
+			t.Errorf("%s: expected error %q but got %q", test.expr, test.err, err)
+		}
+	}
+}
+
+func TestDefineErrors(t *testing.T) {
+	any := func(x interface{}) interface{} { return x }
+
+	tests := []struct {
+		name string
+		def  func(ctx EvalContext) error
+		err  string
+	}{
+		{"invalid name", func(ctx EvalContext) error {
+			return ctx.Define("a-b").Impl(func() interface{} { return nil })
+		}, `Invalid method name "a-b"`},
+		{"invalid param", func(ctx EvalContext) error {
+			return ctx.Define("f").Param("1x", types.AnyType).Impl(any)
+		}, `Invalid parameter name "1x" of f`},
+		{"duplicate param", func(ctx EvalContext) error {
+			return ctx.Define("f").Param("x", types.AnyType).Param("x", types.AnyType).Impl(any)
+		}, "Duplicate parameter x of f"},
+		{"required after optional", func(ctx EvalContext) error {
+			return ctx.Define("f").Param("x", types.AnyType).Optional(1).Param("y", types.AnyType).Impl(any)
+		}, "Required parameter y of f follows an optional parameter"},
+		{"param after variadic", func(ctx EvalContext) error {
+			return ctx.Define("f").Param("x", types.AnyType).Variadic().Param("y", types.AnyType).Impl(any)
+		}, "Parameter y of f follows the variadic parameter"},
+		{"invalid default", func(ctx EvalContext) error {
+			return ctx.Define("f").Param("x", types.IntegerType).Optional("a").Impl(any)
+		}, "Cannot use String as Integer in the default value of parameter x of f"},
+		{"not a function", func(ctx EvalContext) error {
+			return ctx.Define("f").Impl(1)
+		}, "Invalid implementation of f: expected a function but got int"},
+		{"arity", func(ctx EvalContext) error {
+			return ctx.Define("f").Param("x", types.AnyType).Param("y", types.AnyType).Impl(any)
+		}, "Invalid implementation of f: func(interface {}) interface {} does not match the parameters"},
+		{"not variadic", func(ctx EvalContext) error {
+			return ctx.Define("f").Param("x", types.AnyType).Variadic().Impl(any)
+		}, "Invalid implementation of f: func(interface {}) interface {} does not match the parameters"},
+		{"param type", func(ctx EvalContext) error {
+			return ctx.Define("f").Param("x", types.NumberType).Impl(func(x types.Integer) interface{} { return x })
+		}, "Invalid implementation of f: cannot use Number as types.Integer in parameter x"},
+		{"no result", func(ctx EvalContext) error {
+			return ctx.Define("f").Impl(func() {})
+		}, "Invalid implementation of f: expected a result and an optional error but got func()"},
+		{"result type", func(ctx EvalContext) error {
+			return ctx.Define("f").Returns(types.StringType).Impl(func() types.Integer { return 0 })
+		}, "Invalid implementation of f: cannot use Integer as String in the result"},
+		{"same overload", func(ctx EvalContext) error {
+			ctx.Define("f").Param("x", types.AnyType).Impl(any)
+			return ctx.Define("f").Param("y", types.AnyType).Impl(any)
+		}, "Method f already defined as f(x Any) Any"},
+		{"plain method", func(ctx EvalContext) error {
+			ctx.AddMethod("f", any)
+			return ctx.Define("f").Param("x", types.StringType).Impl(any)
+		}, "Method f already exists"},
+	}
+
+	for _, test := range tests {
+		err := test.def(NewEvalContext(nil))
+		if err == nil || err.Error() != test.err {
+			t.Errorf("%s: expected error %q but got %v", test.name, test.err, err)
+		}
+	}
+}
+
+func TestDefinitions(t *testing.T) {
+	ctx := defineContext(t)
+	expected := map[string][]string{
+		"round":  {"round(x Number, digits Integer = 0) Float"},
+		"size":   {"size(s String) Integer", "size(list List<Any>) Integer"},
+		"concat": {"concat(parts ...String) String"},
+	}
+	for name, sigs := range expected {
+		m, _ := ctx.ResolveMethod(name)
+		dm, ok := m.(DefinedMethod)
+		if !ok {
+			t.Fatalf("Expected %s to be a DefinedMethod", name)
+		}
+		var res []string
+		for _, d := range dm.Definitions() {
+			res = append(res, d.String())
+		}
+		if !reflect.DeepEqual(res, sigs) {
+			t.Errorf("Expected %v but got %v", sigs, res)
+		}
+	}
+
+	m, _ := ctx.ResolveMethod("round")
+	if !m.(PureMethod).Pure() {
+		t.Error("Expected round to be pure")
+	}
+	m, _ = ctx.ResolveMethod("size")
+	if m.(PureMethod).Pure() {
+		t.Error("Expected size not to be pure")
+	}
+}
+
+func TestSchemaOf(t *testing.T) {
+	ctx := NewEvalContext(defineContext(t))
+	ctx.AddName("name", types.String("x"))
+	ctx.AddMethod("plain", func(x interface{}) interface{} { return x })
+	schema := SchemaOf(ctx)
+	if docs := map[string]string{"round": "Rounds x to the given number of decimal digits"}; !reflect.DeepEqual(schema.Docs, docs) {
+		t.Errorf("Expected docs %v but got %v", docs, schema.Docs)
+	}
+
+	tests := []struct {
+		expr     string
+		expected string
+		err      string
+	}{
+		{"round(1.5) + 1", "Float", ""},
+		{"round(1.5, 1)", "Float", ""},
+		{"round()", "Any", "Type Error: Invalid number of arguments for round: expected 1 to 2 but got 0"},
+		{"round(name)", "Float", "Type Error: Cannot use String as Number in argument 1 of round"},
+		{"size(name) + size(items)", "Integer", ""},
+		{"size(1)", "Any", "Type Error: No overload of size accepts (Integer)"},
+		{"concat(name, 'a', 'b')", "String", ""},
+		{"plain(1, 2)", "Any", ""},
+		{"round(digits: 2, x: 1.5)", "Float", ""},
+		{"round(1.5, places: 2)", "Any", "Type Error: Unknown argument places of round"},
+		{"round(digits: 2)", "Any", "Type Error: Missing argument x of round"},
+		{"round(1.5, digits: 'a')", "Float", "Type Error: Cannot use String as Integer in argument digits of round"},
+		{"size(list: items)", "Integer", ""},
+		{"size(s: 1)", "Any", "Type Error: No overload of size accepts (s: Integer)"},
+		{"plain(1, options: 2)", "Any", ""},
+	}
+
+	for _, test := range tests {
+		expr, err := Parse(test.expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res, err := Check(expr, schema)
+		if res.String() != test.expected {
+			t.Errorf("%s: expected %s but got %s", test.expr, test.expected, res)
+		}
+		s := ""
+		if err != nil {
+			s = err.Error()
+		}
+		if s != test.err {
+			t.Errorf("%s: expected error %q but got %q", test.expr, test.err, s)
+		}
+	}
+}
@@ -0,0 +1,219 @@
+package goexp
+
+import (
+	"strings"
+
+	"github.com/svstanev/goexp/types"
+)
+
+// Deps describes the free names, the called methods and the literal
+// constants referenced by an expression
+type Deps struct {
+	// Names holds the free variables in order of appearance. Member access
+	// chains are reported as dotted paths, e.g. "user.address.city".
+	Names []string
+
+	// Methods holds the called methods together with the number of
+	// arguments they are called with.
+	Methods []MethodDep
+
+	// Constants holds the values of the literals in order of appearance.
+	Constants []interface{}
+}
+
+// MethodDep is a method call referenced by an expression
+type MethodDep struct {
+	// Name of the method; dotted if the method is called on a name that is a
+	// context, e.g. "user.orders.count"
+	Name  string
+	Arity int
+}
+
+// Dependencies returns the names, methods and constants the given expression
+// depends on; the parameters of lambdas are not free names and are not reported.
+//
+// x.m(args) is m(x, args) unless x is a context, which is known only with the
+// context (see DependenciesWith), so the methods called on values are reported
+// by their names with the receiver counted as the first argument, e.g.
+// name.trim() is the method trim with 1 argument.
+func Dependencies(expr Expr) Deps {
+	return DependenciesWith(expr, nil)
+}
+
+// DependenciesWith returns the dependencies of the expression like Dependencies
+// but reports the methods called on the names that are contexts in the given
+// context by their dotted names, e.g. "user.orders.count"; the names are
+// evaluated to find out which are contexts
+func DependenciesWith(expr Expr, context Context) Deps {
+	c := newDependencyCollector(context)
+	expr.Accept(c, nil)
+	return c.deps
+}
+
+type dependencyCollector struct {
+	deps      Deps
+	names     map[string]bool
+	methods   map[MethodDep]bool
+	constants map[interface{}]bool
+
+	// bound counts the enclosing lambdas declaring each parameter
+	bound map[string]int
+
+	// context resolves the names the methods are called on, if not nil
+	context Context
+}
+
+func newDependencyCollector(context Context) *dependencyCollector {
+	return &dependencyCollector{
+		context:   context,
+		names:     make(map[string]bool),
+		methods:   make(map[MethodDep]bool),
+		constants: make(map[interface{}]bool),
+		bound:     make(map[string]int),
+	}
+}
+
+// isBound returns true if the root of the path is the parameter of an enclosing lambda
+func (c *dependencyCollector) isBound(path string) bool {
+	return c.bound[strings.SplitN(path, ".", 2)[0]] > 0
+}
+
+// isContext returns true if the value of the name is a context
+func (c *dependencyCollector) isContext(name Expr) bool {
+	if c.context == nil {
+		return false
+	}
+	value, err := Eval(name, c.context)
+	if err != nil {
+		return false
+	}
+	_, ok := value.(Context)
+	return ok
+}
+
+func (c *dependencyCollector) addName(name string) {
+	if c.isBound(name) {
+		return
+	}
+	if !c.names[name] {
+		c.names[name] = true
+		c.deps.Names = append(c.deps.Names, name)
+	}
+}
+
+func (c *dependencyCollector) addMethod(m MethodDep) {
+	if !c.methods[m] {
+		c.methods[m] = true
+		c.deps.Methods = append(c.deps.Methods, m)
+	}
+}
+
+func (c *dependencyCollector) addConstant(value interface{}) (interface{}, error) {
+	if !c.constants[value] {
+		c.constants[value] = true
+		c.deps.Constants = append(c.deps.Constants, value)
+	}
+	return nil, nil
+}
+
+func (c *dependencyCollector) VisitStringLiteralExpr(e StringLiteralExpr, context VisitorContext) (interface{}, error) {
+	return c.addConstant(types.NewString(e.Value))
+}
+
+func (c *dependencyCollector) VisitIntegerLiteralExpr(e IntegerLiteralExpr, context VisitorContext) (interface{}, error) {
+	return c.addConstant(types.NewInteger(e.Value))
+}
+
+func (c *dependencyCollector) VisitFloatLiteralExpr(e FloatLiteralExpr, context VisitorContext) (interface{}, error) {
+	return c.addConstant(types.NewFloat(e.Value))
+}
+
+func (c *dependencyCollector) VisitBooleanLiteralExpr(e BooleanLiteralExpr, context VisitorContext) (interface{}, error) {
+	return c.addConstant(types.NewBoolean(e.Value))
+}
+
+func (c *dependencyCollector) VisitNilLiteralExpr(e NilLiteralExpr, context VisitorContext) (interface{}, error) {
+	return c.addConstant(types.Null())
+}
+
+func (c *dependencyCollector) VisitBinaryExpr(e BinaryExpr, context VisitorContext) (interface{}, error) {
+	e.Left.Accept(c, context)
+	return e.Right.Accept(c, context)
+}
+
+func (c *dependencyCollector) VisitUnaryExpr(e UnaryExpr, context VisitorContext) (interface{}, error) {
+	return e.Value.Accept(c, context)
+}
+
+func (c *dependencyCollector) VisitGroupingExpr(e GroupingExpr, context VisitorContext) (interface{}, error) {
+	return e.Expr.Accept(c, context)
+}
+
+func (c *dependencyCollector) VisitCallExpr(e CallExpr, context VisitorContext) (interface{}, error) {
+	if id, ok := e.Name.(IdentifierExpr); ok {
+		m := MethodDep{Name: id.Name, Arity: len(e.Args)}
+		if id.Expr != nil {
+			path, ok := identifierPath(id.Expr)
+			switch {
+			case !ok:
+				id.Expr.Accept(c, context)
+				m.Arity++
+			case c.isBound(path):
+				m.Arity++
+			case c.isContext(id.Expr):
+				c.addName(path)
+				m.Name = path + "." + id.Name
+			default:
+				c.addName(path)
+				m.Arity++
+			}
+		}
+		c.addMethod(m)
+	} else {
+		e.Name.Accept(c, context)
+	}
+	for _, arg := range e.Args {
+		arg.Accept(c, context)
+	}
+	return nil, nil
+}
+
+func (c *dependencyCollector) VisitIdentifierExpr(e IdentifierExpr, context VisitorContext) (interface{}, error) {
+	if path, ok := identifierPath(e); ok {
+		c.addName(path)
+		return nil, nil
+	}
+	// member access on a computed value, e.g. foo().bar
+	return e.Expr.Accept(c, context)
+}
+
+// VisitLetExpr collects the dependencies of the value and of the body, where the name is bound
+func (c *dependencyCollector) VisitLetExpr(e LetExpr, context VisitorContext) (interface{}, error) {
+	e.Value.Accept(c, context)
+	c.bound[e.Name]++
+	defer func() { c.bound[e.Name]-- }()
+	return e.Body.Accept(c, context)
+}
+
+func (c *dependencyCollector) VisitTemplateExpr(e TemplateExpr, context VisitorContext) (interface{}, error) {
+	for _, part := range e.Parts {
+		part.Accept(c, context)
+	}
+	return nil, nil
+}
+
+func (c *dependencyCollector) VisitNamedArgExpr(e NamedArgExpr, context VisitorContext) (interface{}, error) {
+	return e.Value.Accept(c, context)
+}
+
+func (c *dependencyCollector) VisitLambdaExpr(e LambdaExpr, context VisitorContext) (interface{}, error) {
+	c.bound[e.Param]++
+	defer func() { c.bound[e.Param]-- }()
+	return e.Body.Accept(c, context)
+}
+
+// identifierPath returns the dotted path of a chain of identifiers, e.g. "a.b.c"
+func identifierPath(expr Expr) (string, bool) {
+	path, ok := Path(expr)
+	return strings.Join(path, "."), ok
+}
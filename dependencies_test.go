@@ -0,0 +1,104 @@
+package goexp
+
+import (
+	"testing"
+
+	"github.com/go-test/deep"
+	"github.com/svstanev/goexp/types"
+)
+
+func TestDependencies(t *testing.T) {
+	tests := []struct {
+		expr string
+		deps Deps
+	}{
+		{"1 + 2", Deps{
+			Constants: []interface{}{types.Integer(1), types.Integer(2)},
+		}},
+		{"x + y * x", Deps{
+			Names: []string{"x", "y"},
+		}},
+		{"user.address.city == 'Sofia' || user.age > 18", Deps{
+			Names:     []string{"user.address.city", "user.age"},
+			Constants: []interface{}{types.String("Sofia"), types.Integer(18)},
+		}},
+		{"max(x, 1.5) && !flag && nil != true", Deps{
+			Names:     []string{"x", "flag"},
+			Methods:   []MethodDep{{"max", 2}},
+			Constants: []interface{}{types.Float(1.5), types.Null(), types.Boolean(true)},
+		}},
+		{"user.orders.count() + count(a, b) + count(a)", Deps{
+			Names:   []string{"user.orders", "a", "b"},
+			Methods: []MethodDep{{"count", 1}, {"count", 2}},
+		}},
+		{"name.trim() + 'x'.upper() + name.pad(2)", Deps{
+			Names:     []string{"name"},
+			Methods:   []MethodDep{{"trim", 1}, {"upper", 1}, {"pad", 2}},
+			Constants: []interface{}{types.String("x"), types.Integer(2)},
+		}},
+		{"foo(x).bar", Deps{
+			Names:   []string{"x"},
+			Methods: []MethodDep{{"foo", 1}},
+		}},
+		{"(a + b).len()", Deps{
+			Names:   []string{"a", "b"},
+			Methods: []MethodDep{{"len", 1}},
+		}},
+		{"count(orders, o => o.paid && o.total > min) + o.x", Deps{
+			Names:   []string{"orders", "min", "o.x"},
+			Methods: []MethodDep{{"count", 2}},
+		}},
+		{"let total = sum(items.price); total > limit && total < 2 * limit", Deps{
+			Names:     []string{"items.price", "limit"},
+			Methods:   []MethodDep{{"sum", 1}},
+			Constants: []interface{}{types.Integer(2)},
+		}},
+		{"`${user.name} has ${len(items)} items`", Deps{
+			Names:     []string{"user.name", "items"},
+			Methods:   []MethodDep{{"len", 1}},
+			Constants: []interface{}{types.String(" has "), types.String(" items")},
+		}},
+		{"round(x, digits: d)", Deps{
+			Names:   []string{"x", "d"},
+			Methods: []MethodDep{{"round", 2}},
+		}},
+		{"sum(items, i => i.tags.count(t => t == i.kind))", Deps{
+			Names:   []string{"items"},
+			Methods: []MethodDep{{"sum", 2}, {"count", 2}},
+		}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.expr, func(t *testing.T) {
+			expr, err := Parse(test.expr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := deep.Equal(Dependencies(expr), test.deps); diff != nil {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestDependenciesWith(t *testing.T) {
+	orders := NewEvalContext(nil)
+	orders.AddMethod("count", func() types.Integer { return 0 })
+	user := NewEvalContext(nil)
+	user.AddName("orders", orders)
+	user.AddName("name", types.String("Ana"))
+	ctx := NewEvalContext(nil)
+	ctx.AddName("user", user)
+
+	expr, err := Parse("user.orders.count() + user.name.len() + other.len() + count(items, i => i.count())")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := Deps{
+		Names:   []string{"user.orders", "user.name", "other", "items"},
+		Methods: []MethodDep{{"user.orders.count", 0}, {"len", 1}, {"count", 2}, {"count", 1}},
+	}
+	if diff := deep.Equal(DependenciesWith(expr, ctx), expected); diff != nil {
+		t.Error(diff)
+	}
+}
@@ -0,0 +1,269 @@
+package goexp
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// DumpOption configures DumpSExpr and DumpDot
+type DumpOption func(*dumper)
+
+// WithValues overlays the values recorded by EvalTrace on the dumped nodes
+func WithValues(trace *Trace) DumpOption {
+	return func(d *dumper) {
+		d.trace = trace
+	}
+}
+
+/*
+DumpSExpr returns the tree of the expression as an S-expression, e.g.
+
+	(|| (== a 1) (> b 2))
+
+for "a == 1 || b > 2". Groupings are kept as (group x), member access as (. x name)
+and method calls as (call name args...). With WithValues every evaluated node
+that is not a literal is followed by its value, e.g. (== a=1 1)=true.
+*/
+func DumpSExpr(expr Expr, options ...DumpOption) string {
+	d := newDumper(options)
+	var sb strings.Builder
+	d.sexpr(&sb, expr, []int{0})
+	return sb.String()
+}
+
+/*
+DumpDot returns the tree of the expression as a Graphviz DOT graph.
+
+Every node shows its type, operator or name and the span of the operator in the
+source, if known. With WithValues the evaluated nodes show their values, the
+nodes that failed are red and the nodes that were not evaluated are dashed.
+*/
+func DumpDot(expr Expr, options ...DumpOption) string {
+	d := newDumper(options)
+	var sb strings.Builder
+	sb.WriteString("digraph expr {\n")
+	sb.WriteString("\tnode [shape=box, fontname=\"monospace\"];\n")
+	d.dot(&sb, expr, []int{0})
+	sb.WriteString("}\n")
+	return sb.String()
+}
+
+type dumper struct {
+	trace *Trace
+	ids   int
+}
+
+func newDumper(options []DumpOption) *dumper {
+	d := &dumper{}
+	for _, option := range options {
+		option(d)
+	}
+	return d
+}
+
+func (d *dumper) sexpr(sb *strings.Builder, expr Expr, path []int) {
+	nodes := children(expr)
+	child := func(i int) {
+		sb.WriteByte(' ')
+		d.sexpr(sb, nodes[i], append(path[:len(path):len(path)], i))
+	}
+
+	switch e := expr.(type) {
+	case StringLiteralExpr, IntegerLiteralExpr, FloatLiteralExpr, BooleanLiteralExpr, NilLiteralExpr:
+		sb.WriteString(literalString(expr))
+		return
+
+	case IdentifierExpr:
+		if e.Expr == nil {
+			sb.WriteString(e.Name)
+		} else {
+			sb.WriteString("(.")
+			child(0)
+			sb.WriteString(" " + e.Name + ")")
+		}
+
+	case CallExpr:
+		first := 0
+		sb.WriteString("(call ")
+		if id, ok := e.Name.(IdentifierExpr); ok {
+			if id.Expr != nil {
+				sb.WriteString("(.")
+				child(0)
+				sb.WriteString(" " + id.Name + ")")
+				first = 1
+			} else {
+				sb.WriteString(id.Name)
+			}
+		}
+		for i := first; i < len(nodes); i++ {
+			child(i)
+		}
+		sb.WriteByte(')')
+
+	default:
+		sb.WriteString("(" + operatorName(expr))
+		for i := range nodes {
+			child(i)
+		}
+		sb.WriteByte(')')
+	}
+
+	if v, ok := d.trace.lookup(path); ok {
+		sb.WriteString("=" + formatTraceValue(v))
+	}
+}
+
+func (d *dumper) dot(sb *strings.Builder, expr Expr, path []int) string {
+	id := fmt.Sprintf("n%d", d.ids)
+	d.ids++
+
+	lines := []string{strings.TrimPrefix(fmt.Sprintf("%T", expr), "goexp.")}
+	switch e := expr.(type) {
+	case StringLiteralExpr, IntegerLiteralExpr, FloatLiteralExpr, BooleanLiteralExpr, NilLiteralExpr:
+		lines = append(lines, literalString(expr))
+	case IdentifierExpr:
+		lines = append(lines, e.Name)
+	case LambdaExpr:
+		lines = append(lines, e.Param)
+	case NamedArgExpr:
+		lines = append(lines, e.Name)
+	case LetExpr:
+		lines = append(lines, e.Name)
+	case CallExpr:
+		if name, ok := e.Name.(IdentifierExpr); ok {
+			lines = append(lines, name.Name)
+		}
+	case UnaryExpr:
+		lines = append(lines, operatorLabel(e.Operator), spanLabel(e.Operator))
+	case BinaryExpr:
+		lines = append(lines, operatorLabel(e.Operator), spanLabel(e.Operator))
+	}
+
+	var attrs []string
+	if d.trace != nil {
+		if v, ok := d.trace.lookup(path); !ok {
+			attrs = append(attrs, "style=dashed")
+		} else {
+			lines = append(lines, "= "+formatTraceValue(v))
+			if v.err != nil {
+				attrs = append(attrs, "color=red")
+			}
+		}
+	}
+	for i, line := range lines {
+		lines[i] = escapeDot(line)
+	}
+	attrs = append([]string{fmt.Sprintf("label=\"%s\"", strings.Join(lines, `\n`))}, attrs...)
+	fmt.Fprintf(sb, "\t%s [%s];\n", id, strings.Join(attrs, ", "))
+
+	labels := edgeLabels(expr)
+	for i, child := range children(expr) {
+		childID := d.dot(sb, child, append(path[:len(path):len(path)], i))
+		fmt.Fprintf(sb, "\t%s -> %s [label=\"%s\"];\n", id, childID, labels[i])
+	}
+	return id
+}
+
+// edgeLabels returns the roles of the children of the expression
+func edgeLabels(expr Expr) []string {
+	switch e := expr.(type) {
+	case BinaryExpr:
+		return []string{"left", "right"}
+	case UnaryExpr, GroupingExpr:
+		return []string{"operand"}
+	case IdentifierExpr:
+		return []string{"receiver"}
+	case LambdaExpr:
+		return []string{"body"}
+	case NamedArgExpr:
+		return []string{"value"}
+	case LetExpr:
+		return []string{"value", "body"}
+	case TemplateExpr:
+		labels := make([]string, len(e.Parts))
+		for i := range labels {
+			labels[i] = fmt.Sprintf("part %d", i)
+		}
+		return labels
+	case CallExpr:
+		var labels []string
+		if id, ok := e.Name.(IdentifierExpr); ok && id.Expr != nil {
+			labels = append(labels, "receiver")
+		}
+		for i := range e.Args {
+			labels = append(labels, fmt.Sprintf("arg %d", i))
+		}
+		return labels
+	}
+	return nil
+}
+
+func operatorName(expr Expr) string {
+	switch e := expr.(type) {
+	case GroupingExpr:
+		return "group"
+	case UnaryExpr:
+		return ops[e.Operator.Type]
+	case BinaryExpr:
+		return ops[e.Operator.Type]
+	case LambdaExpr:
+		return "=> " + e.Param
+	case NamedArgExpr:
+		return ": " + e.Name
+	case LetExpr:
+		return "let " + e.Name
+	case TemplateExpr:
+		return "template"
+	}
+	return fmt.Sprintf("%T", expr)
+}
+
+// operatorLabel returns the canonical operator followed by the lexeme if it differs, e.g. "&& (and)"
+func operatorLabel(t Token) string {
+	op := ops[t.Type]
+	if t.Lexeme != "" && t.Lexeme != op {
+		return fmt.Sprintf("%s (%s)", op, t.Lexeme)
+	}
+	return op
+}
+
+func spanLabel(t Token) string {
+	if t.Lexeme == "" {
+		return "span unknown"
+	}
+	return fmt.Sprintf("span [%d:%d]", t.Pos, t.Pos+utf8.RuneCountInString(t.Lexeme))
+}
+
+func literalString(expr Expr) string {
+	s, err := Print(expr)
+	if err != nil {
+		return fmt.Sprintf("%v", expr)
+	}
+	return s
+}
+
+func formatTraceValue(v traceValue) string {
+	if v.err != nil {
+		return fmt.Sprintf("error(%s)", quote(v.err.Error()))
+	}
+	if lit, ok := valueLiteral(v.value); ok {
+		return literalString(lit)
+	}
+	if ctx, ok := v.value.(Context); ok {
+		return contextString(ctx)
+	}
+	return fmt.Sprintf("%v", v.value)
+}
+
+// contextString describes a context value by its names, e.g. context{age, name}
+func contextString(ctx Context) string {
+	if lister, ok := ctx.(interface{ Names() []string }); ok {
+		return fmt.Sprintf("context{%s}", strings.Join(lister.Names(), ", "))
+	}
+	return "context"
+}
+
+func escapeDot(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+}
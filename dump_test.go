@@ -0,0 +1,132 @@
+package goexp
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/svstanev/goexp/types"
+)
+
+func TestDumpSExpr(t *testing.T) {
+	tests := []struct {
+		expr     string
+		expected string
+	}{
+		{"a == 1 || b > 2", "(|| (== a 1) (> b 2))"},
+		{"a or b and c", "(|| a (&& b c))"},
+		{"(1 + 2) * 3", "(* (group (+ 1 2)) 3)"},
+		{"1 - 2 - 3", "(- (- 1 2) 3)"},
+		{"2 ** 3 ** 2", "(** (** 2 3) 2)"},
+		{"!a", "(! a)"},
+		{"-x.y.z", "(- (. (. x y) z))"},
+		{"f()", "(call f)"},
+		{"a.f(1, 'x', nil, true, 2.5)", "(call (. a f) 1 \"x\" nil true 2.5)"},
+		{"count(xs, x => x.y > 1)", "(call count xs (=> x (> (. x y) 1)))"},
+		{"round(x, digits: 2)", "(call round x (: digits 2))"},
+		{"let a = 1; a + b", "(let a 1 (+ a b))"},
+		{"`a${b}c`", "(template \"a\" b \"c\")"},
+	}
+
+	for _, test := range tests {
+		expr, err := Parse(test.expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if actual := DumpSExpr(expr); actual != test.expected {
+			t.Errorf("%s: expected %s but got %s", test.expr, test.expected, actual)
+		}
+	}
+}
+
+func TestDumpSExprWithValues(t *testing.T) {
+	ctx := NewEvalContext(nil)
+	ctx.AddName("a", types.NewInteger(1))
+	ctx.AddName("b", types.NewInteger(3))
+	ctx.AddMethod("f", func(x types.Integer) types.Integer { return x * 2 })
+
+	tests := []struct {
+		expr     string
+		expected string
+	}{
+		{"a == 1 || b > 2", "(|| (== a=1 1)=true (> b=3 2)=true)=true"},
+		{"f(a + 1)", "(call f (+ a=1 1)=2)=4"},
+		{"-(b)", "(- (group b=3)=3)=-3"},
+		{"a + c + b", "(+ (+ a=1 c=error(\"c not defined\"))=error(\"c not defined\") b)=error(\"c not defined\")"},
+	}
+
+	for _, test := range tests {
+		expr, err := Parse(test.expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, trace, _ := EvalTrace(expr, ctx)
+		if actual := DumpSExpr(expr, WithValues(trace)); actual != test.expected {
+			t.Errorf("%s: expected %s but got %s", test.expr, test.expected, actual)
+		}
+	}
+}
+
+func TestEvalTrace(t *testing.T) {
+	expr, err := Parse("1 + 2 * 3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, trace, err := EvalTrace(expr, NewEvalContext(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res != types.NewInteger(7) {
+		t.Errorf("Expected 7 but got %v", res)
+	}
+	for _, path := range [][]int{{0}, {0, 0}, {0, 1}, {0, 1, 0}, {0, 1, 1}} {
+		if _, ok := trace.lookup(path); !ok {
+			t.Errorf("Expected a value for %v", path)
+		}
+	}
+	if len(trace.values) != 5 {
+		t.Errorf("Expected 5 values but got %d", len(trace.values))
+	}
+}
+
+func TestDumpDot(t *testing.T) {
+	expr, err := Parse(`a and f("x\"y")`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `digraph expr {
+	node [shape=box, fontname="monospace"];
+	n0 [label="BinaryExpr\n&& (and)\nspan [2:5]"];
+	n1 [label="IdentifierExpr\na"];
+	n0 -> n1 [label="left"];
+	n2 [label="CallExpr\nf"];
+	n3 [label="StringLiteralExpr\n\"x\\\"y\""];
+	n2 -> n3 [label="arg 0"];
+	n0 -> n2 [label="right"];
+}
+`
+	if actual := DumpDot(expr); actual != expected {
+		t.Errorf("Expected\n%s\nbut got\n%s", expected, actual)
+	}
+}
+
+func TestDumpDotWithValues(t *testing.T) {
+	ctx := NewEvalContext(nil)
+	ctx.AddName("a", types.NewBoolean(false))
+
+	expr, err := Parse("a || b || c")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, trace, _ := EvalTrace(expr, ctx)
+	dot := DumpDot(expr, WithValues(trace))
+
+	for _, s := range []string{
+		`n2 [label="IdentifierExpr\na\n= false"];`,
+		`n3 [label="IdentifierExpr\nb\n= error(\"b not defined\")", color=red];`,
+		`n4 [label="IdentifierExpr\nc", style=dashed];`,
+	} {
+		if !strings.Contains(dot, s) {
+			t.Errorf("Expected %s in\n%s", s, dot)
+		}
+	}
+}
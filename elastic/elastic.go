@@ -0,0 +1,171 @@
+/*
+Package elastic translates goexp expressions to Elasticsearch queries.
+
+	expr, _ := goexp.Parse("age >= 18 && city == 'Sofia'")
+	query, residual, err := elastic.Query(expr, nil)
+	// query: {"bool": {"filter": [{"range": {"age": {"gte": 18}}}, {"term": {"city": "Sofia"}}]}}
+
+The supported expressions are comparisons of fields with constants,
+in(field, value1, value2, ...), boolean fields and their combinations with
+&&, || and !. The queries are built in filter context, i.e. they don't score.
+*/
+package elastic
+
+import (
+	"strings"
+
+	"github.com/svstanev/goexp"
+	"github.com/svstanev/goexp/internal/pushdown"
+)
+
+// FieldMapper maps the path of an identifier (e.g. ["user", "age"] for user.age) to a field name
+type FieldMapper = pushdown.FieldMapper
+
+var ranges = map[goexp.TokenType]string{
+	goexp.Less:         "lt",
+	goexp.LessEqual:    "lte",
+	goexp.Greater:      "gt",
+	goexp.GreaterEqual: "gte",
+}
+
+/*
+Query translates the expression to an Elasticsearch query.
+
+The conjuncts of the expression that cannot be translated are returned as
+residual expressions; the query then matches a superset of the documents and
+the residual expressions must be evaluated on the results. By default the
+fields are named by joining the path of the identifiers with ".".
+*/
+func Query(expr goexp.Expr, fields FieldMapper) (map[string]interface{}, []goexp.Expr, error) {
+	if fields == nil {
+		fields = func(path []string) (string, error) {
+			return strings.Join(path, "."), nil
+		}
+	}
+	t := &translator{fields}
+
+	var queries []interface{}
+	var residual []goexp.Expr
+	for _, x := range pushdown.Operands(expr, goexp.And) {
+		query, ok, err := t.translate(x)
+		if err != nil {
+			return nil, nil, err
+		}
+		if ok {
+			queries = append(queries, query)
+		} else {
+			residual = append(residual, x)
+		}
+	}
+
+	switch len(queries) {
+	case 0:
+		return map[string]interface{}{"match_all": map[string]interface{}{}}, residual, nil
+	case 1:
+		return queries[0].(map[string]interface{}), residual, nil
+	default:
+		return boolQuery("filter", queries), residual, nil
+	}
+}
+
+func boolQuery(occur string, queries []interface{}) map[string]interface{} {
+	q := map[string]interface{}{occur: queries}
+	if occur == "should" {
+		q["minimum_should_match"] = 1
+	}
+	return map[string]interface{}{"bool": q}
+}
+
+func not(query interface{}) map[string]interface{} {
+	return boolQuery("must_not", []interface{}{query})
+}
+
+type translator struct {
+	fields FieldMapper
+}
+
+// translate returns false if the expression cannot be translated
+func (t *translator) translate(expr goexp.Expr) (map[string]interface{}, bool, error) {
+	if c, ok := pushdown.AsComparison(expr); ok {
+		field, err := t.fields(c.Path)
+		if err != nil {
+			return nil, false, err
+		}
+		return comparison(field, c), true, nil
+	}
+
+	if path, values, ok := pushdown.AsIn(expr); ok {
+		field, err := t.fields(path)
+		if err != nil {
+			return nil, false, err
+		}
+		return map[string]interface{}{"terms": map[string]interface{}{field: values}}, true, nil
+	}
+
+	switch e := pushdown.Ungroup(expr).(type) {
+	case goexp.IdentifierExpr:
+		path, ok := goexp.Path(e)
+		if !ok {
+			return nil, false, nil
+		}
+		field, err := t.fields(path)
+		if err != nil {
+			return nil, false, err
+		}
+		return term(field, true), true, nil
+
+	case goexp.UnaryExpr:
+		if e.Operator.Type != goexp.Not {
+			return nil, false, nil
+		}
+		query, ok, err := t.translate(e.Value)
+		if !ok || err != nil {
+			return nil, false, err
+		}
+		return not(query), true, nil
+
+	case goexp.BinaryExpr:
+		var occur string
+		switch e.Operator.Type {
+		case goexp.And:
+			occur = "filter"
+		case goexp.Or:
+			occur = "should"
+		default:
+			return nil, false, nil
+		}
+		var queries []interface{}
+		for _, x := range pushdown.Operands(e, e.Operator.Type) {
+			query, ok, err := t.translate(x)
+			if !ok || err != nil {
+				return nil, false, err
+			}
+			queries = append(queries, query)
+		}
+		return boolQuery(occur, queries), true, nil
+	}
+
+	return nil, false, nil
+}
+
+func term(field string, value interface{}) map[string]interface{} {
+	return map[string]interface{}{"term": map[string]interface{}{field: value}}
+}
+
+func comparison(field string, c pushdown.Comparison) map[string]interface{} {
+	exists := map[string]interface{}{"exists": map[string]interface{}{"field": field}}
+	switch c.Op {
+	case goexp.Equal:
+		if c.Value == nil {
+			return not(exists)
+		}
+		return term(field, c.Value)
+	case goexp.NotEqual:
+		if c.Value == nil {
+			return exists
+		}
+		return not(term(field, c.Value))
+	default:
+		return map[string]interface{}{"range": map[string]interface{}{field: map[string]interface{}{ranges[c.Op]: c.Value}}}
+	}
+}
@@ -0,0 +1,60 @@
+package elastic
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/svstanev/goexp"
+)
+
+func TestQuery(t *testing.T) {
+	tests := []struct {
+		expr     string
+		query    string
+		residual []string
+	}{
+		{"age >= 18", `{"range":{"age":{"gte":18}}}`, nil},
+		{"18 < age", `{"range":{"age":{"gt":18}}}`, nil},
+		{"city == 'Sofia'", `{"term":{"city":"Sofia"}}`, nil},
+		{"city != 'Sofia'", `{"bool":{"must_not":[{"term":{"city":"Sofia"}}]}}`, nil},
+		{"email == nil", `{"bool":{"must_not":[{"exists":{"field":"email"}}]}}`, nil},
+		{"email != nil", `{"exists":{"field":"email"}}`, nil},
+		{"vip", `{"term":{"vip":true}}`, nil},
+		{"in(tag, 'a', 'b')", `{"terms":{"tag":["a","b"]}}`, nil},
+		{
+			"age >= 18 && (city == 'Sofia' || !vip)",
+			`{"bool":{"filter":[{"range":{"age":{"gte":18}}},{"bool":{"minimum_should_match":1,"should":[{"term":{"city":"Sofia"}},{"bool":{"must_not":[{"term":{"vip":true}}]}}]}}]}}`,
+			nil,
+		},
+		{"a == 1 && f(b)", `{"term":{"a":1}}`, []string{"f(b)"}},
+		{"a == b", `{"match_all":{}}`, []string{"a == b"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.expr, func(t *testing.T) {
+			expr, err := goexp.Parse(test.expr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			query, residual, err := Query(expr, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, err := json.Marshal(query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != test.query {
+				t.Errorf("Expected %s but got %s", test.query, data)
+			}
+			if len(residual) != len(test.residual) {
+				t.Fatalf("Expected residual %v but got %v", test.residual, residual)
+			}
+			for i, x := range residual {
+				if s, _ := goexp.Print(x); s != test.residual[i] {
+					t.Errorf("Expected residual %s but got %s", test.residual[i], s)
+				}
+			}
+		})
+	}
+}
@@ -3,126 +3,46 @@ package goexp
 import (
 	"fmt"
 	"reflect"
+	"strings"
 
 	"github.com/svstanev/goexp/types"
 )
 
-type Var interface {
-	Value() (interface{}, error)
-}
-
-type varx struct {
-	value interface{}
-}
-
-func (v varx) Value() (interface{}, error) {
-	return v.value, nil
-}
-
-type Method interface {
-	Invoke(args []interface{}) (interface{}, error)
-}
-
-type methodx struct {
-	fn interface{}
-}
-
-func (m methodx) Invoke(args []interface{}) (interface{}, error) {
-	fn := reflect.ValueOf(m.fn)
-	in := make([]reflect.Value, len(args))
-	for i, value := range args {
-		in[i] = reflect.ValueOf(value)
-	}
-	res := fn.Call(in)
-	return res[0].Interface(), nil
-}
-
-type Context interface {
-	ResolveName(name string) (Var, bool)
-	ResolveMethod(name string) (Method, bool)
-}
-
-type EvalContext interface {
-	Context
-	AddName(name string, value interface{}) error
-	AddMethod(name string, fn interface{}) error
-}
-
-type context struct {
-	parent  Context
-	vars    map[string]Var
-	methods map[string]Method
-}
-
-func NewEvalContext(parent Context) EvalContext {
-	return &context{
-		parent:  parent,
-		vars:    make(map[string]Var),
-		methods: make(map[string]Method),
-	}
-}
-
-func (ctx *context) AddName(name string, value interface{}) error {
-	if _, present := ctx.vars[name]; present {
-		return fmt.Errorf("Var %s already exists", name)
-	}
-	ctx.vars[name] = varx{value}
-	return nil
-}
-
-func (ctx *context) AddMethod(name string, fn interface{}) error {
-	if _, present := ctx.methods[name]; present {
-		return fmt.Errorf("Method %s already exists", name)
-	}
-	ctx.methods[name] = methodx{fn}
-	return nil
-}
-
-func (ctx *context) ResolveName(name string) (Var, bool) {
-	if x, ok := ctx.vars[name]; ok {
-		return x, true
-	}
-	if ctx.parent != nil {
-		return ctx.parent.ResolveName(name)
-	}
-	return nil, false
-}
-
-func (ctx *context) ResolveMethod(name string) (Method, bool) {
-	if m, ok := ctx.methods[name]; ok {
-		return m, true
-	}
-	if ctx.parent != nil {
-		return ctx.parent.ResolveMethod(name)
-	}
-	return nil, false
-}
-
 // type Function func(args ...[]interface{}) (interface{}, error)
 
 type interpreter struct {
 	context Context
+	tracer  Tracer
 }
 
 func newInterpreter(context Context) *interpreter {
 	return &interpreter{
-		context,
+		context: context,
 	}
 }
 
 func (i *interpreter) eval(expr Expr) (interface{}, error) {
 	e := newEvaluator()
+	e.tracer = i.tracer
 	return e.Eval(expr, i.context)
 }
 
-type evaluator struct{}
+type evaluator struct {
+	tracer Tracer
+}
 
 func newEvaluator() *evaluator {
 	return &evaluator{}
 }
 
 func (eval *evaluator) Eval(expr Expr, context VisitorContext) (res interface{}, err error) {
-	return expr.Accept(eval, context)
+	if eval.tracer == nil {
+		return expr.Accept(eval, context)
+	}
+	eval.tracer.Enter(expr)
+	res, err = expr.Accept(eval, context)
+	eval.tracer.Exit(expr, res, err)
+	return res, err
 }
 
 func (eval *evaluator) VisitStringLiteralExpr(expr StringLiteralExpr, context VisitorContext) (interface{}, error) {
@@ -198,9 +118,14 @@ func (eval *evaluator) VisitCallExpr(e CallExpr, context VisitorContext) (interf
 		}
 	}
 
+	// x.m(args) is m(x, args) for values that are not contexts
+	var receiver []interface{}
 	ctx, ok := val.(Context)
 	if !ok {
-		return nil, fmt.Errorf("Cannot resolve method %s", id.Name)
+		if ctx, ok = context.(Context); !ok {
+			return nil, fmt.Errorf("Cannot resolve method %s", id.Name)
+		}
+		receiver = []interface{}{val}
 	}
 
 	m, ok := ctx.ResolveMethod(id.Name)
@@ -208,12 +133,33 @@ func (eval *evaluator) VisitCallExpr(e CallExpr, context VisitorContext) (interf
 		return nil, fmt.Errorf("Method not found %s", id.Name)
 	}
 
-	args, err := eval.EvalMany(e.Args, context)
-	if err != nil {
-		return nil, err
+	var args []interface{}
+	var named []NamedArg
+	for _, arg := range e.Args {
+		n, isNamed := arg.(NamedArgExpr)
+		if !isNamed && len(named) > 0 {
+			return nil, fmt.Errorf("Positional argument after named arguments of %s", id.Name)
+		}
+		value, err := eval.Eval(arg, context)
+		if err != nil {
+			return nil, err
+		}
+		if isNamed {
+			named = append(named, NamedArg{Name: n.Name, Value: value})
+		} else {
+			args = append(args, value)
+		}
 	}
 
-	return m.Invoke(args)
+	args = append(receiver, args...)
+	if len(named) == 0 {
+		return m.Invoke(args)
+	}
+	nm, ok := m.(NamedMethod)
+	if !ok {
+		return nil, fmt.Errorf("Method %s does not accept named arguments", id.Name)
+	}
+	return nm.InvokeNamed(args, named)
 }
 
 func (eval *evaluator) VisitIdentifierExpr(e IdentifierExpr, context VisitorContext) (interface{}, error) {
@@ -224,19 +170,101 @@ func (eval *evaluator) VisitIdentifierExpr(e IdentifierExpr, context VisitorCont
 			return nil, err
 		}
 	}
+	return member(val, e.Name)
+}
+
+// member returns the named member of a context; the member of a list (any Go
+// slice or array) is the list of the members of its elements, e.g. items.price
+func member(val interface{}, name string) (interface{}, error) {
 	if ctx, ok := val.(Context); ok {
-		if n, present := ctx.ResolveName(e.Name); present {
+		if n, present := ctx.ResolveName(name); present {
 			return n.Value()
 		}
-		return nil, fmt.Errorf("%s not defined", e.Name)
+		return nil, fmt.Errorf("%s not defined", name)
+	}
+	if v := reflect.ValueOf(val); v.Kind() == reflect.Slice || v.Kind() == reflect.Array {
+		res := make([]interface{}, v.Len())
+		for i := range res {
+			var err error
+			if res[i], err = member(v.Index(i).Interface(), name); err != nil {
+				return nil, err
+			}
+		}
+		return res, nil
 	}
-	return nil, fmt.Errorf("Cannot resolve %s", e.Name)
+	return nil, fmt.Errorf("Cannot resolve %s", name)
 }
 
 func (eval *evaluator) VisitGroupingExpr(e GroupingExpr, context VisitorContext) (interface{}, error) {
 	return eval.Eval(e.Expr, context)
 }
 
+// VisitLetExpr evaluates the value once and the body in a child scope with the value bound to the name
+func (eval *evaluator) VisitLetExpr(e LetExpr, context VisitorContext) (interface{}, error) {
+	value, err := eval.Eval(e.Value, context)
+	if err != nil {
+		return nil, err
+	}
+	parent, _ := context.(Context)
+	scope := NewEvalContext(parent)
+	if err := scope.AddName(e.Name, value); err != nil {
+		return nil, err
+	}
+	return eval.Eval(e.Body, scope)
+}
+
+// VisitTemplateExpr concatenates the text representations of the parts
+func (eval *evaluator) VisitTemplateExpr(e TemplateExpr, context VisitorContext) (interface{}, error) {
+	values, err := eval.EvalMany(e.Parts, context)
+	if err != nil {
+		return nil, err
+	}
+	return templateString(values)
+}
+
+// templateString concatenates the text representations of the values
+func templateString(values []interface{}) (types.String, error) {
+	var b strings.Builder
+	for _, value := range values {
+		s, err := types.ToString(value)
+		if err != nil {
+			return "", err
+		}
+		b.WriteString(s)
+	}
+	return types.String(b.String()), nil
+}
+
+// VisitNamedArgExpr returns the value of the argument; VisitCallExpr binds it to the parameter
+func (eval *evaluator) VisitNamedArgExpr(e NamedArgExpr, context VisitorContext) (interface{}, error) {
+	return eval.Eval(e.Value, context)
+}
+
+func (eval *evaluator) VisitLambdaExpr(e LambdaExpr, context VisitorContext) (interface{}, error) {
+	ctx, _ := context.(Context)
+	return &Lambda{param: e.Param, body: e.Body, context: ctx, eval: eval}, nil
+}
+
+// Lambda is the value of a lambda expression, e.g. o => o.paid. Methods receive
+// lambdas as arguments of type interface{} or *Lambda and call them with the
+// elements of a collection.
+type Lambda struct {
+	param   string
+	body    Expr
+	context Context
+	eval    *evaluator
+}
+
+// Call evaluates the body of the lambda with the parameter bound to arg in a
+// child of the context the lambda was created in
+func (l *Lambda) Call(arg interface{}) (interface{}, error) {
+	ctx := NewEvalContext(l.context)
+	if err := ctx.AddName(l.param, arg); err != nil {
+		return nil, err
+	}
+	return l.eval.Eval(l.body, ctx)
+}
+
 // type Lazy func() (interface{}, error)
 
 func unaryOp(x interface{}, op Token) (interface{}, error) {
@@ -250,7 +278,13 @@ func unaryOp(x interface{}, op Token) (interface{}, error) {
 	}
 }
 
-func binaryOp(x, y interface{}, op Token) (interface{}, error) {
+func binaryOp(x, y interface{}, op Token) (res interface{}, err error) {
+	defer func() {
+		if e, ok := err.(types.NotSupportedError); ok {
+			err = binaryOpNotSupportedError(x, y, e.Op)
+		}
+	}()
+
 	switch op.Type {
 	case Add:
 		return add(x, y)
@@ -273,7 +307,7 @@ func binaryOp(x, y interface{}, op Token) (interface{}, error) {
 	case GreaterEqual:
 		return gte(x, y)
 	case Equal:
-		return equals(x, y)
+		return eq(x, y)
 	case NotEqual:
 		return ne(x, y)
 	case And:
@@ -288,6 +322,8 @@ func binaryOp(x, y interface{}, op Token) (interface{}, error) {
 func toBoolean(x interface{}) (b types.Boolean, ok bool) {
 	if b, ok = x.(types.Boolean); ok {
 		// all good
+	} else if v, isBool := x.(bool); isBool {
+		b, ok = types.NewBoolean(v), true
 	} else if bc, ok := x.(types.BooleanConverter); ok {
 		b = bc.ToBoolean()
 	} else {
@@ -322,49 +358,58 @@ func or(x, y interface{}) (res interface{}, err error) {
 	return
 }
 
-func ne(x, y interface{}) (bool, error) {
+func eq(x, y interface{}) (interface{}, error) {
 	var res bool
 	var err error
 	if res, err = equals(x, y); err != nil {
-		return false, err
+		return nil, err
 	}
-	return !res, nil
+	return types.NewBoolean(res), nil
 }
 
-func lt(x, y interface{}) (bool, error) {
+func ne(x, y interface{}) (interface{}, error) {
+	var res bool
+	var err error
+	if res, err = equals(x, y); err != nil {
+		return nil, err
+	}
+	return types.NewBoolean(!res), nil
+}
+
+func lt(x, y interface{}) (interface{}, error) {
 	var res int
 	var err error
 	if res, err = compare(x, y); err != nil {
-		return false, err
+		return nil, err
 	}
-	return res < 0, nil
+	return types.NewBoolean(res < 0), nil
 }
 
-func lte(x, y interface{}) (bool, error) {
+func lte(x, y interface{}) (interface{}, error) {
 	var res int
 	var err error
 	if res, err = compare(x, y); err != nil {
-		return false, err
+		return nil, err
 	}
-	return res <= 0, nil
+	return types.NewBoolean(res <= 0), nil
 }
 
-func gt(x, y interface{}) (bool, error) {
+func gt(x, y interface{}) (interface{}, error) {
 	var res int
 	var err error
 	if res, err = compare(x, y); err != nil {
-		return false, err
+		return nil, err
 	}
-	return res > 0, nil
+	return types.NewBoolean(res > 0), nil
 }
 
-func gte(x, y interface{}) (bool, error) {
+func gte(x, y interface{}) (interface{}, error) {
 	var res int
 	var err error
 	if res, err = compare(x, y); err != nil {
-		return false, err
+		return nil, err
 	}
-	return res >= 0, nil
+	return types.NewBoolean(res >= 0), nil
 }
 
 func add(x, y interface{}) (result interface{}, err error) {
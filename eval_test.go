@@ -31,6 +31,22 @@ func TestEval(t *testing.T) {
 	ctx.AddMethod("max", func(values ...types.Integer) (types.Integer, error) {
 		return reduce(values, max, math.MinInt64), nil
 	})
+	ctx.AddMethod("apply", func(f *Lambda, arg interface{}) (interface{}, error) {
+		return f.Call(arg)
+	})
+	items := make([]Context, 2)
+	for i := range items {
+		item := NewEvalContext(nil)
+		item.AddName("price", types.Integer(i+1))
+		items[i] = item
+	}
+	ctx.AddName("items", items)
+	ctx.AddMethod("label", func(value interface{}, opts struct {
+		Prefix string
+		Width  int `goexp:"w"`
+	}) (interface{}, error) {
+		return types.String(fmt.Sprintf("%s%*v", opts.Prefix, opts.Width, value)), nil
+	})
 
 	tests := []struct {
 		expr   string
@@ -42,7 +58,42 @@ func TestEval(t *testing.T) {
 		{"2 ** 3", types.Integer(8), nil},
 		{"2.5 ** 3", types.Float(15.625), nil},
 
+		{"x.max(y, 0)", types.Integer(2), nil},
+		{"(x + y).max(5)", types.Integer(5), nil},
+		{"(3).max()", types.Integer(3), nil},
+
+		{"items.price", []interface{}{types.Integer(1), types.Integer(2)}, nil},
+		{"apply(v => v * x + y, 3)", types.Integer(5), nil},
+		{"apply(x => x * 10, 3)", types.Integer(30), nil},
+		{"apply(v => apply(w => v + w, 1), 2)", types.Integer(3), nil},
+		{"apply(i => i.price, items)", []interface{}{types.Integer(1), types.Integer(2)}, nil},
+
 		{"1 < 'foo'", nil, binaryOpNotSupportedError(types.Integer(1), types.String("foo"), "cmp")},
+		{"x.min()", nil, fmt.Errorf("Method not found min")},
+		{"x.price", nil, fmt.Errorf("Cannot resolve price")},
+		{"items.cost", nil, fmt.Errorf("cost not defined")},
+
+		{"let z = x + y; z * z", types.Integer(9), nil},
+		{"let x = x + 1; let x = x * 10; x + y", types.Integer(22), nil},
+		{"let f = v => v + x; apply(f, 2)", types.Integer(3), nil},
+		{"(let z = 5; z) + x", types.Integer(6), nil},
+		{"(let z = 5; z) + z", nil, fmt.Errorf("z not defined")},
+		{"let z = nope; 1", nil, fmt.Errorf("nope not defined")},
+
+		{"`${x} + ${y} = ${x + y}`", types.String("1 + 2 = 3"), nil},
+		{"`a${nil}b ${true} ${2.5 * 2} ${1e30}`", types.String("ab true 5 1e+30"), nil},
+		{"`prices: ${items.price}`", types.String("prices: [1, 2]"), nil},
+		{"`${`${x}` + 1}`", types.String("11"), nil},
+		{"let s = `${x}`; s + s", types.String("11"), nil},
+		{"'x=' + x", types.String("x=1"), nil},
+		{"`${nope}`", nil, fmt.Errorf("nope not defined")},
+
+		{"label(x)", types.String("Integer(1)"), nil},
+		{"label(y, prefix: '#')", types.String("#Integer(2)"), nil},
+		{"x.label(w: 11, prefix: '>')", types.String("> Integer(1)"), nil},
+		{"label(x, width: 2)", nil, fmt.Errorf("Unknown argument width")},
+		{"label(x, w: 'a')", nil, fmt.Errorf("Cannot use types.String as int in argument w")},
+		{"max(x, y: 1)", nil, fmt.Errorf("Cannot use named arguments with func(...types.Integer) (types.Integer, error)")},
 	}
 
 	for i, test := range tests {
@@ -68,6 +119,26 @@ func TestEval(t *testing.T) {
 	// }
 }
 
+func TestLetEvaluatesOnce(t *testing.T) {
+	calls := 0
+	ctx := NewEvalContext(nil)
+	ctx.AddMethod("next", func() types.Integer {
+		calls++
+		return types.Integer(calls)
+	})
+
+	res, err := EvalString("let n = next(); n + n * 10", ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res != types.Integer(11) || calls != 1 {
+		t.Errorf("Expected 11 with one call but got %v with %d calls", res, calls)
+	}
+	if names := ctx.Names(); len(names) != 0 {
+		t.Errorf("Expected the binding not to leak into the context but got %v", names)
+	}
+}
+
 func eval(expr string, context Context) (interface{}, error) {
 	scanner := newScanner(expr)
 	tokens, err := scanner.scan()
@@ -0,0 +1,199 @@
+package goexp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/svstanev/goexp/types"
+)
+
+// ExplainTree is a Tracer recording the value of every evaluated node of an expression
+type ExplainTree struct {
+	Root  *ExplainNode
+	stack []*ExplainNode
+}
+
+// ExplainNode is an evaluated node with its value or error and its evaluated children in order
+type ExplainNode struct {
+	Expr     Expr
+	Value    interface{}
+	Err      error
+	Children []*ExplainNode
+}
+
+// Explain evaluates the expression and returns the tree explaining its result
+func Explain(expr Expr, context Context) (interface{}, *ExplainTree, error) {
+	t := NewExplainTree()
+	res, err := EvalWithTracer(expr, context, t)
+	return res, t, err
+}
+
+// NewExplainTree returns an empty tree to be used as a Tracer
+func NewExplainTree() *ExplainTree {
+	return &ExplainTree{}
+}
+
+// Enter implements Tracer
+func (t *ExplainTree) Enter(expr Expr) {
+	n := &ExplainNode{Expr: expr}
+	if len(t.stack) == 0 {
+		t.Root = n
+	} else {
+		parent := t.stack[len(t.stack)-1]
+		parent.Children = append(parent.Children, n)
+	}
+	t.stack = append(t.stack, n)
+}
+
+// Exit implements Tracer
+func (t *ExplainTree) Exit(expr Expr, value interface{}, err error) {
+	n := t.stack[len(t.stack)-1]
+	n.Value, n.Err = value, err
+	t.stack = t.stack[:len(t.stack)-1]
+}
+
+/*
+String renders the tree as indented text, one line per evaluated subexpression
+with its result and the values of the names it uses, e.g.
+
+	age > 18 && country == "BG" → false
+	  age > 18 → false (age = 16)
+	  country == "BG" → true (country = "BG")
+*/
+func (t *ExplainTree) String() string {
+	var sb strings.Builder
+	if t.Root != nil {
+		writeExplainNode(&sb, t.Root, 0)
+	}
+	return sb.String()
+}
+
+func writeExplainNode(sb *strings.Builder, n *ExplainNode, depth int) {
+	n = n.ungroup()
+	fmt.Fprintf(sb, "%s%s → %s", strings.Repeat("  ", depth), exprSource(n.Expr), n.result())
+
+	var names []string
+	var nested []*ExplainNode
+	seen := map[string]bool{}
+	for _, c := range n.Children {
+		c = c.ungroup()
+		switch c.Expr.(type) {
+		case StringLiteralExpr, IntegerLiteralExpr, FloatLiteralExpr, BooleanLiteralExpr, NilLiteralExpr:
+			continue
+		}
+		if _, ok := Path(c.Expr); !ok {
+			nested = append(nested, c)
+			continue
+		}
+		name := fmt.Sprintf("%s = %s", exprSource(c.Expr), c.result())
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	if len(names) > 0 {
+		fmt.Fprintf(sb, " (%s)", strings.Join(names, ", "))
+	}
+	sb.WriteByte('\n')
+
+	for _, c := range nested {
+		writeExplainNode(sb, c, depth+1)
+	}
+}
+
+// ungroup returns the node of the expression inside a grouping
+func (n *ExplainNode) ungroup() *ExplainNode {
+	for {
+		if _, ok := n.Expr.(GroupingExpr); !ok || len(n.Children) != 1 {
+			return n
+		}
+		n = n.Children[0]
+	}
+}
+
+func (n *ExplainNode) result() string {
+	if n.Err != nil {
+		return "error: " + n.Err.Error()
+	}
+	return formatTraceValue(traceValue{n.Value, nil})
+}
+
+func exprSource(expr Expr) string {
+	s, err := Print(expr)
+	if err != nil {
+		return fmt.Sprintf("%T", expr)
+	}
+	return s
+}
+
+type explainJSON struct {
+	Type     string         `json:"type"`
+	Expr     string         `json:"expr"`
+	Value    interface{}    `json:"value"`
+	Error    string         `json:"error,omitempty"`
+	Children []*explainJSON `json:"children,omitempty"`
+}
+
+/*
+MarshalJSON exports the tree as nested objects of the form
+
+	{"type": "BinaryExpr", "expr": "age > 18", "value": false, "children": [...]}
+
+with "error" instead of the value for the nodes that failed.
+*/
+func (t *ExplainTree) MarshalJSON() ([]byte, error) {
+	if t.Root == nil {
+		return []byte("null"), nil
+	}
+	var buf bytes.Buffer
+	e := json.NewEncoder(&buf)
+	e.SetEscapeHTML(false)
+	if err := e.Encode(t.Root.toJSON()); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+func (n *ExplainNode) toJSON() *explainJSON {
+	res := &explainJSON{
+		Type: strings.TrimPrefix(fmt.Sprintf("%T", n.Expr), "goexp."),
+		Expr: exprSource(n.Expr),
+	}
+	if n.Err != nil {
+		res.Error = n.Err.Error()
+	} else {
+		res.Value = jsonValue(n.Value)
+	}
+	for _, c := range n.Children {
+		res.Children = append(res.Children, c.toJSON())
+	}
+	return res
+}
+
+// jsonValue returns the value as a Go value that can be encoded as JSON
+func jsonValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case types.String:
+		return string(v)
+	case types.Integer:
+		return int64(v)
+	case types.Float:
+		if math.IsInf(float64(v), 0) || math.IsNaN(float64(v)) {
+			return fmt.Sprintf("%v", float64(v))
+		}
+		return float64(v)
+	case types.Boolean:
+		return bool(v)
+	case bool, string, int64, float64:
+		return v
+	case Context:
+		return contextString(v)
+	}
+	if types.IsNull(value) {
+		return nil
+	}
+	return fmt.Sprintf("%v", value)
+}
@@ -0,0 +1,133 @@
+package goexp
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/svstanev/goexp/types"
+)
+
+func newExplainContext() EvalContext {
+	ctx := NewEvalContext(nil)
+	ctx.AddName("age", types.NewInteger(16))
+	ctx.AddName("country", types.NewString("BG"))
+	user := NewEvalContext(nil)
+	user.AddName("name", types.NewString("John"))
+	ctx.AddName("user", user)
+	ctx.AddMethod("len", func(s types.String) types.Integer { return types.NewInteger(int64(len(s))) })
+	return ctx
+}
+
+func TestExplain(t *testing.T) {
+	tests := []struct {
+		expr     string
+		expected string
+	}{
+		{"age > 18", "age > 18 → false (age = 16)\n"},
+		{
+			"age > 18 && country == 'BG'",
+			"age > 18 && country == \"BG\" → false\n" +
+				"  age > 18 → false (age = 16)\n" +
+				"  country == \"BG\" → true (country = \"BG\")\n",
+		},
+		{
+			"!(age >= 16 || age < 10) || len(user.name) == 4",
+			"!(age >= 16 || age < 10) || len(user.name) == 4 → true\n" +
+				"  !(age >= 16 || age < 10) → false\n" +
+				"    age >= 16 || age < 10 → true\n" +
+				"      age >= 16 → true (age = 16)\n" +
+				"      age < 10 → false (age = 16)\n" +
+				"  len(user.name) == 4 → true\n" +
+				"    len(user.name) → 4 (user.name = \"John\")\n",
+		},
+		{"age * age", "age * age → 256 (age = 16)\n"},
+		{"1 + 2", "1 + 2 → 3\n"},
+		{
+			"age > 10 && foo",
+			"age > 10 && foo → error: foo not defined (foo = error: foo not defined)\n" +
+				"  age > 10 → true (age = 16)\n",
+		},
+	}
+
+	ctx := newExplainContext()
+	for _, test := range tests {
+		expr, err := Parse(test.expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, tree, _ := Explain(expr, ctx)
+		if actual := tree.String(); actual != test.expected {
+			t.Errorf("%s: expected\n%s\nbut got\n%s", test.expr, test.expected, actual)
+		}
+	}
+}
+
+func TestExplainJSON(t *testing.T) {
+	expr, err := Parse("age + 1 == x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, tree, _ := Explain(expr, newExplainContext())
+	data, err := json.Marshal(tree)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `{"type":"BinaryExpr","expr":"age + 1 == x","value":null,"error":"x not defined","children":[` +
+		`{"type":"BinaryExpr","expr":"age + 1","value":17,"children":[` +
+		`{"type":"IdentifierExpr","expr":"age","value":16},` +
+		`{"type":"IntegerLiteralExpr","expr":"1","value":1}]},` +
+		`{"type":"IdentifierExpr","expr":"x","value":null,"error":"x not defined"}]}`
+	actual := strings.NewReplacer(`>`, ">", `<`, "<", `&`, "&").Replace(string(data))
+	if actual != expected {
+		t.Errorf("Expected\n%s\nbut got\n%s", expected, actual)
+	}
+}
+
+type logTracer struct {
+	depth int
+	lines []string
+}
+
+func (l *logTracer) Enter(expr Expr) {
+	l.lines = append(l.lines, fmt.Sprintf("%s> %s", strings.Repeat(" ", l.depth), exprSource(expr)))
+	l.depth++
+}
+
+func (l *logTracer) Exit(expr Expr, value interface{}, err error) {
+	l.depth--
+	l.lines = append(l.lines, fmt.Sprintf("%s< %v %v", strings.Repeat(" ", l.depth), value, err))
+}
+
+func TestEvalWithTracer(t *testing.T) {
+	expr, err := Parse("-(age)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	log := &logTracer{}
+	trace := NewTrace()
+	res, err := EvalWithTracer(expr, newExplainContext(), MultiTracer(log, trace))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res != types.NewInteger(-16) {
+		t.Errorf("Expected -16 but got %v", res)
+	}
+
+	expected := []string{
+		"> -age",
+		" > age",
+		"  > age",
+		"  < Integer(16) <nil>",
+		" < Integer(16) <nil>",
+		"< Integer(-16) <nil>",
+	}
+	if strings.Join(log.lines, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected\n%s\nbut got\n%s", strings.Join(expected, "\n"), strings.Join(log.lines, "\n"))
+	}
+	if len(trace.values) != 3 {
+		t.Errorf("Expected 3 traced values but got %d", len(trace.values))
+	}
+}
@@ -1,3 +1,5 @@
 module github.com/svstanev/goexp
 
+go 1.16
+
 require github.com/go-test/deep v1.0.1
@@ -12,6 +12,29 @@ func Parse(expr string) (Expr, error) {
 	return parser.parse()
 }
 
+// Tokenize returns the tokens of the given string, the last one being EOF
+func Tokenize(expr string) ([]Token, error) {
+	return newScanner(expr).scan()
+}
+
+// ErrorPosition returns the position (in runes) of a syntax error returned by
+// Parse or Tokenize, or of the first type error returned by Check
+func ErrorPosition(err error) (int, bool) {
+	switch e := err.(type) {
+	case *scannerError:
+		return e.Pos, true
+	case parseError:
+		return e.token.Pos, true
+	case TypeError:
+		return e.Pos, e.Pos >= 0
+	case TypeErrors:
+		if len(e) > 0 {
+			return ErrorPosition(e[0])
+		}
+	}
+	return 0, false
+}
+
 // Eval returns the result of the evaluation of the given expression
 func Eval(expr Expr, context Context) (interface{}, error) {
 	in := newInterpreter(context)
@@ -0,0 +1,117 @@
+// Package pushdown contains helpers for translating expressions to the query languages of other systems
+package pushdown
+
+import "github.com/svstanev/goexp"
+
+// FieldMapper maps the path of an identifier (e.g. ["user", "age"] for user.age) to a field name
+type FieldMapper func(path []string) (string, error)
+
+// Comparison is a comparison of a field with a constant, e.g. age > 18
+type Comparison struct {
+	Path  []string
+	Op    goexp.TokenType
+	Value interface{}
+}
+
+// flipped maps the comparison operators to the ones used when the operands are swapped
+var flipped = map[goexp.TokenType]goexp.TokenType{
+	goexp.Equal:        goexp.Equal,
+	goexp.NotEqual:     goexp.NotEqual,
+	goexp.Less:         goexp.Greater,
+	goexp.LessEqual:    goexp.GreaterEqual,
+	goexp.Greater:      goexp.Less,
+	goexp.GreaterEqual: goexp.LessEqual,
+}
+
+// Ungroup returns the expression inside the grouping expressions
+func Ungroup(expr goexp.Expr) goexp.Expr {
+	for {
+		g, ok := expr.(goexp.GroupingExpr)
+		if !ok {
+			return expr
+		}
+		expr = g.Expr
+	}
+}
+
+// Operands splits a chain of the given binary operator, e.g. the conjuncts of a && b && c
+func Operands(expr goexp.Expr, op goexp.TokenType) []goexp.Expr {
+	if e, ok := Ungroup(expr).(goexp.BinaryExpr); ok && e.Operator.Type == op {
+		return append(Operands(e.Left, op), Operands(e.Right, op)...)
+	}
+	return []goexp.Expr{expr}
+}
+
+// Literal returns the Go value of a literal expression; nil is returned for the nil literal
+func Literal(expr goexp.Expr) (interface{}, bool) {
+	switch e := Ungroup(expr).(type) {
+	case goexp.StringLiteralExpr:
+		return e.Value, true
+	case goexp.IntegerLiteralExpr:
+		return e.Value, true
+	case goexp.FloatLiteralExpr:
+		return e.Value, true
+	case goexp.BooleanLiteralExpr:
+		return e.Value, true
+	case goexp.NilLiteralExpr:
+		return nil, true
+	case goexp.UnaryExpr:
+		if e.Operator.Type == goexp.Sub {
+			switch v := Ungroup(e.Value).(type) {
+			case goexp.IntegerLiteralExpr:
+				return -v.Value, true
+			case goexp.FloatLiteralExpr:
+				return -v.Value, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// AsComparison returns the comparison of a field with a constant, with the field on the left
+func AsComparison(expr goexp.Expr) (Comparison, bool) {
+	e, ok := Ungroup(expr).(goexp.BinaryExpr)
+	if !ok {
+		return Comparison{}, false
+	}
+	op, ok := flipped[e.Operator.Type]
+	if !ok {
+		return Comparison{}, false
+	}
+
+	if path, ok := goexp.Path(Ungroup(e.Left)); ok {
+		if value, ok := Literal(e.Right); ok {
+			return Comparison{path, e.Operator.Type, value}, true
+		}
+	}
+	if path, ok := goexp.Path(Ungroup(e.Right)); ok {
+		if value, ok := Literal(e.Left); ok {
+			return Comparison{path, op, value}, true
+		}
+	}
+	return Comparison{}, false
+}
+
+// AsIn returns the field and the values of a call to in(field, value1, value2, ...)
+func AsIn(expr goexp.Expr) ([]string, []interface{}, bool) {
+	e, ok := Ungroup(expr).(goexp.CallExpr)
+	if !ok || len(e.Args) == 0 {
+		return nil, nil, false
+	}
+	if id, ok := e.Name.(goexp.IdentifierExpr); !ok || id.Name != "in" || id.Expr != nil {
+		return nil, nil, false
+	}
+	path, ok := goexp.Path(Ungroup(e.Args[0]))
+	if !ok {
+		return nil, nil, false
+	}
+	values := make([]interface{}, 0, len(e.Args)-1)
+	for _, arg := range e.Args[1:] {
+		value, ok := Literal(arg)
+		if !ok {
+			return nil, nil, false
+		}
+		values = append(values, value)
+	}
+	return path, values, true
+}
@@ -0,0 +1,181 @@
+package stdlib
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+
+	"github.com/svstanev/goexp"
+	"github.com/svstanev/goexp/types"
+)
+
+// Sum and Avg are added by both the math and the aggregate packages: they
+// aggregate their number arguments or the elements of a list, e.g. sum(1, 2)
+// and sum(items, i => i.price)
+var (
+	Sum = Function{Name: "sum", Fn: sum, Sig: Optional(types.NumberType, types.AnyType), Shared: true}
+	Avg = Function{Name: "avg", Fn: avg, Sig: Optional(types.FloatType, types.AnyType), Shared: true}
+)
+
+// sum returns the sum of the elements of a list, or of the arguments if the first one is not a list
+func sum(args ...interface{}) (interface{}, error) {
+	values, err := operands("sum", args)
+	if err != nil {
+		return nil, err
+	}
+	nums, isFloat, err := Numbers("sum", values)
+	if err != nil {
+		return nil, err
+	}
+	if !isFloat {
+		var s int64
+		for _, n := range nums {
+			i := int64(n.(types.Integer))
+			if i > 0 && s > math.MaxInt64-i || i < 0 && s < math.MinInt64-i {
+				return nil, fmt.Errorf("Integer overflow in sum")
+			}
+			s += i
+		}
+		return types.Integer(s), nil
+	}
+	var s float64
+	for _, f := range Floats(nums) {
+		s += f
+	}
+	return Result("sum", s)
+}
+
+// avg returns the mean of the elements of a list, or of the arguments if the first one is not a list
+func avg(args ...interface{}) (interface{}, error) {
+	values, err := operands("avg", args)
+	if err != nil {
+		return nil, err
+	}
+	nums, _, err := Numbers("avg", values)
+	if err != nil {
+		return nil, err
+	}
+	if len(nums) == 0 {
+		return types.Null(), nil
+	}
+	return Result("avg", Mean(Floats(nums)))
+}
+
+// operands returns the values aggregated by sum and avg: the elements of a list
+// mapped by the optional lambda, or the arguments themselves
+func operands(name string, args []interface{}) ([]interface{}, error) {
+	if len(args) > 0 && isList(args[0]) {
+		return Collect(name, 2, args[0], args[1:])
+	}
+	values := make([]interface{}, len(args))
+	for i, arg := range args {
+		values[i] = types.FromGo(arg)
+	}
+	return values, nil
+}
+
+func isList(x interface{}) bool {
+	k := reflect.ValueOf(x).Kind()
+	return k == reflect.Slice || k == reflect.Array
+}
+
+// Collect returns the elements of a list mapped by the optional lambda, the
+// last of the arguments of a method with the given number of parameters
+func Collect(name string, params int, items interface{}, fn []interface{}) ([]interface{}, error) {
+	if len(fn) > 1 {
+		return nil, TooManyArguments(params, params-1+len(fn))
+	}
+	elems, err := Elements(name, items)
+	if err != nil || len(fn) == 0 {
+		return elems, err
+	}
+	f, err := Lambda(name, fn[0])
+	if err != nil {
+		return nil, err
+	}
+	res := make([]interface{}, len(elems))
+	for i, elem := range elems {
+		v, err := f.Call(elem)
+		if err != nil {
+			return nil, err
+		}
+		res[i] = types.FromGo(v)
+	}
+	return res, nil
+}
+
+// Elements returns the elements of a list (any Go slice or array) as values of the types package
+func Elements(name string, items interface{}) ([]interface{}, error) {
+	if !isList(items) {
+		return nil, fmt.Errorf("Invalid argument for %s: expected a list but got %T", name, items)
+	}
+	v := reflect.ValueOf(items)
+	res := make([]interface{}, v.Len())
+	for i := range res {
+		res[i] = types.FromGo(v.Index(i).Interface())
+	}
+	return res, nil
+}
+
+// Numbers returns the numbers of the values skipping nils; if any of them is a
+// Float, isFloat is true
+func Numbers(name string, values []interface{}) (nums []interface{}, isFloat bool, err error) {
+	nums = make([]interface{}, 0, len(values))
+	for _, v := range values {
+		switch n := v.(type) {
+		case types.Integer:
+			nums = append(nums, n)
+		case types.Float:
+			if math.IsNaN(float64(n)) {
+				return nil, false, fmt.Errorf("Invalid argument for %s: NaN", name)
+			}
+			nums = append(nums, n)
+			isFloat = true
+		default:
+			if !types.IsNull(v) {
+				return nil, false, fmt.Errorf("Invalid argument for %s: expected a number but got %T", name, v)
+			}
+		}
+	}
+	return nums, isFloat, nil
+}
+
+// Floats returns the numbers as float64s
+func Floats(nums []interface{}) []float64 {
+	res := make([]float64, len(nums))
+	for i, n := range nums {
+		switch v := n.(type) {
+		case types.Integer:
+			res[i] = float64(v)
+		case types.Float:
+			res[i] = float64(v)
+		}
+	}
+	return res
+}
+
+// Mean returns the mean of the numbers, computed incrementally so that large
+// numbers do not overflow
+func Mean(xs []float64) float64 {
+	var m float64
+	for i, x := range xs {
+		m += (x - m) / float64(i+1)
+	}
+	return m
+}
+
+// Lambda returns the lambda argument of the named method
+func Lambda(name string, fn interface{}) (*goexp.Lambda, error) {
+	if f, ok := fn.(*goexp.Lambda); ok {
+		return f, nil
+	}
+	return nil, fmt.Errorf("Invalid argument for %s: expected a lambda but got %T", name, fn)
+}
+
+// Result returns the Float result of the named method if it is a finite number
+func Result(name string, v float64) (interface{}, error) {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return nil, fmt.Errorf("Result of %s is not a finite number", name)
+	}
+	return types.Float(v), nil
+}
@@ -0,0 +1,92 @@
+// Package stdlib contains helpers shared by the libraries of the stdlib packages
+package stdlib
+
+import (
+	"fmt"
+
+	"github.com/svstanev/goexp"
+	"github.com/svstanev/goexp/types"
+)
+
+// Function is a method of a library; it is pure unless its implementation is
+// wrapped by Impure
+type Function struct {
+	Name string
+	Fn   interface{}
+	Sig  *types.Signature
+
+	// Shared is set for the functions added by several libraries, e.g. Sum
+	Shared bool
+}
+
+type impure struct {
+	fn interface{}
+}
+
+// Impure marks the implementation of a method whose result does not depend
+// only on its arguments, e.g. now()
+func Impure(fn interface{}) interface{} {
+	return impure{fn}
+}
+
+// Signature returns the signature of a method with the given parameters
+func Signature(returns *types.Type, params ...*types.Type) *types.Signature {
+	return &types.Signature{Params: params, Returns: returns}
+}
+
+// Optional returns the signature of a method whose last parameter may be omitted
+func Optional(returns *types.Type, params ...*types.Type) *types.Signature {
+	return &types.Signature{Params: params, Variadic: true, Returns: returns}
+}
+
+// Install adds the functions to the context; it fails if any of their names
+// is already defined by the context, except for the shared functions, which
+// are skipped then so that the libraries adding them may be installed together
+func Install(ctx goexp.EvalContext, functions []Function) error {
+	for _, f := range functions {
+		if f.Shared && defines(ctx, f.Name) {
+			continue
+		}
+		var err error
+		if i, ok := f.Fn.(impure); ok {
+			err = ctx.AddMethod(f.Name, i.fn)
+		} else {
+			err = ctx.AddPureMethod(f.Name, f.Fn)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// defines returns true if the method is defined by the context itself rather
+// than by one of its parents; removing it from a snapshot leaves the context as it is
+func defines(ctx goexp.EvalContext, name string) bool {
+	return ctx.Snapshot().RemoveMethod(name)
+}
+
+// Schema returns the signatures of the functions
+func Schema(functions []Function) *types.Schema {
+	s := &types.Schema{Methods: map[string]*types.Signature{}}
+	for _, f := range functions {
+		s.Methods[f.Name] = f.Sig
+	}
+	return s
+}
+
+// ToString returns the string of a String argument of the named method
+func ToString(name string, value interface{}) (string, error) {
+	switch v := value.(type) {
+	case types.String:
+		return string(v), nil
+	case string:
+		return v, nil
+	}
+	return "", fmt.Errorf("Invalid argument for %s: expected a String but got %T", name, value)
+}
+
+// TooManyArguments is the error of a method called with n arguments but accepting at most max
+func TooManyArguments(max, n int) error {
+	return fmt.Errorf("Invalid number of arguments: expected at most %d but got %d", max, n)
+}
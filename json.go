@@ -0,0 +1,409 @@
+package goexp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"unicode/utf8"
+)
+
+// JSONVersion is the version of the JSON schema produced by EncodeJSON
+const JSONVersion = 1
+
+// maxJSONDepth limits the nesting of decoded expressions
+const maxJSONDepth = 1000
+
+/*
+EncodeJSON returns the JSON representation of the expression:
+
+	{"version": 1, "expr": {"type": "binary", "operator": {"op": "+", "lexeme": "+", "span": [2, 3]}, "left": ..., "right": ...}}
+
+Every node has a "type" discriminator: string, integer, float, boolean, nil,
+grouping, unary, binary, call, identifier, lambda, let and named (a named
+argument of a call). Operators keep the lexeme and the span in the source, if known.
+*/
+func EncodeJSON(expr Expr) ([]byte, error) {
+	node, err := encodeNode(expr)
+	if err != nil {
+		return nil, err
+	}
+	var b bytes.Buffer
+	e := json.NewEncoder(&b)
+	e.SetEscapeHTML(false)
+	if err := e.Encode(jsonDocument{Version: JSONVersion, Expr: node}); err != nil {
+		return nil, err
+	}
+	return bytes.TrimRight(b.Bytes(), "\n"), nil
+}
+
+// DecodeJSON returns the expression encoded by EncodeJSON. The document is
+// validated, so the result is an expression that can be evaluated and printed.
+func DecodeJSON(data []byte) (Expr, error) {
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.DisallowUnknownFields()
+	d.UseNumber()
+
+	var doc jsonDocument
+	if err := d.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("Invalid JSON expression: %v", err)
+	}
+	if doc.Version != JSONVersion {
+		return nil, fmt.Errorf("Unsupported JSON expression version %d", doc.Version)
+	}
+	return decodeNode(doc.Expr, "expr", 0)
+}
+
+type jsonDocument struct {
+	Version int       `json:"version"`
+	Expr    *jsonNode `json:"expr"`
+}
+
+type jsonNode struct {
+	Type     string          `json:"type"`
+	Value    json.RawMessage `json:"value,omitempty"`
+	Name     string          `json:"name,omitempty"`
+	Operator *jsonToken      `json:"operator,omitempty"`
+	Operand  *jsonNode       `json:"operand,omitempty"`
+	Left     *jsonNode       `json:"left,omitempty"`
+	Right    *jsonNode       `json:"right,omitempty"`
+	Expr     *jsonNode       `json:"expr,omitempty"`
+	Callee   *jsonNode       `json:"callee,omitempty"`
+	Args     []*jsonNode     `json:"args,omitempty"`
+	Body     *jsonNode       `json:"body,omitempty"`
+}
+
+type jsonToken struct {
+	Op     string `json:"op"`
+	Lexeme string `json:"lexeme,omitempty"`
+	Span   []int  `json:"span,omitempty"`
+}
+
+func rawJSON(v interface{}) json.RawMessage {
+	data, _ := json.Marshal(v)
+	return data
+}
+
+// decodeNumber decodes a JSON number; unlike json.Number it doesn't accept strings
+func decodeNumber(data json.RawMessage) (json.Number, bool) {
+	var n json.Number
+	if len(data) == 0 || data[0] == '"' || json.Unmarshal(data, &n) != nil {
+		return "", false
+	}
+	return n, true
+}
+
+func encodeToken(t Token) (*jsonToken, error) {
+	op, ok := ops[t.Type]
+	if !ok {
+		return nil, fmt.Errorf("Unknown operator %q", t.Lexeme)
+	}
+	token := &jsonToken{Op: op, Lexeme: t.Lexeme}
+	if t.Lexeme != "" {
+		token.Span = []int{t.Pos, t.Pos + utf8.RuneCountInString(t.Lexeme)}
+	}
+	return token, nil
+}
+
+func encodeNode(expr Expr) (*jsonNode, error) {
+	switch e := expr.(type) {
+	case StringLiteralExpr:
+		return &jsonNode{Type: "string", Value: rawJSON(e.Value)}, nil
+	case IntegerLiteralExpr:
+		return &jsonNode{Type: "integer", Value: rawJSON(e.Value)}, nil
+	case FloatLiteralExpr:
+		if math.IsInf(e.Value, 0) || math.IsNaN(e.Value) {
+			return nil, fmt.Errorf("Cannot encode %v", e.Value)
+		}
+		return &jsonNode{Type: "float", Value: rawJSON(e.Value)}, nil
+	case BooleanLiteralExpr:
+		return &jsonNode{Type: "boolean", Value: rawJSON(e.Value)}, nil
+	case NilLiteralExpr:
+		return &jsonNode{Type: "nil"}, nil
+	case GroupingExpr:
+		inner, err := encodeNode(e.Expr)
+		if err != nil {
+			return nil, err
+		}
+		return &jsonNode{Type: "grouping", Expr: inner}, nil
+	case UnaryExpr:
+		op, err := encodeToken(e.Operator)
+		if err != nil {
+			return nil, err
+		}
+		operand, err := encodeNode(e.Value)
+		if err != nil {
+			return nil, err
+		}
+		return &jsonNode{Type: "unary", Operator: op, Operand: operand}, nil
+	case BinaryExpr:
+		op, err := encodeToken(e.Operator)
+		if err != nil {
+			return nil, err
+		}
+		left, err := encodeNode(e.Left)
+		if err != nil {
+			return nil, err
+		}
+		right, err := encodeNode(e.Right)
+		if err != nil {
+			return nil, err
+		}
+		return &jsonNode{Type: "binary", Operator: op, Left: left, Right: right}, nil
+	case CallExpr:
+		callee, err := encodeNode(e.Name)
+		if err != nil {
+			return nil, err
+		}
+		args := make([]*jsonNode, len(e.Args))
+		for i, arg := range e.Args {
+			if args[i], err = encodeNode(arg); err != nil {
+				return nil, err
+			}
+		}
+		return &jsonNode{Type: "call", Callee: callee, Args: args}, nil
+	case IdentifierExpr:
+		node := &jsonNode{Type: "identifier", Name: e.Name}
+		if e.Expr != nil {
+			var err error
+			if node.Expr, err = encodeNode(e.Expr); err != nil {
+				return nil, err
+			}
+		}
+		return node, nil
+	case LambdaExpr:
+		body, err := encodeNode(e.Body)
+		if err != nil {
+			return nil, err
+		}
+		return &jsonNode{Type: "lambda", Name: e.Param, Expr: body}, nil
+	case NamedArgExpr:
+		value, err := encodeNode(e.Value)
+		if err != nil {
+			return nil, err
+		}
+		return &jsonNode{Type: "named", Name: e.Name, Expr: value}, nil
+	case TemplateExpr:
+		parts := make([]*jsonNode, len(e.Parts))
+		for i, part := range e.Parts {
+			var err error
+			if parts[i], err = encodeNode(part); err != nil {
+				return nil, err
+			}
+		}
+		return &jsonNode{Type: "template", Args: parts}, nil
+	case LetExpr:
+		value, err := encodeNode(e.Value)
+		if err != nil {
+			return nil, err
+		}
+		body, err := encodeNode(e.Body)
+		if err != nil {
+			return nil, err
+		}
+		return &jsonNode{Type: "let", Name: e.Name, Expr: value, Body: body}, nil
+	default:
+		return nil, fmt.Errorf("Cannot encode %T", expr)
+	}
+}
+
+// jsonOperators maps the operators to their token types
+var jsonOperators = func() map[string]TokenType {
+	m := make(map[string]TokenType)
+	for t, op := range ops {
+		m[op] = t
+	}
+	return m
+}()
+
+func decodeToken(t *jsonToken, path string, unary bool) (Token, error) {
+	if t == nil {
+		return Token{}, fmt.Errorf("%s: missing operator", path)
+	}
+	tokenType, ok := jsonOperators[t.Op]
+	_, isBinary := binaryPrecedence[tokenType]
+	switch {
+	case !ok,
+		unary && tokenType != Not && tokenType != Sub,
+		!unary && !isBinary:
+		return Token{}, fmt.Errorf("%s: invalid operator %q", path, t.Op)
+	}
+
+	token := Token{Type: tokenType, Lexeme: t.Lexeme}
+	if t.Span != nil {
+		if len(t.Span) != 2 || t.Span[0] < 0 || t.Span[1] < t.Span[0] {
+			return Token{}, fmt.Errorf("%s: invalid span %v", path, t.Span)
+		}
+		token.Pos = t.Span[0]
+	}
+	return token, nil
+}
+
+func decodeNode(node *jsonNode, path string, depth int) (Expr, error) {
+	if node == nil {
+		return nil, fmt.Errorf("%s: missing expression", path)
+	}
+	if depth > maxJSONDepth {
+		return nil, fmt.Errorf("%s: expression is nested too deep", path)
+	}
+
+	child := func(n *jsonNode, name string) (Expr, error) {
+		return decodeNode(n, path+"."+name, depth+1)
+	}
+
+	switch node.Type {
+	case "string":
+		var s string
+		if err := json.Unmarshal(node.Value, &s); err != nil {
+			return nil, fmt.Errorf("%s: invalid string value", path)
+		}
+		return StringLiteralExpr{s}, nil
+
+	case "integer":
+		n, ok := decodeNumber(node.Value)
+		if !ok {
+			return nil, fmt.Errorf("%s: invalid integer value", path)
+		}
+		value, err := strconv.ParseInt(string(n), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid integer value %s", path, n)
+		}
+		return IntegerLiteralExpr{value}, nil
+
+	case "float":
+		n, ok := decodeNumber(node.Value)
+		if !ok {
+			return nil, fmt.Errorf("%s: invalid float value", path)
+		}
+		value, err := strconv.ParseFloat(string(n), 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid float value %s", path, n)
+		}
+		return FloatLiteralExpr{value}, nil
+
+	case "boolean":
+		var b bool
+		if err := json.Unmarshal(node.Value, &b); err != nil {
+			return nil, fmt.Errorf("%s: invalid boolean value", path)
+		}
+		return BooleanLiteralExpr{b}, nil
+
+	case "nil":
+		return NilLiteralExpr{}, nil
+
+	case "grouping":
+		inner, err := child(node.Expr, "expr")
+		if err != nil {
+			return nil, err
+		}
+		return GroupingExpr{inner}, nil
+
+	case "unary":
+		op, err := decodeToken(node.Operator, path+".operator", true)
+		if err != nil {
+			return nil, err
+		}
+		operand, err := child(node.Operand, "operand")
+		if err != nil {
+			return nil, err
+		}
+		return UnaryExpr{Value: operand, Operator: op}, nil
+
+	case "binary":
+		op, err := decodeToken(node.Operator, path+".operator", false)
+		if err != nil {
+			return nil, err
+		}
+		left, err := child(node.Left, "left")
+		if err != nil {
+			return nil, err
+		}
+		right, err := child(node.Right, "right")
+		if err != nil {
+			return nil, err
+		}
+		return BinaryExpr{Left: left, Right: right, Operator: op}, nil
+
+	case "call":
+		callee, err := child(node.Callee, "callee")
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := callee.(IdentifierExpr); !ok {
+			return nil, fmt.Errorf("%s.callee: expected an identifier", path)
+		}
+		args := make([]Expr, len(node.Args))
+		for i, arg := range node.Args {
+			argPath := fmt.Sprintf("args[%d]", i)
+			if arg == nil || arg.Type != "named" {
+				if args[i], err = child(arg, argPath); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			if !isIdentifier(arg.Name) {
+				return nil, fmt.Errorf("%s.%s: invalid identifier %q", path, argPath, arg.Name)
+			}
+			value, err := child(arg.Expr, argPath+".expr")
+			if err != nil {
+				return nil, err
+			}
+			args[i] = NamedArgExpr{Name: arg.Name, Value: value}
+		}
+		return CallExpr{Name: callee, Args: args}, nil
+
+	case "identifier":
+		if !isIdentifier(node.Name) {
+			return nil, fmt.Errorf("%s: invalid identifier %q", path, node.Name)
+		}
+		id := IdentifierExpr{Name: node.Name}
+		if node.Expr != nil {
+			var err error
+			if id.Expr, err = child(node.Expr, "expr"); err != nil {
+				return nil, err
+			}
+		}
+		return id, nil
+
+	case "lambda":
+		if !isIdentifier(node.Name) {
+			return nil, fmt.Errorf("%s: invalid identifier %q", path, node.Name)
+		}
+		body, err := child(node.Expr, "expr")
+		if err != nil {
+			return nil, err
+		}
+		return LambdaExpr{Param: node.Name, Body: body}, nil
+
+	case "let":
+		if !isIdentifier(node.Name) {
+			return nil, fmt.Errorf("%s: invalid identifier %q", path, node.Name)
+		}
+		value, err := child(node.Expr, "expr")
+		if err != nil {
+			return nil, err
+		}
+		body, err := child(node.Body, "body")
+		if err != nil {
+			return nil, err
+		}
+		return LetExpr{Name: node.Name, Value: value, Body: body}, nil
+
+	case "template":
+		parts := make([]Expr, len(node.Args))
+		for i, arg := range node.Args {
+			var err error
+			if parts[i], err = child(arg, fmt.Sprintf("args[%d]", i)); err != nil {
+				return nil, err
+			}
+		}
+		return TemplateExpr{parts}, nil
+
+	case "named":
+		return nil, fmt.Errorf("%s: named argument outside of a call", path)
+
+	default:
+		return nil, fmt.Errorf("%s: unknown expression type %q", path, node.Type)
+	}
+}
@@ -0,0 +1,98 @@
+package goexp
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/go-test/deep"
+)
+
+func TestEncodeJSON(t *testing.T) {
+	expr, err := Parse("a and -b.c(1, 'x') >= 2.5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := EncodeJSON(expr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `{"version":1,"expr":{"type":"binary","operator":{"op":"&&","lexeme":"and","span":[2,5]},` +
+		`"left":{"type":"identifier","name":"a"},` +
+		`"right":{"type":"binary","operator":{"op":">=","lexeme":">=","span":[19,21]},` +
+		`"left":{"type":"unary","operator":{"op":"-","lexeme":"-","span":[6,7]},"operand":{"type":"call","callee":{"type":"identifier","name":"c","expr":{"type":"identifier","name":"b"}},` +
+		`"args":[{"type":"integer","value":1},{"type":"string","value":"x"}]}},` +
+		`"right":{"type":"float","value":2.5}}}}`
+	if string(data) != expected {
+		t.Errorf("Expected\n%s\nbut got\n%s", expected, data)
+	}
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	for _, s := range []string{"1 + 2 * 3", "!(a || b) && c != nil", "foo.bar(1, 2.5, 'x', true, false)", "-9223372036854775807 - 1", "count(xs, x => x > 1)", "round(x, digits: 2)", "let a = 1; a + 1", "`a${b}c${`${d}`}`"} {
+		expr, err := Parse(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		testJSONRoundTrip(t, expr)
+	}
+
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 1000; i++ {
+		testJSONRoundTrip(t, randomPrintableExpr(r, 5))
+	}
+}
+
+func testJSONRoundTrip(t *testing.T, expr Expr) {
+	data, err := EncodeJSON(expr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := DecodeJSON(data)
+	if err != nil {
+		t.Fatalf("%s: %v", data, err)
+	}
+	if diff := deep.Equal(decoded, expr); diff != nil {
+		t.Fatalf("%s: %v", data, diff)
+	}
+}
+
+func TestDecodeJSONErrors(t *testing.T) {
+	tests := []struct {
+		json string
+		err  string
+	}{
+		{`{"version":2,"expr":{"type":"nil"}}`, "Unsupported JSON expression version 2"},
+		{`{"version":1}`, "expr: missing expression"},
+		{`{"version":1,"expr":{"type":"foo"}}`, `expr: unknown expression type "foo"`},
+		{`{"version":1,"expr":{"type":"nil","extra":1}}`, `Invalid JSON expression: json: unknown field "extra"`},
+		{`{"version":1,"expr":{"type":"binary","operator":{"op":"+"},"left":{"type":"nil"}}}`, "expr.right: missing expression"},
+		{`{"version":1,"expr":{"type":"binary","left":{"type":"nil"},"right":{"type":"nil"}}}`, "expr.operator: missing operator"},
+		{`{"version":1,"expr":{"type":"binary","operator":{"op":"!"},"left":{"type":"nil"},"right":{"type":"nil"}}}`, `expr.operator: invalid operator "!"`},
+		{`{"version":1,"expr":{"type":"unary","operator":{"op":"*"},"operand":{"type":"nil"}}}`, `expr.operator: invalid operator "*"`},
+		{`{"version":1,"expr":{"type":"unary","operator":{"op":"-","span":[3]},"operand":{"type":"nil"}}}`, "expr.operator: invalid span [3]"},
+		{`{"version":1,"expr":{"type":"integer","value":1.5}}`, "expr: invalid integer value 1.5"},
+		{`{"version":1,"expr":{"type":"integer","value":"1"}}`, "expr: invalid integer value"},
+		{`{"version":1,"expr":{"type":"string"}}`, "expr: invalid string value"},
+		{`{"version":1,"expr":{"type":"identifier","name":"a b"}}`, `expr: invalid identifier "a b"`},
+		{`{"version":1,"expr":{"type":"call","callee":{"type":"string","value":"f"}}}`, "expr.callee: expected an identifier"},
+		{`{"version":1,"expr":{"type":"lambda","name":"nil","expr":{"type":"nil"}}}`, `expr: invalid identifier "nil"`},
+		{`{"version":1,"expr":{"type":"lambda","name":"x"}}`, "expr.expr: missing expression"},
+		{`{"version":1,"expr":{"type":"named","name":"x","expr":{"type":"nil"}}}`, "expr: named argument outside of a call"},
+		{`{"version":1,"expr":{"type":"let","name":"x","expr":{"type":"nil"}}}`, "expr.body: missing expression"},
+		{`{"version":1,"expr":{"type":"call","callee":{"type":"identifier","name":"f"},"args":[{"type":"named","name":"1"}]}}`, `expr.args[0]: invalid identifier "1"`},
+		{`{"version":1,"expr":{"type":"call","callee":{"type":"identifier","name":"f"},"args":[{"type":"named","name":"a"}]}}`, "expr.args[0].expr: missing expression"},
+		{`{"version":1,"expr":{"type":"call","callee":{"type":"identifier","name":"f"},"args":[{"type":"nil"},null]}}`, "expr.args[1]: missing expression"},
+		{`{"version":1,"expr":` + strings.Repeat(`{"type":"grouping","expr":`, 1100) + `{"type":"nil"}` + strings.Repeat("}", 1100) + "}", "nested too deep"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.err, func(t *testing.T) {
+			expr, err := DecodeJSON([]byte(test.json))
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf(`Expected "%s" error but got "%v" (%#v)`, test.err, err, expr)
+			}
+		})
+	}
+}
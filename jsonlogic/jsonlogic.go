@@ -0,0 +1,434 @@
+/*
+Package jsonlogic converts goexp expressions to and from JSONLogic rules (http://jsonlogic.com).
+
+	expr, _ := goexp.Parse("age > 18 && user.country == 'BG'")
+	rule, _ := jsonlogic.FromExpr(expr)
+	// {"and": [{">": [{"var": "age"}, 18]}, {"===": [{"var": "user.country"}, "BG"]}]}
+
+Names map to "var" operations with dotted paths and the operations that are
+not part of the common subset map to method calls. The parts of the languages
+that cannot be converted (e.g. "**", "if", "map" or "var" with a default value)
+are reported as errors.
+
+goexp doesn't convert values implicitly, so the conversion keeps to the rules
+where both languages agree:
+
+  - "==" and "!=" are the strict "===" and "!==" of JSONLogic; the loose
+    "==" and "!=" are not supported
+  - the operands of "and", "or", "!" and "!!" must be booleans; literals of
+    other types are reported as errors and goexp reports the other values
+    when the expression is evaluated, where JSONLogic would use their truthiness
+  - "+" and "*" with a single argument, which cast it to a number, are not supported
+  - "+" of strings is not supported, since JSONLogic casts the operands of "+"
+    to numbers; "+" of names is converted as addition
+  - "/" and "%" are not supported, since JSONLogic divides integers as floats
+    and returns NaN for a zero divisor
+*/
+package jsonlogic
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/svstanev/goexp"
+)
+
+var operations = map[goexp.TokenType]string{
+	goexp.And:          "and",
+	goexp.Or:           "or",
+	goexp.Equal:        "===",
+	goexp.NotEqual:     "!==",
+	goexp.Less:         "<",
+	goexp.LessEqual:    "<=",
+	goexp.Greater:      ">",
+	goexp.GreaterEqual: ">=",
+	goexp.Add:          "+",
+	goexp.Sub:          "-",
+	goexp.Mul:          "*",
+	goexp.Div:          "/",
+	goexp.Modulo:       "%",
+}
+
+// tokens maps the JSONLogic operations to goexp operators
+var tokens = map[string]goexp.TokenType{
+	"and": goexp.And,
+	"or":  goexp.Or,
+	"===": goexp.Equal,
+	"!==": goexp.NotEqual,
+	"<":   goexp.Less,
+	"<=":  goexp.LessEqual,
+	">":   goexp.Greater,
+	">=":  goexp.GreaterEqual,
+	"+":   goexp.Add,
+	"-":   goexp.Sub,
+	"*":   goexp.Mul,
+	"!":   goexp.Not,
+}
+
+// unsupported are the JSONLogic operations that have no goexp equivalent
+var unsupported = map[string]string{
+	"if":           "conditionals are not supported",
+	"?:":           "conditionals are not supported",
+	"map":          "array operations are not supported",
+	"filter":       "array operations are not supported",
+	"reduce":       "array operations are not supported",
+	"all":          "array operations are not supported",
+	"none":         "array operations are not supported",
+	"some":         "array operations are not supported",
+	"merge":        "array operations are not supported",
+	"missing":      "missing is not supported",
+	"missing_some": "missing_some is not supported",
+	"log":          "log is not supported",
+	"==":           `loose equality is not supported, use "==="`,
+	"!=":           `loose equality is not supported, use "!=="`,
+	"/":            "division is not supported, JSONLogic divides integers as floats",
+	"%":            "remainder is not supported, JSONLogic computes it on floats",
+}
+
+// Encode returns the JSON of the JSONLogic rule of the expression
+func Encode(expr goexp.Expr) ([]byte, error) {
+	rule, err := FromExpr(expr)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	e := json.NewEncoder(&buf)
+	e.SetEscapeHTML(false)
+	if err := e.Encode(rule); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// Decode returns the expression of the JSONLogic rule in data
+func Decode(data []byte) (goexp.Expr, error) {
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.UseNumber()
+	var rule interface{}
+	if err := d.Decode(&rule); err != nil {
+		return nil, err
+	}
+	return ToExpr(rule)
+}
+
+// FromExpr returns the JSONLogic rule of the expression as maps, slices and values
+func FromExpr(expr goexp.Expr) (interface{}, error) {
+	switch e := expr.(type) {
+	case goexp.StringLiteralExpr:
+		return e.Value, nil
+	case goexp.IntegerLiteralExpr:
+		return e.Value, nil
+	case goexp.FloatLiteralExpr:
+		if math.IsInf(e.Value, 0) || math.IsNaN(e.Value) {
+			return nil, fmt.Errorf("Cannot convert %v to JSONLogic", e.Value)
+		}
+		return e.Value, nil
+	case goexp.BooleanLiteralExpr:
+		return e.Value, nil
+	case goexp.NilLiteralExpr:
+		return nil, nil
+	case goexp.GroupingExpr:
+		return FromExpr(e.Expr)
+
+	case goexp.IdentifierExpr:
+		path, ok := goexp.Path(e)
+		if !ok {
+			return nil, fmt.Errorf("Cannot convert %s to JSONLogic: member access is supported only on names", printExpr(e))
+		}
+		return operation("var", strings.Join(path, ".")), nil
+
+	case goexp.UnaryExpr:
+		x, err := FromExpr(e.Value)
+		if err != nil {
+			return nil, err
+		}
+		switch e.Operator.Type {
+		case goexp.Not:
+			return operation("!", []interface{}{x}), nil
+		case goexp.Sub:
+			return operation("-", []interface{}{x}), nil
+		}
+
+	case goexp.BinaryExpr:
+		op, ok := operations[e.Operator.Type]
+		if !ok {
+			break
+		}
+		if reason, ok := unsupported[op]; ok {
+			return nil, fmt.Errorf("Cannot convert %q: %s", op, reason)
+		}
+		if e.Operator.Type == goexp.Add {
+			if err := numbers(op, []goexp.Expr{e.Left, e.Right}); err != nil {
+				return nil, err
+			}
+		}
+		var args []interface{}
+		for i, x := range []goexp.Expr{e.Left, e.Right} {
+			arg, err := FromExpr(x)
+			if err != nil {
+				return nil, err
+			}
+			// flatten left-associative chains, e.g. a && b && c
+			if m, ok := arg.(map[string]interface{}); ok && i == 0 && isAssociative(e.Operator.Type) {
+				if nested, ok := m[op].([]interface{}); ok && len(nested) > 1 {
+					args = append(args, nested...)
+					continue
+				}
+			}
+			args = append(args, arg)
+		}
+		return operation(op, args), nil
+
+	case goexp.CallExpr:
+		id, ok := e.Name.(goexp.IdentifierExpr)
+		if !ok || id.Expr != nil {
+			return nil, fmt.Errorf("Cannot convert %s to JSONLogic: only calls to functions are supported", printExpr(e))
+		}
+		args := make([]interface{}, len(e.Args))
+		for i, x := range e.Args {
+			var err error
+			if args[i], err = FromExpr(x); err != nil {
+				return nil, err
+			}
+		}
+		return operation(id.Name, args), nil
+	}
+
+	return nil, fmt.Errorf("Cannot convert %s to JSONLogic", printExpr(expr))
+}
+
+func isAssociative(t goexp.TokenType) bool {
+	return t == goexp.And || t == goexp.Or || t == goexp.Add || t == goexp.Mul
+}
+
+func operation(op string, args interface{}) map[string]interface{} {
+	return map[string]interface{}{op: args}
+}
+
+// ToExpr returns the expression of a JSONLogic rule decoded from JSON
+func ToExpr(rule interface{}) (goexp.Expr, error) {
+	switch v := rule.(type) {
+	case nil:
+		return goexp.NilLiteralExpr{}, nil
+	case bool:
+		return goexp.BooleanLiteralExpr{Value: v}, nil
+	case string:
+		return goexp.StringLiteralExpr{Value: v}, nil
+	case json.Number:
+		if n, err := strconv.ParseInt(string(v), 10, 64); err == nil {
+			return goexp.IntegerLiteralExpr{Value: n}, nil
+		}
+		f, err := v.Float64()
+		if err != nil {
+			return nil, fmt.Errorf("Invalid number %s", v)
+		}
+		return goexp.FloatLiteralExpr{Value: f}, nil
+	case float64:
+		if v == math.Trunc(v) && math.Abs(v) < 1<<53 {
+			return goexp.IntegerLiteralExpr{Value: int64(v)}, nil
+		}
+		return goexp.FloatLiteralExpr{Value: v}, nil
+	case int:
+		return goexp.IntegerLiteralExpr{Value: int64(v)}, nil
+	case int64:
+		return goexp.IntegerLiteralExpr{Value: v}, nil
+	case []interface{}:
+		return nil, fmt.Errorf("Arrays are supported only as arguments of operations")
+	case map[string]interface{}:
+		if len(v) != 1 {
+			return nil, fmt.Errorf("Expected an operation with a single key but got %d keys", len(v))
+		}
+		for op, args := range v {
+			return toOperation(op, args)
+		}
+	}
+	return nil, fmt.Errorf("Unsupported value %T", rule)
+}
+
+func toOperation(op string, value interface{}) (goexp.Expr, error) {
+	if reason, ok := unsupported[op]; ok {
+		return nil, fmt.Errorf("Cannot convert %q: %s", op, reason)
+	}
+
+	// a single argument may be given without an array
+	values, ok := value.([]interface{})
+	if !ok {
+		values = []interface{}{value}
+	}
+
+	if op == "var" {
+		return toVar(values)
+	}
+
+	args := make([]goexp.Expr, len(values))
+	for i, x := range values {
+		var err error
+		if args[i], err = ToExpr(x); err != nil {
+			return nil, err
+		}
+	}
+
+	switch op {
+	case "and", "or", "!", "!!":
+		if err := booleans(op, args); err != nil {
+			return nil, err
+		}
+	case "+", "*":
+		if len(args) == 1 {
+			return nil, fmt.Errorf("Cannot convert %q with a single argument: casting to a number is not supported", op)
+		}
+		if err := numbers(op, args); err != nil {
+			return nil, err
+		}
+	}
+
+	switch op {
+	case "!":
+		if len(args) != 1 {
+			return nil, fmt.Errorf("%q expects 1 argument but got %d", op, len(args))
+		}
+		return unary(goexp.Not, args[0]), nil
+
+	case "!!":
+		if len(args) != 1 {
+			return nil, fmt.Errorf("%q expects 1 argument but got %d", op, len(args))
+		}
+		return unary(goexp.Not, unary(goexp.Not, args[0])), nil
+
+	case "-":
+		if len(args) == 1 {
+			// negative numbers are literals, as parsed
+			switch x := args[0].(type) {
+			case goexp.IntegerLiteralExpr:
+				if x.Value != math.MinInt64 {
+					return goexp.IntegerLiteralExpr{Value: -x.Value}, nil
+				}
+			case goexp.FloatLiteralExpr:
+				return goexp.FloatLiteralExpr{Value: -x.Value}, nil
+			}
+			return unary(goexp.Sub, args[0]), nil
+		}
+		return binary(op, args, 2, 2)
+
+	case "and", "or", "+", "*":
+		return binary(op, args, 1, -1)
+
+	case "<", "<=":
+		if len(args) == 3 {
+			// between: a < b < c
+			left, _ := binary(op, args[:2], 2, 2)
+			right, _ := binary(op, args[1:], 2, 2)
+			return goexp.BinaryExpr{Left: left, Right: right, Operator: goexp.Token{Type: goexp.And, Lexeme: "&&"}}, nil
+		}
+		return binary(op, args, 2, 2)
+	}
+
+	if _, ok := tokens[op]; ok {
+		return binary(op, args, 2, 2)
+	}
+
+	// custom operations are method calls
+	if !isName(op) {
+		return nil, fmt.Errorf("Cannot convert %q: not a valid method name", op)
+	}
+	return goexp.CallExpr{Name: goexp.IdentifierExpr{Name: op}, Args: args}, nil
+}
+
+func toVar(values []interface{}) (goexp.Expr, error) {
+	if len(values) != 1 {
+		return nil, fmt.Errorf(`Cannot convert "var" with a default value`)
+	}
+	path, ok := values[0].(string)
+	if !ok || path == "" {
+		return nil, fmt.Errorf(`Cannot convert "var": expected a non-empty path but got %v`, values[0])
+	}
+
+	var expr goexp.Expr
+	for _, name := range strings.Split(path, ".") {
+		if !isName(name) {
+			return nil, fmt.Errorf(`Cannot convert "var" %q: %q is not a valid name`, path, name)
+		}
+		if expr == nil {
+			expr = goexp.IdentifierExpr{Name: name}
+		} else {
+			expr = goexp.IdentifierExpr{Name: name, Expr: expr}
+		}
+	}
+	return expr, nil
+}
+
+// booleans checks that none of the arguments is a literal of another type than boolean
+func booleans(op string, args []goexp.Expr) error {
+	for _, arg := range args {
+		switch arg.(type) {
+		case goexp.StringLiteralExpr, goexp.IntegerLiteralExpr, goexp.FloatLiteralExpr, goexp.NilLiteralExpr:
+			return fmt.Errorf("Cannot convert %q: expected boolean operands but got %s", op, printExpr(arg))
+		}
+	}
+	return nil
+}
+
+// numbers checks that none of the arguments is a string, which JSONLogic
+// would cast to a number
+func numbers(op string, args []goexp.Expr) error {
+	for _, arg := range args {
+		for g, ok := arg.(goexp.GroupingExpr); ok; g, ok = arg.(goexp.GroupingExpr) {
+			arg = g.Expr
+		}
+		switch arg.(type) {
+		case goexp.StringLiteralExpr, goexp.TemplateExpr:
+			return fmt.Errorf("Cannot convert %q of strings: JSONLogic casts the operands to numbers", op)
+		}
+	}
+	return nil
+}
+
+func unary(t goexp.TokenType, x goexp.Expr) goexp.Expr {
+	lexeme := "!"
+	if t == goexp.Sub {
+		lexeme = "-"
+	}
+	return goexp.UnaryExpr{Value: x, Operator: goexp.Token{Type: t, Lexeme: lexeme}}
+}
+
+// binary folds the arguments with the operator; max < 0 means any number of arguments
+func binary(op string, args []goexp.Expr, min, max int) (goexp.Expr, error) {
+	if len(args) < min || max >= 0 && len(args) > max {
+		return nil, fmt.Errorf("%q got unexpected number of arguments: %d", op, len(args))
+	}
+	t := tokens[op]
+	token := goexp.Token{Type: t, Lexeme: operations[t]}
+	if t == goexp.And {
+		token.Lexeme = "&&"
+	} else if t == goexp.Or {
+		token.Lexeme = "||"
+	}
+
+	expr := args[0]
+	for _, arg := range args[1:] {
+		expr = goexp.BinaryExpr{Left: expr, Right: arg, Operator: token}
+	}
+	return expr, nil
+}
+
+// isName returns true if the string can be parsed as a name
+func isName(s string) bool {
+	expr, err := goexp.Parse(s)
+	if err != nil {
+		return false
+	}
+	id, ok := expr.(goexp.IdentifierExpr)
+	return ok && id.Expr == nil && id.Name == s
+}
+
+func printExpr(expr goexp.Expr) string {
+	s, err := goexp.Print(expr)
+	if err != nil {
+		return fmt.Sprintf("%T", expr)
+	}
+	return s
+}
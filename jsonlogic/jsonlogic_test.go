@@ -0,0 +1,181 @@
+package jsonlogic
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/svstanev/goexp"
+)
+
+func TestEncode(t *testing.T) {
+	tests := []struct {
+		expr     string
+		expected string
+	}{
+		{"age > 18", `{">":[{"var":"age"},18]}`},
+		{"a && b && c", `{"and":[{"var":"a"},{"var":"b"},{"var":"c"}]}`},
+		{"a || (b && c)", `{"or":[{"var":"a"},{"and":[{"var":"b"},{"var":"c"}]}]}`},
+		{"user.address.city == 'Sofia'", `{"===":[{"var":"user.address.city"},"Sofia"]}`},
+		{"!(x != nil)", `{"!":[{"!==":[{"var":"x"},null]}]}`},
+		{"1 + 2 + 3.5", `{"+":[1,2,3.5]}`},
+		{"1 - (2 - 3)", `{"-":[1,{"-":[2,3]}]}`},
+		{"-x * 2", `{"*":[{"-":[{"var":"x"}]},2]}`},
+		{"a + b", `{"+":[{"var":"a"},{"var":"b"}]}`},
+		{"in(x, 'abc')", `{"in":[{"var":"x"},"abc"]}`},
+	}
+
+	for _, test := range tests {
+		expr, err := goexp.Parse(test.expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := Encode(expr)
+		if err != nil {
+			t.Errorf("%s: %v", test.expr, err)
+			continue
+		}
+		if string(data) != test.expected {
+			t.Errorf("%s: expected %s but got %s", test.expr, test.expected, data)
+		}
+	}
+}
+
+func TestEncodeErrors(t *testing.T) {
+	tests := []struct {
+		expr string
+		err  string
+	}{
+		{"2 ** 3", "Cannot convert 2 ** 3 to JSONLogic"},
+		{"a.b(1)", "only calls to functions are supported"},
+		{"f(1).x", "member access is supported only on names"},
+		{"f(a ** 2)", "Cannot convert a ** 2 to JSONLogic"},
+		{"a / 2", `Cannot convert "/": division is not supported`},
+		{"f(a % 2)", `Cannot convert "%": remainder is not supported`},
+		{"'a' + b", `Cannot convert "+" of strings`},
+		{"a + 1 + ('b')", `Cannot convert "+" of strings`},
+		{"a + `${b}`", `Cannot convert "+" of strings`},
+	}
+
+	for _, test := range tests {
+		expr, err := goexp.Parse(test.expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := FromExpr(expr); err == nil {
+			t.Errorf("%s: expected an error", test.expr)
+		} else if !strings.Contains(err.Error(), test.err) {
+			t.Errorf("%s: expected error containing %q but got %q", test.expr, test.err, err)
+		}
+	}
+}
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		rule     string
+		expected string
+	}{
+		{`{"and":[{">":[{"var":"age"},18]},{"===":[{"var":"user.country"},"BG"]}]}`, "age > 18 && user.country == 'BG'"},
+		{`{"or":[true,false,{"var":"a"}]}`, "true || false || a"},
+		{`{"and":[{"var":"a"}]}`, "a"},
+		{`{"!":{"var":"a"}}`, "!a"},
+		{`{"!!":[{"var":"a"}]}`, "!(!a)"},
+		{`{"===":[1,1.0]}`, "1 == 1.0"},
+		{`{"!==":[1,2]}`, "1 != 2"},
+		{`{"-":[5]}`, "-5"},
+		{`{"-":[{"var":"a"}]}`, "-a"},
+		{`{"+":[1,2,3]}`, "1 + 2 + 3"},
+		{`{"*":[{"var":"a"},2]}`, "a * 2"},
+		{`{"<":[1,{"var":"x"},10]}`, "1 < x && x < 10"},
+		{`{"cat":["a",{"var":"b"}]}`, "cat('a', b)"},
+		{`"foo"`, "'foo'"},
+	}
+
+	for _, test := range tests {
+		actual, err := Decode([]byte(test.rule))
+		if err != nil {
+			t.Errorf("%s: %v", test.rule, err)
+			continue
+		}
+		expected, err := goexp.Parse(test.expected)
+		if err != nil {
+			t.Fatal(err)
+		}
+		s1, _ := goexp.Print(actual)
+		s2, _ := goexp.Print(expected)
+		if s1 != s2 {
+			t.Errorf("%s: expected %s but got %s", test.rule, s2, s1)
+		}
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	tests := []struct {
+		rule string
+		err  string
+	}{
+		{`{"if":[true,1,2]}`, "conditionals are not supported"},
+		{`{"map":[[1],{"var":""}]}`, "array operations are not supported"},
+		{`{"var":["a",1]}`, "default value"},
+		{`{"var":""}`, "non-empty path"},
+		{`{"var":"a.1"}`, "not a valid name"},
+		{`{"var":1}`, "non-empty path"},
+		{`{"a":1,"b":2}`, "single key"},
+		{`[1,2]`, "Arrays"},
+		{`{">":[1]}`, "number of arguments"},
+		{`{"!":[true,false]}`, "expects 1 argument"},
+		{`{"in":[{"a b":[]}]}`, "not a valid method name"},
+		{`{"==":[1,"1"]}`, `loose equality is not supported, use "==="`},
+		{`{"!=":[{"var":"a"},null]}`, `loose equality is not supported, use "!=="`},
+		{`{"or":[true,false,null]}`, `Cannot convert "or": expected boolean operands but got nil`},
+		{`{"and":[{"var":"a"},"yes"]}`, `Cannot convert "and": expected boolean operands but got "yes"`},
+		{`{"!!":[0]}`, `Cannot convert "!!": expected boolean operands but got 0`},
+		{`{"+":["3"]}`, `Cannot convert "+" with a single argument`},
+		{`{"*":[{"var":"a"}]}`, `Cannot convert "*" with a single argument`},
+		{`{"/":[7,2]}`, `Cannot convert "/": division is not supported`},
+		{`{"%":[{"var":"a"},2]}`, `Cannot convert "%": remainder is not supported`},
+		{`{"+":[{"var":"a"},"b"]}`, `Cannot convert "+" of strings`},
+	}
+
+	for _, test := range tests {
+		_, err := Decode([]byte(test.rule))
+		if err == nil {
+			t.Errorf("%s: expected an error", test.rule)
+		} else if !strings.Contains(err.Error(), test.err) {
+			t.Errorf("%s: expected error containing %q but got %q", test.rule, test.err, err)
+		}
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, s := range []string{
+		"a && b || !c",
+		"x.y.z >= 1.5 && f(1, 'x', nil) != false",
+		"a - b * c - d",
+		"-(a + b) < 10",
+	} {
+		expr, err := goexp.Parse(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rule, err := FromExpr(expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// go through JSON to get the generic representation
+		data, err := json.Marshal(rule)
+		if err != nil {
+			t.Fatal(err)
+		}
+		actual, err := Decode(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// tokens differ in positions and lexemes so compare the printed expressions
+		s1, _ := goexp.Print(actual)
+		s2, _ := goexp.Print(expr)
+		if s1 != s2 {
+			t.Errorf("Expected %s but got %s", s2, s1)
+		}
+	}
+}
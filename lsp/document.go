@@ -0,0 +1,594 @@
+package lsp
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/svstanev/goexp"
+	"github.com/svstanev/goexp/types"
+)
+
+// ruleLine matches "name = expression" as goexp-gen does
+var ruleLine = regexp.MustCompile(`^\s*([A-Za-z_][A-Za-z0-9_]*)\s*=($|[^=].*$)`)
+
+// document is an open .gx file: a rule per line, empty lines and # comments
+type document struct {
+	uri     string
+	lines   []string
+	eols    []string // the line breaks ending the lines, "\n" or "\r\n"
+	rules   []*rule
+	byName  map[string]*rule
+	invalid []int
+}
+
+// rule is a "name = expression" line; the columns are in runes
+type rule struct {
+	name      string
+	line      int
+	nameStart int
+	nameEnd   int
+	exprStart int
+	source    string
+	expr      goexp.Expr
+	tokens    []goexp.Token
+	err       error
+}
+
+func newDocument(uri, text string) *document {
+	d := &document{uri: uri, byName: map[string]*rule{}}
+	d.lines = strings.Split(text, "\n")
+	d.eols = make([]string, len(d.lines))
+	for i, line := range d.lines {
+		if i < len(d.lines)-1 {
+			d.eols[i] = "\n"
+			if strings.HasSuffix(line, "\r") {
+				d.eols[i] = "\r\n"
+			}
+		}
+		line = strings.TrimSuffix(line, "\r")
+		d.lines[i] = line
+
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		m := ruleLine.FindStringSubmatchIndex(line)
+		if m == nil {
+			d.invalid = append(d.invalid, i)
+			continue
+		}
+
+		r := &rule{
+			name:      line[m[2]:m[3]],
+			line:      i,
+			nameStart: utf8.RuneCountInString(line[:m[2]]),
+			nameEnd:   utf8.RuneCountInString(line[:m[3]]),
+			exprStart: utf8.RuneCountInString(line[:m[4]]),
+			source:    line[m[4]:m[5]],
+		}
+		if r.tokens, r.err = goexp.Tokenize(r.source); r.err == nil {
+			r.expr, r.err = goexp.Parse(r.source)
+		}
+		d.rules = append(d.rules, r)
+		if _, ok := d.byName[r.name]; !ok {
+			d.byName[r.name] = r
+		}
+	}
+	return d
+}
+
+// position converts a line and rune column to an LSP position
+func (d *document) position(line, column int) Position {
+	if line >= len(d.lines) {
+		return Position{Line: line}
+	}
+	runes := []rune(d.lines[line])
+	if column > len(runes) {
+		column = len(runes)
+	}
+	return Position{Line: line, Character: len(utf16.Encode(runes[:column]))}
+}
+
+func (d *document) span(line, start, end int) Range {
+	return Range{d.position(line, start), d.position(line, end)}
+}
+
+// column converts the UTF-16 character offset of an LSP position to a rune column
+func (d *document) column(p Position) int {
+	if p.Line < 0 || p.Line >= len(d.lines) {
+		return 0
+	}
+	n := 0
+	for i, r := range []rune(d.lines[p.Line]) {
+		if n >= p.Character {
+			return i
+		}
+		n += len(utf16.Encode([]rune{r}))
+	}
+	return utf8.RuneCountInString(d.lines[p.Line])
+}
+
+func (d *document) ruleAt(line int) *rule {
+	for _, r := range d.rules {
+		if r.line == line {
+			return r
+		}
+	}
+	return nil
+}
+
+// reference is a name or a method call in an expression, e.g. user.address.city or user.orders.count(1)
+type reference struct {
+	path   []string
+	tokens []goexp.Token
+	call   bool
+	args   int
+}
+
+// references returns the names and the method calls of the rule's expression
+// in order of appearance; members of values that are not names, e.g. f().x,
+// and the names bound by lambdas and let in their scope are skipped
+func (r *rule) references() []reference {
+	if r.expr == nil {
+		return nil
+	}
+	c := &referenceCollector{idents: identifiers(r.tokens), bound: map[string]int{}}
+	r.expr.Accept(c, nil)
+	return c.refs
+}
+
+// identifiers returns the identifier tokens, including those of the
+// expressions embedded in template strings, in order of appearance
+func identifiers(tokens []goexp.Token) []goexp.Token {
+	var res []goexp.Token
+	for _, t := range tokens {
+		if t.Type == goexp.Identifier {
+			res = append(res, t)
+		}
+		parts, ok := t.Literal.([]goexp.TemplatePart)
+		if t.Type != goexp.Template || !ok {
+			continue
+		}
+		for _, part := range parts {
+			if !part.Expr {
+				continue
+			}
+			inner, err := goexp.Tokenize(part.Text)
+			if err != nil {
+				continue
+			}
+			for _, it := range identifiers(inner) {
+				it.Pos += t.Pos + part.Pos
+				res = append(res, it)
+			}
+		}
+	}
+	return res
+}
+
+// referenceCollector walks an expression and collects its references; the
+// nodes are visited in order of appearance, so the names of the nodes are
+// the identifier tokens in order
+type referenceCollector struct {
+	idents []goexp.Token
+	refs   []reference
+
+	// bound counts the enclosing lambdas and lets declaring each name
+	bound map[string]int
+}
+
+// next returns the tokens of the next n names
+func (c *referenceCollector) next(n int) []goexp.Token {
+	if n > len(c.idents) {
+		n = len(c.idents)
+	}
+	res := c.idents[:n]
+	c.idents = c.idents[n:]
+	return res
+}
+
+func (c *referenceCollector) add(ref reference) {
+	if len(ref.tokens) == len(ref.path) {
+		c.refs = append(c.refs, ref)
+	}
+}
+
+// scoped visits the expression with the name bound
+func (c *referenceCollector) scoped(name string, expr goexp.Expr) (interface{}, error) {
+	c.bound[name]++
+	defer func() { c.bound[name]-- }()
+	return expr.Accept(c, nil)
+}
+
+func (c *referenceCollector) VisitStringLiteralExpr(e goexp.StringLiteralExpr, context goexp.VisitorContext) (interface{}, error) {
+	return nil, nil
+}
+
+func (c *referenceCollector) VisitIntegerLiteralExpr(e goexp.IntegerLiteralExpr, context goexp.VisitorContext) (interface{}, error) {
+	return nil, nil
+}
+
+func (c *referenceCollector) VisitFloatLiteralExpr(e goexp.FloatLiteralExpr, context goexp.VisitorContext) (interface{}, error) {
+	return nil, nil
+}
+
+func (c *referenceCollector) VisitBooleanLiteralExpr(e goexp.BooleanLiteralExpr, context goexp.VisitorContext) (interface{}, error) {
+	return nil, nil
+}
+
+func (c *referenceCollector) VisitNilLiteralExpr(e goexp.NilLiteralExpr, context goexp.VisitorContext) (interface{}, error) {
+	return nil, nil
+}
+
+func (c *referenceCollector) VisitBinaryExpr(e goexp.BinaryExpr, context goexp.VisitorContext) (interface{}, error) {
+	e.Left.Accept(c, context)
+	return e.Right.Accept(c, context)
+}
+
+func (c *referenceCollector) VisitUnaryExpr(e goexp.UnaryExpr, context goexp.VisitorContext) (interface{}, error) {
+	return e.Value.Accept(c, context)
+}
+
+func (c *referenceCollector) VisitGroupingExpr(e goexp.GroupingExpr, context goexp.VisitorContext) (interface{}, error) {
+	return e.Expr.Accept(c, context)
+}
+
+// VisitCallExpr adds the method call; x.m() of a bound x is m(x) with x of an
+// unknown type and is skipped
+func (c *referenceCollector) VisitCallExpr(e goexp.CallExpr, context goexp.VisitorContext) (interface{}, error) {
+	id, ok := e.Name.(goexp.IdentifierExpr)
+	switch path, isPath := goexp.Path(e.Name); {
+	case isPath:
+		tokens := c.next(len(path))
+		if len(path) == 1 || c.bound[path[0]] == 0 {
+			c.add(reference{path: path, tokens: tokens, call: true, args: len(e.Args)})
+		}
+	case ok:
+		// a method of a computed value, e.g. f().m()
+		id.Expr.Accept(c, context)
+		c.next(1)
+	default:
+		e.Name.Accept(c, context)
+	}
+	for _, arg := range e.Args {
+		arg.Accept(c, context)
+	}
+	return nil, nil
+}
+
+func (c *referenceCollector) VisitIdentifierExpr(e goexp.IdentifierExpr, context goexp.VisitorContext) (interface{}, error) {
+	if path, ok := goexp.Path(e); ok {
+		tokens := c.next(len(path))
+		if c.bound[path[0]] == 0 {
+			c.add(reference{path: path, tokens: tokens})
+		}
+		return nil, nil
+	}
+	// member access on a computed value, e.g. f().x
+	e.Expr.Accept(c, context)
+	c.next(1)
+	return nil, nil
+}
+
+func (c *referenceCollector) VisitLambdaExpr(e goexp.LambdaExpr, context goexp.VisitorContext) (interface{}, error) {
+	c.next(1)
+	return c.scoped(e.Param, e.Body)
+}
+
+func (c *referenceCollector) VisitNamedArgExpr(e goexp.NamedArgExpr, context goexp.VisitorContext) (interface{}, error) {
+	c.next(1)
+	return e.Value.Accept(c, context)
+}
+
+// VisitLetExpr visits the value and then the body, where the name is bound
+func (c *referenceCollector) VisitLetExpr(e goexp.LetExpr, context goexp.VisitorContext) (interface{}, error) {
+	c.next(1)
+	e.Value.Accept(c, context)
+	return c.scoped(e.Name, e.Body)
+}
+
+func (c *referenceCollector) VisitTemplateExpr(e goexp.TemplateExpr, context goexp.VisitorContext) (interface{}, error) {
+	for _, part := range e.Parts {
+		part.Accept(c, context)
+	}
+	return nil, nil
+}
+
+// tokenRange returns the columns of a token of the rule's expression
+func (r *rule) tokenRange(t goexp.Token) (int, int) {
+	start := r.exprStart + t.Pos
+	return start, start + utf8.RuneCountInString(t.Lexeme)
+}
+
+// referenceAt returns the prefix of the reference under the column and the token it ends with
+func (r *rule) referenceAt(column int) (reference, goexp.Token, bool) {
+	for _, ref := range r.references() {
+		for i, t := range ref.tokens {
+			if start, end := r.tokenRange(t); column >= start && column <= end {
+				res := reference{path: ref.path[:i+1], tokens: ref.tokens[:i+1]}
+				if i == len(ref.tokens)-1 {
+					res.call, res.args = ref.call, ref.args
+				}
+				return res, t, true
+			}
+		}
+	}
+	return reference{}, goexp.Token{}, false
+}
+
+func (d *document) diagnostics(schema *types.Schema) []Diagnostic {
+	res := []Diagnostic{}
+	add := func(line, start, end int, severity DiagnosticSeverity, format string, args ...interface{}) {
+		res = append(res, Diagnostic{
+			Range:    d.span(line, start, end),
+			Severity: severity,
+			Source:   "goexp",
+			Message:  fmt.Sprintf(format, args...),
+		})
+	}
+
+	for _, line := range d.invalid {
+		add(line, 0, utf8.RuneCountInString(d.lines[line]), SeverityError, "Expected <name> = <expression>")
+	}
+
+	for _, r := range d.rules {
+		if first := d.byName[r.name]; first != r {
+			add(r.line, r.nameStart, r.nameEnd, SeverityError, "%s is already defined on line %d", r.name, first.line+1)
+		}
+		if r.err != nil {
+			pos, _ := goexp.ErrorPosition(r.err)
+			add(r.line, r.exprStart+pos, r.exprStart+pos+1, SeverityError, "%v", r.err)
+			continue
+		}
+		for _, ref := range r.references() {
+			d.checkReference(r, ref, schema, add)
+		}
+	}
+
+	for _, r := range d.rules {
+		if cycle := d.cycle(r); cycle != nil {
+			add(r.line, r.nameStart, r.nameEnd, SeverityError, "Cycle detected: %s", strings.Join(cycle, " -> "))
+		}
+	}
+	return res
+}
+
+func (d *document) checkReference(r *rule, ref reference, schema *types.Schema, add func(int, int, int, DiagnosticSeverity, string, ...interface{})) {
+	first, last := ref.tokens[0], ref.tokens[len(ref.tokens)-1]
+	start, _ := r.tokenRange(first)
+	_, end := r.tokenRange(last)
+	name := strings.Join(ref.path, ".")
+
+	if !ref.call && len(ref.path) == 1 && d.byName[name] != nil {
+		return
+	}
+	if schema == nil {
+		return
+	}
+	if ref.call {
+		m, implicit, ok := method(schema, ref.path)
+		args := ref.args + implicit
+		if implicit > 0 {
+			name = ref.path[len(ref.path)-1]
+		}
+		switch {
+		case !ok:
+			add(r.line, start, end, SeverityWarning, "Method %s is not declared", name)
+		case m == nil || accepts(m, args):
+		case len(m.Overloads) > 0:
+			add(r.line, start, end, SeverityError, "No overload of %s accepts %d arguments", name, args)
+		case m.Variadic:
+			add(r.line, start, end, SeverityError, "%s expects at least %d arguments but got %d", signature(name, m), m.MinArgs(), args)
+		case m.Optional > 0:
+			add(r.line, start, end, SeverityError, "%s expects %d to %d arguments but got %d", signature(name, m), m.MinArgs(), len(m.Params), args)
+		default:
+			add(r.line, start, end, SeverityError, "%s expects %d arguments but got %d", signature(name, m), len(m.Params), args)
+		}
+		return
+	}
+	if _, ok := lookup(schema, ref.path); !ok {
+		add(r.line, start, end, SeverityWarning, "%s is not declared", name)
+	}
+}
+
+// cycle returns the path of rules from start back to itself, if any; the rules
+// with errors have no dependencies
+func (d *document) cycle(start *rule) []string {
+	visited := map[*rule]bool{}
+	var visit func(r *rule, path []string) []string
+	visit = func(r *rule, path []string) []string {
+		path = append(path, r.name)
+		if r.expr == nil {
+			return nil
+		}
+		for _, name := range goexp.Dependencies(r.expr).Names {
+			// the rule of a member access chain, e.g. a of a.b
+			next := d.byName[strings.SplitN(name, ".", 2)[0]]
+			if next == start {
+				return append(path, start.name)
+			}
+			if next != nil && !visited[next] {
+				visited[next] = true
+				if cycle := visit(next, path); cycle != nil {
+					return cycle
+				}
+			}
+		}
+		return nil
+	}
+	return visit(start, nil)
+}
+
+func (d *document) hover(schema *types.Schema, p Position) *Hover {
+	r := d.ruleAt(p.Line)
+	if r == nil {
+		return nil
+	}
+	column := d.column(p)
+	if column >= r.nameStart && column <= r.nameEnd {
+		span := d.span(r.line, r.nameStart, r.nameEnd)
+		return markdown(codeBlock(r.definition()), &span)
+	}
+
+	ref, t, ok := r.referenceAt(column)
+	if !ok {
+		return nil
+	}
+	start, end := r.tokenRange(t)
+	span := d.span(r.line, start, end)
+	name := strings.Join(ref.path, ".")
+
+	if ref.call {
+		if m, implicit, ok := method(schema, ref.path); ok && m != nil {
+			path := ref.path
+			if implicit > 0 {
+				path = path[len(path)-1:]
+				name = path[0]
+			}
+			return markdown(codeBlock(signature(name, m))+doc(docOf(schema, path)), &span)
+		}
+		return nil
+	}
+	if def := d.byName[name]; def != nil && len(ref.path) == 1 {
+		return markdown(codeBlock(def.definition())+fmt.Sprintf("\n\nDefined on line %d", def.line+1), &span)
+	}
+	if t, ok := lookup(schema, ref.path); ok {
+		return markdown(codeBlock(fmt.Sprintf("%s: %s", name, t))+doc(docOf(schema, ref.path)), &span)
+	}
+	return nil
+}
+
+// definition returns the rule formatted by the printer if it's valid
+func (r *rule) definition() string {
+	source := strings.TrimSpace(r.source)
+	if r.expr != nil {
+		if s, err := goexp.Print(r.expr); err == nil {
+			source = s
+		}
+	}
+	return fmt.Sprintf("%s = %s", r.name, source)
+}
+
+func markdown(s string, span *Range) *Hover {
+	return &Hover{Contents: MarkupContent{Kind: "markdown", Value: s}, Range: span}
+}
+
+func codeBlock(s string) string {
+	return "```goexp\n" + s + "\n```"
+}
+
+func doc(s string) string {
+	if s == "" {
+		return ""
+	}
+	return "\n\n" + s
+}
+
+func (d *document) definition(p Position) *Location {
+	r := d.ruleAt(p.Line)
+	if r == nil {
+		return nil
+	}
+	ref, _, ok := r.referenceAt(d.column(p))
+	if !ok || ref.call || len(ref.path) != 1 {
+		return nil
+	}
+	def := d.byName[ref.path[0]]
+	if def == nil {
+		return nil
+	}
+	return &Location{URI: d.uri, Range: d.span(def.line, def.nameStart, def.nameEnd)}
+}
+
+// completionPrefix matches the member access chain and the partial name before the cursor
+var completionPrefix = regexp.MustCompile(`((?:[A-Za-z_][A-Za-z0-9_]*\.)*)([A-Za-z0-9_]*)$`)
+
+func (d *document) completion(schema *types.Schema, p Position) []CompletionItem {
+	items := []CompletionItem{}
+	r := d.ruleAt(p.Line)
+	column := d.column(p)
+	if r == nil || column < r.exprStart {
+		return items
+	}
+	prefix := string([]rune(d.lines[p.Line])[r.exprStart:column])
+	if _, err := goexp.Tokenize(prefix); err != nil {
+		// e.g. inside a string
+		return items
+	}
+
+	m := completionPrefix.FindStringSubmatchIndex(prefix)
+	if m[0] > 0 && strings.ContainsAny(prefix[m[0]-1:m[0]], ".)") {
+		return items
+	}
+	partial := prefix[m[4]:m[5]]
+	var path []string
+	if receiver := prefix[m[2]:m[3]]; receiver != "" {
+		path = strings.Split(strings.TrimSuffix(receiver, "."), ".")
+	}
+
+	t, ok := lookup(schema, path)
+	if !ok || t.Kind != types.ObjectKind {
+		return items
+	}
+	nameKind, methodKind := CompletionVariable, CompletionFunction
+	if len(path) > 0 {
+		nameKind, methodKind = CompletionField, CompletionMethod
+	}
+	member := func(name string) []string {
+		return append(path[:len(path):len(path)], name)
+	}
+	for _, name := range sortedFields(t) {
+		if f := t.Fields[name]; strings.HasPrefix(name, partial) {
+			items = append(items, CompletionItem{Label: name, Kind: nameKind, Detail: f.String(), Documentation: docOf(schema, member(name))})
+		}
+	}
+	for _, name := range sortedMethods(t) {
+		if m := t.Methods[name]; strings.HasPrefix(name, partial) {
+			items = append(items, CompletionItem{Label: name, Kind: methodKind, Detail: signature(name, m), Documentation: docOf(schema, member(name))})
+		}
+	}
+	if len(path) == 0 {
+		for _, def := range d.rules {
+			if def != d.byName[def.name] || def == r || !strings.HasPrefix(def.name, partial) {
+				continue
+			}
+			items = append(items, CompletionItem{Label: def.name, Kind: CompletionVariable, Detail: "rule", Documentation: def.definition()})
+		}
+	}
+	return items
+}
+
+// format returns the edits reprinting the valid rules in canonical form; the
+// line breaks are kept as they are
+func (d *document) format() []TextEdit {
+	lines := make([]string, len(d.lines))
+	copy(lines, d.lines)
+	for _, r := range d.rules {
+		if r.expr != nil {
+			lines[r.line] = r.definition()
+		}
+	}
+
+	changed := false
+	for i := range lines {
+		if lines[i] != d.lines[i] {
+			changed = true
+		}
+	}
+	if !changed {
+		return []TextEdit{}
+	}
+	var sb strings.Builder
+	for i, line := range lines {
+		sb.WriteString(line)
+		sb.WriteString(d.eols[i])
+	}
+	last := len(d.lines) - 1
+	return []TextEdit{{
+		Range:   Range{Position{0, 0}, d.position(last, utf8.RuneCountInString(d.lines[last]))},
+		NewText: sb.String(),
+	}}
+}
@@ -0,0 +1,276 @@
+package lsp
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/svstanev/goexp/types"
+)
+
+// schemaJSON is the JSON form of a types.Schema read by ReadSchema
+type schemaJSON struct {
+	Names   map[string]*nameJSON   `json:"names,omitempty"`
+	Methods map[string]*methodJSON `json:"methods,omitempty"`
+}
+
+type nameJSON struct {
+	Type    string                 `json:"type"`
+	Doc     string                 `json:"doc,omitempty"`
+	Names   map[string]*nameJSON   `json:"names,omitempty"`
+	Methods map[string]*methodJSON `json:"methods,omitempty"`
+}
+
+type methodJSON struct {
+	Params   []string `json:"params"`
+	Variadic bool     `json:"variadic,omitempty"`
+	Returns  string   `json:"returns,omitempty"`
+	Doc      string   `json:"doc,omitempty"`
+}
+
+// UnmarshalJSON accepts a type name or an object
+func (n *nameJSON) UnmarshalJSON(data []byte) error {
+	var t string
+	if err := json.Unmarshal(data, &t); err == nil {
+		*n = nameJSON{Type: t}
+		return nil
+	}
+	type name nameJSON
+	var s name
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	*n = nameJSON(s)
+	return nil
+}
+
+// scalarTypes are the types of names by their names in JSON schemas
+var scalarTypes = map[string]*types.Type{
+	"any":      types.AnyType,
+	"integer":  types.IntegerType,
+	"float":    types.FloatType,
+	"number":   types.NumberType,
+	"string":   types.StringType,
+	"boolean":  types.BooleanType,
+	"nil":      types.NilType,
+	"date":     types.DateType,
+	"duration": types.DurationType,
+}
+
+/*
+ReadSchema decodes the JSON schema of the names and methods available to the
+rules, e.g.
+
+	{
+		"names": {
+			"age": "integer",
+			"user": {"doc": "The current user", "names": {"name": "string"}}
+		},
+		"methods": {
+			"len": {"params": ["string"], "returns": "integer", "doc": "The length of a string"}
+		}
+	}
+
+The types are "any", "integer", "float", "number", "string", "boolean", "nil",
+"date", "duration" or "object" for names with members. A name may be given by
+its type only. The docs are the Docs of the schema by the dotted paths of the
+names and methods, e.g. "user.name".
+*/
+func ReadSchema(r io.Reader) (*types.Schema, error) {
+	d := json.NewDecoder(r)
+	d.DisallowUnknownFields()
+	var s schemaJSON
+	if err := d.Decode(&s); err != nil {
+		return nil, err
+	}
+	res := &types.Schema{Docs: map[string]string{}}
+	var err error
+	if res.Names, res.Methods, err = s.members(nil, res.Docs); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// LoadSchema reads the JSON schema in the given file
+func LoadSchema(filename string) (*types.Schema, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	s, err := ReadSchema(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", filename, err)
+	}
+	return s, nil
+}
+
+// members returns the types of the names and the signatures of the methods at
+// the path and adds their docs
+func (s *schemaJSON) members(path []string, docs map[string]string) (map[string]*types.Type, map[string]*types.Signature, error) {
+	names := make(map[string]*types.Type, len(s.Names))
+	for name, n := range s.Names {
+		p := append(path[:len(path):len(path)], name)
+		t, err := n.typ(p, docs)
+		if err != nil {
+			return nil, nil, err
+		}
+		names[name] = t
+		addDoc(docs, p, n.Doc)
+	}
+
+	methods := make(map[string]*types.Signature, len(s.Methods))
+	for name, m := range s.Methods {
+		p := append(path[:len(path):len(path)], name)
+		sig := &types.Signature{Variadic: m.Variadic}
+		for _, param := range m.Params {
+			t, err := typeOf(param, p)
+			if err != nil {
+				return nil, nil, err
+			}
+			sig.Params = append(sig.Params, t)
+		}
+		if m.Returns != "" {
+			t, err := typeOf(m.Returns, p)
+			if err != nil {
+				return nil, nil, err
+			}
+			sig.Returns = t
+		}
+		methods[name] = sig
+		addDoc(docs, p, m.Doc)
+	}
+	return names, methods, nil
+}
+
+// typ returns the type of the name at the path; names with members are
+// objects and names without a type are Any
+func (n *nameJSON) typ(path []string, docs map[string]string) (*types.Type, error) {
+	members := n.Names != nil || n.Methods != nil
+	switch {
+	case n.Type == "" && !members:
+		return types.AnyType, nil
+	case n.Type != "object" && !members:
+		return typeOf(n.Type, path)
+	case n.Type != "object" && n.Type != "":
+		return nil, fmt.Errorf("Invalid type %q of %s with members", n.Type, strings.Join(path, "."))
+	}
+	s := schemaJSON{Names: n.Names, Methods: n.Methods}
+	fields, methods, err := s.members(path, docs)
+	if err != nil {
+		return nil, err
+	}
+	return types.ObjectOf(fields, methods), nil
+}
+
+func typeOf(name string, path []string) (*types.Type, error) {
+	if t, ok := scalarTypes[name]; ok {
+		return t, nil
+	}
+	return nil, fmt.Errorf("Unknown type %q of %s", name, strings.Join(path, "."))
+}
+
+func addDoc(docs map[string]string, path []string, doc string) {
+	if doc != "" {
+		docs[strings.Join(path, ".")] = doc
+	}
+}
+
+// docOf returns the doc of the name or method at the path
+func docOf(schema *types.Schema, path []string) string {
+	if schema == nil {
+		return ""
+	}
+	return schema.Docs[strings.Join(path, ".")]
+}
+
+// lookup returns the type of the name at the path; the empty path is the
+// object of all names and methods of the schema
+func lookup(schema *types.Schema, path []string) (*types.Type, bool) {
+	t := schema.Type()
+	for _, name := range path {
+		var ok bool
+		if t, ok = t.Field(name); !ok {
+			return nil, false
+		}
+	}
+	return t, true
+}
+
+// method returns the signature of the method at the path and the number of
+// its implicit arguments: a method of a name that is not an object is a
+// method of the schema called with the name as first argument, e.g. name.len()
+// is len(name). The signature is nil if the receiver is Any.
+func method(schema *types.Schema, path []string) (*types.Signature, int, bool) {
+	if len(path) == 0 {
+		return nil, 0, false
+	}
+	receiver, name := path[:len(path)-1], path[len(path)-1]
+	t, ok := lookup(schema, receiver)
+	switch {
+	case !ok:
+		return nil, 0, false
+	case t.Kind == types.AnyKind:
+		return nil, 0, true
+	case t.Kind == types.ObjectKind:
+		m, ok := t.Method(name)
+		return m, 0, ok
+	}
+	m, ok := schema.Type().Method(name)
+	return m, 1, ok
+}
+
+// accepts returns true if the method or one of its overloads can be called with n arguments
+func accepts(m *types.Signature, n int) bool {
+	for _, sig := range append([]*types.Signature{m}, m.Overloads...) {
+		if sig.Accepts(n) {
+			return true
+		}
+	}
+	return false
+}
+
+// signature returns the method as it is called, e.g. max(Integer, ...Integer) Integer;
+// the optional parameters are in brackets
+func signature(name string, m *types.Signature) string {
+	params := make([]string, len(m.Params))
+	for i, p := range m.Params {
+		params[i] = p.String()
+		last := i == len(m.Params)-1
+		if m.Variadic && last {
+			params[i] = "..." + params[i]
+		}
+		if i < len(m.Names) {
+			params[i] = m.Names[i] + " " + params[i]
+		}
+		if i >= m.MinArgs() && !(m.Variadic && last) {
+			params[i] = "[" + params[i] + "]"
+		}
+	}
+	s := fmt.Sprintf("%s(%s)", name, strings.Join(params, ", "))
+	if m.Returns != nil {
+		s += " " + m.Returns.String()
+	}
+	return s
+}
+
+func sortedFields(t *types.Type) []string {
+	res := make([]string, 0, len(t.Fields))
+	for name := range t.Fields {
+		res = append(res, name)
+	}
+	sort.Strings(res)
+	return res
+}
+
+func sortedMethods(t *types.Type) []string {
+	res := make([]string, 0, len(t.Methods))
+	for name := range t.Methods {
+		res = append(res, name)
+	}
+	sort.Strings(res)
+	return res
+}
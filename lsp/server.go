@@ -0,0 +1,182 @@
+/*
+Package lsp implements a Language Server Protocol server for .gx rule files.
+
+Every line of a .gx file is a rule in the form "name = expression"; empty lines
+and lines starting with # are ignored. The rules are computed names that can be
+used by the other rules in the file. The names and the methods available to
+the rules are declared by a types.Schema, e.g. read by ReadSchema.
+
+The server provides diagnostics (syntax errors, undeclared names and methods,
+wrong number of arguments, duplicate rules and cycles), hover, completion of
+names and methods, go to definition of rules and formatting through the
+canonical printer. It supports only full document synchronization.
+*/
+package lsp
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/svstanev/goexp/types"
+)
+
+// Server is a language server for .gx files
+type Server struct {
+	schema      *types.Schema
+	conn        *conn
+	docs        map[string]*document
+	initialized bool
+	shutdown    bool
+}
+
+// NewServer returns a server checking the rules against the schema; nil disables the checks of the names and methods
+func NewServer(schema *types.Schema) *Server {
+	return &Server{schema: schema, docs: map[string]*document{}}
+}
+
+// Serve reads the requests from r and writes the responses and notifications
+// to w until the exit notification or the end of the input
+func (s *Server) Serve(r io.Reader, w io.Writer) error {
+	s.conn = newConn(r, w)
+	for {
+		msg, err := s.conn.read()
+		if err == io.EOF {
+			return nil
+		}
+		if e, ok := err.(*rpcError); ok {
+			s.conn.reply(nil, nil, e)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		if msg.Method == "exit" {
+			return nil
+		}
+		result, err := s.handle(msg)
+		if msg.ID == nil {
+			// notifications have no response
+			continue
+		}
+		if err := s.conn.reply(msg.ID, result, err); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *Server) handle(msg *message) (interface{}, error) {
+	switch {
+	case msg.Method == "initialize":
+		s.initialized = true
+		return InitializeResult{
+			Capabilities: ServerCapabilities{
+				TextDocumentSync:           1,
+				HoverProvider:              true,
+				CompletionProvider:         &CompletionOptions{TriggerCharacters: []string{"."}},
+				DefinitionProvider:         true,
+				DocumentFormattingProvider: true,
+			},
+			ServerInfo: ServerInfo{Name: "goexp"},
+		}, nil
+	case !s.initialized:
+		return nil, &rpcError{codeNotInitialized, "Server not initialized"}
+	case s.shutdown:
+		return nil, &rpcError{codeInvalidRequest, "Server is shut down"}
+	}
+
+	switch msg.Method {
+	case "initialized":
+		return nil, nil
+	case "shutdown":
+		s.shutdown = true
+		return nil, nil
+
+	case "textDocument/didOpen":
+		var params DidOpenTextDocumentParams
+		if err := decodeParams(msg, &params); err != nil {
+			return nil, err
+		}
+		return nil, s.update(params.TextDocument.URI, params.TextDocument.Text)
+
+	case "textDocument/didChange":
+		var params DidChangeTextDocumentParams
+		if err := decodeParams(msg, &params); err != nil {
+			return nil, err
+		}
+		if n := len(params.ContentChanges); n > 0 {
+			return nil, s.update(params.TextDocument.URI, params.ContentChanges[n-1].Text)
+		}
+		return nil, nil
+
+	case "textDocument/didClose":
+		var params DidCloseTextDocumentParams
+		if err := decodeParams(msg, &params); err != nil {
+			return nil, err
+		}
+		delete(s.docs, params.TextDocument.URI)
+		return nil, s.conn.notify("textDocument/publishDiagnostics", PublishDiagnosticsParams{URI: params.TextDocument.URI, Diagnostics: []Diagnostic{}})
+
+	case "textDocument/hover":
+		d, params, err := s.position(msg)
+		if err != nil || d == nil {
+			return nil, err
+		}
+		if h := d.hover(s.schema, params.Position); h != nil {
+			return h, nil
+		}
+		return nil, nil
+
+	case "textDocument/completion":
+		d, params, err := s.position(msg)
+		if err != nil || d == nil {
+			return nil, err
+		}
+		return CompletionList{Items: d.completion(s.schema, params.Position)}, nil
+
+	case "textDocument/definition":
+		d, params, err := s.position(msg)
+		if err != nil || d == nil {
+			return nil, err
+		}
+		if l := d.definition(params.Position); l != nil {
+			return l, nil
+		}
+		return nil, nil
+
+	case "textDocument/formatting":
+		var params DocumentFormattingParams
+		if err := decodeParams(msg, &params); err != nil {
+			return nil, err
+		}
+		d := s.docs[params.TextDocument.URI]
+		if d == nil {
+			return nil, nil
+		}
+		return d.format(), nil
+	}
+
+	return nil, &rpcError{codeMethodNotFound, "Method not found " + msg.Method}
+}
+
+// update replaces the text of a document and publishes its diagnostics
+func (s *Server) update(uri, text string) error {
+	d := newDocument(uri, text)
+	s.docs[uri] = d
+	return s.conn.notify("textDocument/publishDiagnostics", PublishDiagnosticsParams{URI: uri, Diagnostics: d.diagnostics(s.schema)})
+}
+
+func (s *Server) position(msg *message) (*document, *TextDocumentPositionParams, error) {
+	var params TextDocumentPositionParams
+	if err := decodeParams(msg, &params); err != nil {
+		return nil, nil, err
+	}
+	return s.docs[params.TextDocument.URI], &params, nil
+}
+
+func decodeParams(msg *message, params interface{}) error {
+	if err := json.Unmarshal(msg.Params, params); err != nil {
+		return &rpcError{codeInvalidParams, err.Error()}
+	}
+	return nil
+}
@@ -0,0 +1,428 @@
+package lsp
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/go-test/deep"
+	"github.com/svstanev/goexp/types"
+)
+
+const testSchema = `{
+	"names": {
+		"age": "integer",
+		"country": {"type": "string", "doc": "ISO country code"},
+		"user": {"doc": "The current user", "names": {"name": "string", "address": {"names": {"city": "string"}}}, "methods": {"orders": {"params": [], "returns": "integer"}}}
+	},
+	"methods": {
+		"len": {"params": ["string"], "returns": "integer", "doc": "The length of a string"},
+		"max": {"params": ["integer", "integer"], "variadic": true, "returns": "integer"}
+	}
+}`
+
+// client is an in-process JSON-RPC client of a server
+type client struct {
+	t             *testing.T
+	conn          *conn
+	id            int
+	notifications []*message
+	messages      chan *message
+	done          chan error
+}
+
+func newClient(t *testing.T, schema *types.Schema) *client {
+	serverIn, clientOut := io.Pipe()
+	clientIn, serverOut := io.Pipe()
+	c := &client{t: t, conn: newConn(clientIn, clientOut), messages: make(chan *message, 100), done: make(chan error, 1)}
+	go func() {
+		err := NewServer(schema).Serve(serverIn, serverOut)
+		serverOut.Close()
+		c.done <- err
+	}()
+	// read continuously so the server never blocks on the synchronous pipe
+	go func() {
+		for {
+			msg, err := c.conn.read()
+			if err != nil {
+				close(c.messages)
+				return
+			}
+			c.messages <- msg
+		}
+	}()
+	return c
+}
+
+// call sends a request and returns its response, collecting the notifications sent before it
+func (c *client) call(method string, params interface{}, result interface{}) *rpcError {
+	c.id++
+	id := json.RawMessage(fmt.Sprint(c.id))
+	c.send(&message{ID: &id, Method: method}, params)
+	for msg := range c.messages {
+		if msg.ID == nil {
+			c.notifications = append(c.notifications, msg)
+			continue
+		}
+		if string(*msg.ID) != string(id) {
+			c.t.Fatalf("Expected response %s but got %s", id, *msg.ID)
+		}
+		if msg.Error != nil {
+			return msg.Error
+		}
+		if result != nil {
+			if err := json.Unmarshal(msg.Result, result); err != nil {
+				c.t.Fatal(err)
+			}
+		}
+		return nil
+	}
+	c.t.Fatal("Connection closed")
+	return nil
+}
+
+func (c *client) notify(method string, params interface{}) {
+	c.send(&message{Method: method}, params)
+}
+
+func (c *client) send(msg *message, params interface{}) {
+	data, err := json.Marshal(params)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	msg.Params = data
+	if err := c.conn.write(msg); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+// diagnostics returns the diagnostics published last, after a round trip to the server
+func (c *client) diagnostics(uri string) []Diagnostic {
+	c.call("textDocument/hover", TextDocumentPositionParams{TextDocument: TextDocumentIdentifier{URI: "none"}}, nil)
+	for i := len(c.notifications) - 1; i >= 0; i-- {
+		var params PublishDiagnosticsParams
+		if err := json.Unmarshal(c.notifications[i].Params, &params); err != nil {
+			c.t.Fatal(err)
+		}
+		if params.URI == uri {
+			return params.Diagnostics
+		}
+	}
+	c.t.Fatalf("No diagnostics for %s", uri)
+	return nil
+}
+
+func (c *client) close() {
+	if err := c.call("shutdown", nil, nil); err != nil {
+		c.t.Fatal(err)
+	}
+	c.notify("exit", nil)
+	if err := <-c.done; err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+func startClient(t *testing.T, text string) *client {
+	schema, err := ReadSchema(strings.NewReader(testSchema))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := newClient(t, schema)
+	var res InitializeResult
+	if err := c.call("initialize", map[string]interface{}{}, &res); err != nil {
+		t.Fatal(err)
+	}
+	if !res.Capabilities.HoverProvider || res.Capabilities.TextDocumentSync != 1 {
+		t.Fatalf("Unexpected capabilities %+v", res.Capabilities)
+	}
+	c.notify("initialized", map[string]interface{}{})
+	c.notify("textDocument/didOpen", DidOpenTextDocumentParams{TextDocument: TextDocumentItem{URI: "file:///rules.gx", LanguageID: "goexp", Version: 1, Text: text}})
+	return c
+}
+
+func at(line, character int) TextDocumentPositionParams {
+	return TextDocumentPositionParams{TextDocument: TextDocumentIdentifier{URI: "file:///rules.gx"}, Position: Position{line, character}}
+}
+
+func span(line, start, end int) Range {
+	return Range{Position{line, start}, Position{line, end}}
+}
+
+func TestDiagnostics(t *testing.T) {
+	c := startClient(t, strings.Join([]string{
+		"# rules",
+		"adult = age >= 18",
+		"local = country == 'BG' && adult",
+		"bad = age +",
+		"unknown = foo > 1 && user.email == '' && user.address.city == 'Sofia' && len(o => o.zip) > 0 && max(1, digits: age) > 0 && (let n = age; n > 1) && `${user.phone}` != ''",
+		"calls = len(country) + len(1, 2) + max() + max(1, 2, 3) + nope() + user.orders() + country.len() + country.len(1)",
+		"not a rule",
+		"adult = true",
+		"a = b",
+		"b = a || c",
+		"c = '😀' + zzz",
+	}, "\n"))
+	defer c.close()
+
+	expected := []Diagnostic{
+		{span(6, 0, 10), SeverityError, "goexp", "Expected <name> = <expression>"},
+		{span(3, 11, 11), SeverityError, "goexp", "Parse Error at pos 6: Unexpected end of expression"},
+		{span(4, 10, 13), SeverityWarning, "goexp", "foo is not declared"},
+		{span(4, 21, 31), SeverityWarning, "goexp", "user.email is not declared"},
+		{span(4, 150, 160), SeverityWarning, "goexp", "user.phone is not declared"},
+		{span(5, 23, 26), SeverityError, "goexp", "len(String) Integer expects 1 arguments but got 2"},
+		{span(5, 35, 38), SeverityError, "goexp", "max(Integer, ...Integer) Integer expects at least 1 arguments but got 0"},
+		{span(5, 58, 62), SeverityWarning, "goexp", "Method nope is not declared"},
+		{span(5, 99, 110), SeverityError, "goexp", "len(String) Integer expects 1 arguments but got 2"},
+		{span(7, 0, 5), SeverityError, "goexp", "adult is already defined on line 2"},
+		{span(10, 11, 14), SeverityWarning, "goexp", "zzz is not declared"},
+		{span(8, 0, 1), SeverityError, "goexp", "Cycle detected: a -> b -> a"},
+		{span(9, 0, 1), SeverityError, "goexp", "Cycle detected: b -> a -> b"},
+	}
+	if diff := deep.Equal(c.diagnostics("file:///rules.gx"), expected); diff != nil {
+		t.Error(diff)
+	}
+
+	c.notify("textDocument/didChange", DidChangeTextDocumentParams{
+		TextDocument:   TextDocumentIdentifier{URI: "file:///rules.gx"},
+		ContentChanges: []TextDocumentContentChangeEvent{{Text: "ok = age > 1"}},
+	})
+	if d := c.diagnostics("file:///rules.gx"); len(d) != 0 {
+		t.Errorf("Expected no diagnostics but got %v", d)
+	}
+
+	c.notify("textDocument/didClose", DidCloseTextDocumentParams{TextDocument: TextDocumentIdentifier{URI: "file:///rules.gx"}})
+	if d := c.diagnostics("file:///rules.gx"); len(d) != 0 {
+		t.Errorf("Expected no diagnostics but got %v", d)
+	}
+}
+
+// TestDiagnosticsScopes checks that the names bound by lambdas and let hide
+// the names of the context only in their scope and that member access chains
+// depend on their rules
+func TestDiagnosticsScopes(t *testing.T) {
+	c := startClient(t, strings.Join([]string{
+		"a = nope(age, x => x.a) && x > 1",
+		"b = (let y = age; y) + y",
+		"c = let z = z; z",
+		"d = `${v => v}${v}` != ''",
+		"e = let w = 1; nope(w, w => w) + w",
+		"f = let a = age; let b = a; b.len() + nope(b, u => u.len()) + a",
+		"g = h.x",
+		"h = g",
+	}, "\n"))
+	defer c.close()
+
+	expected := []Diagnostic{
+		{span(0, 4, 8), SeverityWarning, "goexp", "Method nope is not declared"},
+		{span(0, 27, 28), SeverityWarning, "goexp", "x is not declared"},
+		{span(1, 23, 24), SeverityWarning, "goexp", "y is not declared"},
+		{span(2, 12, 13), SeverityWarning, "goexp", "z is not declared"},
+		{span(3, 16, 17), SeverityWarning, "goexp", "v is not declared"},
+		{span(4, 15, 19), SeverityWarning, "goexp", "Method nope is not declared"},
+		{span(5, 38, 42), SeverityWarning, "goexp", "Method nope is not declared"},
+		{span(6, 4, 7), SeverityWarning, "goexp", "h.x is not declared"},
+		{span(6, 0, 1), SeverityError, "goexp", "Cycle detected: g -> h -> g"},
+		{span(7, 0, 1), SeverityError, "goexp", "Cycle detected: h -> g -> h"},
+	}
+	if diff := deep.Equal(c.diagnostics("file:///rules.gx"), expected); diff != nil {
+		t.Error(diff)
+	}
+}
+
+func TestReadSchema(t *testing.T) {
+	schema, err := ReadSchema(strings.NewReader(`{"names": {"id": {"doc": "The id"}, "user": {"names": {"born": "date"}, "methods": {"age": {"params": [], "returns": "integer", "doc": "The age"}}}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := &types.Schema{
+		Names: map[string]*types.Type{
+			"id": types.AnyType,
+			"user": types.ObjectOf(
+				map[string]*types.Type{"born": types.DateType},
+				map[string]*types.Signature{"age": {Returns: types.IntegerType}},
+			),
+		},
+		Methods: map[string]*types.Signature{},
+		Docs:    map[string]string{"id": "The id", "user.age": "The age"},
+	}
+	if diff := deep.Equal(schema, expected); diff != nil {
+		t.Error(diff)
+	}
+
+	tests := []struct {
+		schema string
+		err    string
+	}{
+		{`{"names": {"a": "int"}}`, `Unknown type "int" of a`},
+		{`{"names": {"a": {"names": {"b": "string"}, "methods": {"m": {"params": ["list"]}}}}}`, `Unknown type "list" of a.m`},
+		{`{"names": {"a": {"type": "string", "names": {"b": "string"}}}}`, `Invalid type "string" of a with members`},
+		{`{"name": {}}`, `json: unknown field "name"`},
+	}
+	for _, test := range tests {
+		if _, err := ReadSchema(strings.NewReader(test.schema)); err == nil || err.Error() != test.err {
+			t.Errorf("%s: expected error %q but got %v", test.schema, test.err, err)
+		}
+	}
+}
+
+func TestSignature(t *testing.T) {
+	tests := []struct {
+		sig      *types.Signature
+		expected string
+	}{
+		{&types.Signature{}, "f()"},
+		{&types.Signature{Params: []*types.Type{types.StringType, nil}, Variadic: true, Returns: types.StringType}, "f(String, ...Any) String"},
+		{&types.Signature{Params: []*types.Type{types.NumberType, types.IntegerType}, Optional: 1, Names: []string{"x", "digits"}, Returns: types.FloatType}, "f(x Number, [digits Integer]) Float"},
+	}
+	for _, test := range tests {
+		if s := signature("f", test.sig); s != test.expected {
+			t.Errorf("Expected %s but got %s", test.expected, s)
+		}
+	}
+}
+
+func TestHover(t *testing.T) {
+	c := startClient(t, "adult = age >= 18\nlocal = (country=='BG')  &&  adult && len(user.address.city) > 0\n")
+	defer c.close()
+
+	tests := []struct {
+		pos      TextDocumentPositionParams
+		expected *Hover
+	}{
+		{at(0, 2), &Hover{MarkupContent{"markdown", "```goexp\nadult = age >= 18\n```"}, &Range{Position{0, 0}, Position{0, 5}}}},
+		{at(0, 9), &Hover{MarkupContent{"markdown", "```goexp\nage: Integer\n```"}, &Range{Position{0, 8}, Position{0, 11}}}},
+		{at(1, 10), &Hover{MarkupContent{"markdown", "```goexp\ncountry: String\n```\n\nISO country code"}, &Range{Position{1, 9}, Position{1, 16}}}},
+		{at(1, 31), &Hover{MarkupContent{"markdown", "```goexp\nadult = age >= 18\n```\n\nDefined on line 1"}, &Range{Position{1, 29}, Position{1, 34}}}},
+		{at(1, 39), &Hover{MarkupContent{"markdown", "```goexp\nlen(String) Integer\n```\n\nThe length of a string"}, &Range{Position{1, 38}, Position{1, 41}}}},
+		{at(1, 43), &Hover{MarkupContent{"markdown", "```goexp\nuser: Object{address, name}\n```\n\nThe current user"}, &Range{Position{1, 42}, Position{1, 46}}}},
+		{at(1, 50), &Hover{MarkupContent{"markdown", "```goexp\nuser.address: Object{city}\n```"}, &Range{Position{1, 47}, Position{1, 54}}}},
+		{at(1, 25), nil},
+	}
+	for _, test := range tests {
+		var h *Hover
+		if err := c.call("textDocument/hover", test.pos, &h); err != nil {
+			t.Fatal(err)
+		}
+		if diff := deep.Equal(h, test.expected); diff != nil {
+			t.Errorf("%v: %v", test.pos.Position, diff)
+		}
+	}
+}
+
+func TestCompletion(t *testing.T) {
+	c := startClient(t, "adult = age >= 18\nx = a\ny = user.\nz = user.address.c\nw = 'us\nv = f().")
+	defer c.close()
+
+	labels := func(pos TextDocumentPositionParams) []string {
+		var list CompletionList
+		if err := c.call("textDocument/completion", pos, &list); err != nil {
+			t.Fatal(err)
+		}
+		res := []string{}
+		for _, item := range list.Items {
+			res = append(res, fmt.Sprintf("%s:%d:%s", item.Label, item.Kind, item.Detail))
+		}
+		return res
+	}
+
+	tests := []struct {
+		pos      TextDocumentPositionParams
+		expected []string
+	}{
+		{at(1, 5), []string{"age:6:Integer", "adult:6:rule"}},
+		{at(1, 4), []string{"age:6:Integer", "country:6:String", "user:6:Object{address, name}", "len:3:len(String) Integer", "max:3:max(Integer, ...Integer) Integer", "adult:6:rule", "y:6:rule", "z:6:rule", "w:6:rule", "v:6:rule"}},
+		{at(0, 10), []string{"age:6:Integer"}},
+		{at(2, 9), []string{"address:5:Object{city}", "name:5:String", "orders:2:orders() Integer"}},
+		{at(3, 18), []string{"city:5:String"}},
+		{at(4, 7), []string{}},
+		{at(5, 8), []string{}},
+		{at(0, 3), []string{}},
+	}
+	for _, test := range tests {
+		if diff := deep.Equal(labels(test.pos), test.expected); diff != nil {
+			t.Errorf("%v: %v", test.pos.Position, diff)
+		}
+	}
+}
+
+func TestDefinition(t *testing.T) {
+	c := startClient(t, "# adults\n  adult = age >= 18\nlocal = adult && country == 'BG'")
+	defer c.close()
+
+	var loc *Location
+	if err := c.call("textDocument/definition", at(2, 10), &loc); err != nil {
+		t.Fatal(err)
+	}
+	expected := &Location{URI: "file:///rules.gx", Range: span(1, 2, 7)}
+	if diff := deep.Equal(loc, expected); diff != nil {
+		t.Error(diff)
+	}
+
+	loc = nil
+	if err := c.call("textDocument/definition", at(2, 20), &loc); err != nil {
+		t.Fatal(err)
+	}
+	if loc != nil {
+		t.Errorf("Expected no definition but got %v", loc)
+	}
+}
+
+func TestFormatting(t *testing.T) {
+	c := startClient(t, "# rules\nadult=age>=18 and (country=='BG')\nbad = 1 +\nok = 1 + 2\n")
+	defer c.close()
+
+	var edits []TextEdit
+	if err := c.call("textDocument/formatting", DocumentFormattingParams{TextDocument: TextDocumentIdentifier{URI: "file:///rules.gx"}}, &edits); err != nil {
+		t.Fatal(err)
+	}
+	expected := []TextEdit{{
+		Range:   Range{Position{0, 0}, Position{4, 0}},
+		NewText: "# rules\nadult = age >= 18 && country == \"BG\"\nbad = 1 +\nok = 1 + 2\n",
+	}}
+	if diff := deep.Equal(edits, expected); diff != nil {
+		t.Error(diff)
+	}
+}
+
+func TestFormattingCRLF(t *testing.T) {
+	c := startClient(t, "# rules\r\nadult=age>=18\n\r\nok = 1 + 2\r\nlocal=country=='BG'")
+	defer c.close()
+
+	var edits []TextEdit
+	if err := c.call("textDocument/formatting", DocumentFormattingParams{TextDocument: TextDocumentIdentifier{URI: "file:///rules.gx"}}, &edits); err != nil {
+		t.Fatal(err)
+	}
+	expected := []TextEdit{{
+		Range:   Range{Position{0, 0}, Position{4, 19}},
+		NewText: "# rules\r\nadult = age >= 18\n\r\nok = 1 + 2\r\nlocal = country == \"BG\"",
+	}}
+	if diff := deep.Equal(edits, expected); diff != nil {
+		t.Error(diff)
+	}
+}
+
+func TestProtocolErrors(t *testing.T) {
+	c := newClient(t, nil)
+	if err := c.call("textDocument/hover", at(0, 0), nil); err == nil || err.Code != codeNotInitialized {
+		t.Errorf("Expected not initialized error but got %v", err)
+	}
+	if err := c.call("initialize", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.call("foo/bar", nil, nil); err == nil || err.Code != codeMethodNotFound {
+		t.Errorf("Expected method not found error but got %v", err)
+	}
+	if err := c.call("textDocument/hover", "x", nil); err == nil || err.Code != codeInvalidParams {
+		t.Errorf("Expected invalid params error but got %v", err)
+	}
+
+	// without a schema only the rules are known
+	c.notify("textDocument/didOpen", DidOpenTextDocumentParams{TextDocument: TextDocumentItem{URI: "file:///a.gx", Text: "a = x + y(1)\nb = a"}})
+	if d := c.diagnostics("file:///a.gx"); len(d) != 0 {
+		t.Errorf("Expected no diagnostics but got %v", d)
+	}
+	c.close()
+}
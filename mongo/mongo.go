@@ -0,0 +1,142 @@
+/*
+Package mongo translates goexp expressions to MongoDB filter documents.
+
+	expr, _ := goexp.Parse("age >= 18 && (city == 'Sofia' || vip)")
+	filter, residual, err := mongo.Filter(expr, nil)
+	// filter: {"$and": [{"age": {"$gte": 18}}, {"$or": [{"city": "Sofia"}, {"vip": true}]}]}
+
+The supported expressions are comparisons of fields with constants,
+in(field, value1, value2, ...), boolean fields and their combinations with
+&&, || and !.
+*/
+package mongo
+
+import (
+	"strings"
+
+	"github.com/svstanev/goexp"
+	"github.com/svstanev/goexp/internal/pushdown"
+)
+
+// FieldMapper maps the path of an identifier (e.g. ["user", "age"] for user.age) to a field name
+type FieldMapper = pushdown.FieldMapper
+
+var operators = map[goexp.TokenType]string{
+	goexp.Equal:        "$eq",
+	goexp.NotEqual:     "$ne",
+	goexp.Less:         "$lt",
+	goexp.LessEqual:    "$lte",
+	goexp.Greater:      "$gt",
+	goexp.GreaterEqual: "$gte",
+}
+
+/*
+Filter translates the expression to a MongoDB filter document.
+
+The conjuncts of the expression that cannot be translated are returned as
+residual expressions; the filter then matches a superset of the documents and
+the residual expressions must be evaluated on the results. By default the
+fields are named by joining the path of the identifiers with ".".
+*/
+func Filter(expr goexp.Expr, fields FieldMapper) (map[string]interface{}, []goexp.Expr, error) {
+	if fields == nil {
+		fields = func(path []string) (string, error) {
+			return strings.Join(path, "."), nil
+		}
+	}
+	t := &translator{fields}
+
+	var filters []interface{}
+	var residual []goexp.Expr
+	for _, x := range pushdown.Operands(expr, goexp.And) {
+		filter, ok, err := t.translate(x)
+		if err != nil {
+			return nil, nil, err
+		}
+		if ok {
+			filters = append(filters, filter)
+		} else {
+			residual = append(residual, x)
+		}
+	}
+
+	switch len(filters) {
+	case 0:
+		return map[string]interface{}{}, residual, nil
+	case 1:
+		return filters[0].(map[string]interface{}), residual, nil
+	default:
+		return map[string]interface{}{"$and": filters}, residual, nil
+	}
+}
+
+type translator struct {
+	fields FieldMapper
+}
+
+// translate returns false if the expression cannot be translated
+func (t *translator) translate(expr goexp.Expr) (map[string]interface{}, bool, error) {
+	if c, ok := pushdown.AsComparison(expr); ok {
+		field, err := t.fields(c.Path)
+		if err != nil {
+			return nil, false, err
+		}
+		if c.Op == goexp.Equal {
+			return map[string]interface{}{field: c.Value}, true, nil
+		}
+		return map[string]interface{}{field: map[string]interface{}{operators[c.Op]: c.Value}}, true, nil
+	}
+
+	if path, values, ok := pushdown.AsIn(expr); ok {
+		field, err := t.fields(path)
+		if err != nil {
+			return nil, false, err
+		}
+		return map[string]interface{}{field: map[string]interface{}{"$in": values}}, true, nil
+	}
+
+	switch e := pushdown.Ungroup(expr).(type) {
+	case goexp.IdentifierExpr:
+		path, ok := goexp.Path(e)
+		if !ok {
+			return nil, false, nil
+		}
+		field, err := t.fields(path)
+		if err != nil {
+			return nil, false, err
+		}
+		return map[string]interface{}{field: true}, true, nil
+
+	case goexp.UnaryExpr:
+		if e.Operator.Type != goexp.Not {
+			return nil, false, nil
+		}
+		filter, ok, err := t.translate(e.Value)
+		if !ok || err != nil {
+			return nil, false, err
+		}
+		return map[string]interface{}{"$nor": []interface{}{filter}}, true, nil
+
+	case goexp.BinaryExpr:
+		var op string
+		switch e.Operator.Type {
+		case goexp.And:
+			op = "$and"
+		case goexp.Or:
+			op = "$or"
+		default:
+			return nil, false, nil
+		}
+		var filters []interface{}
+		for _, x := range pushdown.Operands(e, e.Operator.Type) {
+			filter, ok, err := t.translate(x)
+			if !ok || err != nil {
+				return nil, false, err
+			}
+			filters = append(filters, filter)
+		}
+		return map[string]interface{}{op: filters}, true, nil
+	}
+
+	return nil, false, nil
+}
@@ -0,0 +1,81 @@
+package mongo
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/svstanev/goexp"
+)
+
+func TestFilter(t *testing.T) {
+	tests := []struct {
+		expr     string
+		filter   string
+		residual []string
+	}{
+		{"age >= 18", `{"age":{"$gte":18}}`, nil},
+		{"18 < age", `{"age":{"$gt":18}}`, nil},
+		{"name == 'Bob'", `{"name":"Bob"}`, nil},
+		{"email != nil", `{"email":{"$ne":null}}`, nil},
+		{"user.address.city == 'Sofia'", `{"user.address.city":"Sofia"}`, nil},
+		{"balance > -1.5", `{"balance":{"$gt":-1.5}}`, nil},
+		{"age >= 18 && (city == 'Sofia' || vip)", `{"$and":[{"age":{"$gte":18}},{"$or":[{"city":"Sofia"},{"vip":true}]}]}`, nil},
+		{"!(a == 1 || b == 2)", `{"$nor":[{"$or":[{"a":1},{"b":2}]}]}`, nil},
+		{"in(status, 'new', 'open')", `{"status":{"$in":["new","open"]}}`, nil},
+		{"a == 1 && b == c && d < 2", `{"$and":[{"a":1},{"d":{"$lt":2}}]}`, []string{"b == c"}},
+		{"a == 1 || b == c", `{}`, []string{"a == 1 || b == c"}},
+		{"len(name) > 3 && x + 1 > 2", `{}`, []string{"len(name) > 3", "x + 1 > 2"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.expr, func(t *testing.T) {
+			expr, err := goexp.Parse(test.expr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			filter, residual, err := Filter(expr, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, err := json.Marshal(filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != test.filter {
+				t.Errorf("Expected %s but got %s", test.filter, data)
+			}
+			if len(residual) != len(test.residual) {
+				t.Fatalf("Expected residual %v but got %v", test.residual, residual)
+			}
+			for i, x := range residual {
+				if s, _ := goexp.Print(x); s != test.residual[i] {
+					t.Errorf("Expected residual %s but got %s", test.residual[i], s)
+				}
+			}
+		})
+	}
+}
+
+func TestFilterFieldMapper(t *testing.T) {
+	fields := func(path []string) (string, error) {
+		if path[0] == "secret" {
+			return "", fmt.Errorf("Unknown field secret")
+		}
+		return "doc_" + path[0], nil
+	}
+
+	expr, _ := goexp.Parse("age > 1")
+	filter, _, err := Filter(expr, fields)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := json.Marshal(filter); string(data) != `{"doc_age":{"$gt":1}}` {
+		t.Errorf("Unexpected filter %s", data)
+	}
+
+	expr, _ = goexp.Parse("age > 1 && secret == 1")
+	if _, _, err := Filter(expr, fields); err == nil {
+		t.Error("Expected the mapper error")
+	}
+}
@@ -56,6 +56,32 @@ type IdentifierExpr struct {
 	Expr Expr
 }
 
+// LambdaExpr is an anonymous function of one parameter, e.g. o => o.paid
+type LambdaExpr struct {
+	Param string
+	Body  Expr
+}
+
+// LetExpr binds the value of an expression to a name in the scope of the body,
+// e.g. let total = a + b; total > 100
+type LetExpr struct {
+	Name  string
+	Value Expr
+	Body  Expr
+}
+
+// TemplateExpr is a template string, e.g. `Hello ${name}`; the text between
+// the embedded expressions is represented by string literals
+type TemplateExpr struct {
+	Parts []Expr
+}
+
+// NamedArgExpr is an argument of a call passed by the name of the parameter, e.g. digits: 2
+type NamedArgExpr struct {
+	Name  string
+	Value Expr
+}
+
 func (StringLiteralExpr) exprNode()  {}
 func (IntegerLiteralExpr) exprNode() {}
 func (FloatLiteralExpr) exprNode()   {}
@@ -66,6 +92,10 @@ func (BinaryExpr) exprNode()         {}
 func (CallExpr) exprNode()           {}
 func (IdentifierExpr) exprNode()     {}
 func (GroupingExpr) exprNode()       {}
+func (LambdaExpr) exprNode()         {}
+func (NamedArgExpr) exprNode()       {}
+func (LetExpr) exprNode()            {}
+func (TemplateExpr) exprNode()       {}
 
 func (s StringLiteralExpr) Accept(v Visitor, context VisitorContext) (interface{}, error) {
 	return v.VisitStringLiteralExpr(s, context)
@@ -106,3 +136,65 @@ func (e CallExpr) Accept(v Visitor, context VisitorContext) (interface{}, error)
 func (e GroupingExpr) Accept(v Visitor, context VisitorContext) (interface{}, error) {
 	return v.VisitGroupingExpr(e, context)
 }
+
+func (e LambdaExpr) Accept(v Visitor, context VisitorContext) (interface{}, error) {
+	return v.VisitLambdaExpr(e, context)
+}
+
+func (e NamedArgExpr) Accept(v Visitor, context VisitorContext) (interface{}, error) {
+	return v.VisitNamedArgExpr(e, context)
+}
+
+func (e LetExpr) Accept(v Visitor, context VisitorContext) (interface{}, error) {
+	return v.VisitLetExpr(e, context)
+}
+
+func (e TemplateExpr) Accept(v Visitor, context VisitorContext) (interface{}, error) {
+	return v.VisitTemplateExpr(e, context)
+}
+
+// Path returns the names of a chain of identifiers, e.g. ["a", "b", "c"] for a.b.c.
+// It returns false if the expression is not an identifier or the chain contains other expressions.
+func Path(expr Expr) ([]string, bool) {
+	var path []string
+	for expr != nil {
+		id, ok := expr.(IdentifierExpr)
+		if !ok {
+			return nil, false
+		}
+		path = append([]string{id.Name}, path...)
+		expr = id.Expr
+	}
+	return path, path != nil
+}
+
+// children returns the subexpressions of the expression in the order they are evaluated
+func children(expr Expr) []Expr {
+	switch e := expr.(type) {
+	case GroupingExpr:
+		return []Expr{e.Expr}
+	case UnaryExpr:
+		return []Expr{e.Value}
+	case BinaryExpr:
+		return []Expr{e.Left, e.Right}
+	case IdentifierExpr:
+		if e.Expr != nil {
+			return []Expr{e.Expr}
+		}
+	case CallExpr:
+		var res []Expr
+		if id, ok := e.Name.(IdentifierExpr); ok && id.Expr != nil {
+			res = append(res, id.Expr)
+		}
+		return append(res, e.Args...)
+	case LambdaExpr:
+		return []Expr{e.Body}
+	case NamedArgExpr:
+		return []Expr{e.Value}
+	case LetExpr:
+		return []Expr{e.Value, e.Body}
+	case TemplateExpr:
+		return e.Parts
+	}
+	return nil
+}
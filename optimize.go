@@ -0,0 +1,289 @@
+package goexp
+
+import (
+	"math"
+	"strings"
+
+	"github.com/svstanev/goexp/types"
+)
+
+/*
+Optimize returns a simplified version of the expression:
+
+  - operations on literals are folded into a single literal
+  - grouping expressions are dropped
+  - boolean identities are applied, e.g. "x > 0 && true" becomes "x > 0", "!(!(x > 0))"
+    becomes "x > 0" and "false && x" becomes "false"
+
+Since the evaluation neither converts the operands of "&&", "||" and "!" nor
+short-circuits, "x && true" and "!(!x)" are only simplified if x is known to
+be a Boolean: a boolean literal, a comparison or a logical operation. The
+absorbing operands, "false && x" and "true || x", drop x whatever it is, and
+so the errors it would report when evaluated. Operations that fail (e.g. division by zero) are left as they are so the
+error is reported when the expression is evaluated; so are operations whose
+result has no literal, e.g. 1.0 / 0 (+Inf).
+*/
+func Optimize(expr Expr) Expr {
+	return OptimizeWith(expr, nil)
+}
+
+// OptimizeWith optimizes the expression like Optimize and also folds calls
+// with constant arguments to the pure methods of the given context
+func OptimizeWith(expr Expr, context Context) Expr {
+	o := &optimizer{context}
+	return o.optimize(expr)
+}
+
+type optimizer struct {
+	context Context
+}
+
+func (o *optimizer) optimize(expr Expr) Expr {
+	res, _ := expr.Accept(o, nil)
+	return res.(Expr)
+}
+
+func (o *optimizer) VisitStringLiteralExpr(e StringLiteralExpr, context VisitorContext) (interface{}, error) {
+	return e, nil
+}
+
+func (o *optimizer) VisitIntegerLiteralExpr(e IntegerLiteralExpr, context VisitorContext) (interface{}, error) {
+	return e, nil
+}
+
+func (o *optimizer) VisitFloatLiteralExpr(e FloatLiteralExpr, context VisitorContext) (interface{}, error) {
+	return e, nil
+}
+
+func (o *optimizer) VisitBooleanLiteralExpr(e BooleanLiteralExpr, context VisitorContext) (interface{}, error) {
+	return e, nil
+}
+
+func (o *optimizer) VisitNilLiteralExpr(e NilLiteralExpr, context VisitorContext) (interface{}, error) {
+	return e, nil
+}
+
+func (o *optimizer) VisitGroupingExpr(e GroupingExpr, context VisitorContext) (interface{}, error) {
+	return o.optimize(e.Expr), nil
+}
+
+func (o *optimizer) VisitLambdaExpr(e LambdaExpr, context VisitorContext) (interface{}, error) {
+	return LambdaExpr{Param: e.Param, Body: o.optimize(e.Body)}, nil
+}
+
+func (o *optimizer) VisitLetExpr(e LetExpr, context VisitorContext) (interface{}, error) {
+	return LetExpr{Name: e.Name, Value: o.optimize(e.Value), Body: o.optimize(e.Body)}, nil
+}
+
+func (o *optimizer) VisitTemplateExpr(e TemplateExpr, context VisitorContext) (interface{}, error) {
+	parts := make([]Expr, len(e.Parts))
+	for i, part := range e.Parts {
+		parts[i] = o.optimize(part)
+	}
+	return foldTemplate(parts), nil
+}
+
+func (o *optimizer) VisitNamedArgExpr(e NamedArgExpr, context VisitorContext) (interface{}, error) {
+	return NamedArgExpr{Name: e.Name, Value: o.optimize(e.Value)}, nil
+}
+
+func (o *optimizer) VisitIdentifierExpr(e IdentifierExpr, context VisitorContext) (interface{}, error) {
+	if e.Expr != nil {
+		e.Expr = o.optimize(e.Expr)
+	}
+	return e, nil
+}
+
+func (o *optimizer) VisitUnaryExpr(e UnaryExpr, context VisitorContext) (interface{}, error) {
+	value := o.optimize(e.Value)
+
+	if e.Operator.Type == Not {
+		if inner, ok := value.(UnaryExpr); ok && inner.Operator.Type == Not && boolean(inner.Value) {
+			return inner.Value, nil
+		}
+	}
+
+	if x, ok := literalValue(value); ok {
+		if res, err := unaryOp(x, e.Operator); err == nil {
+			if lit, ok := valueLiteral(res); ok {
+				return lit, nil
+			}
+		}
+	}
+
+	return UnaryExpr{Value: value, Operator: e.Operator}, nil
+}
+
+func (o *optimizer) VisitBinaryExpr(e BinaryExpr, context VisitorContext) (interface{}, error) {
+	left, right := o.optimize(e.Left), o.optimize(e.Right)
+
+	switch e.Operator.Type {
+	case And:
+		if res, ok := simplifyLogical(left, right, false); ok {
+			return res, nil
+		}
+	case Or:
+		if res, ok := simplifyLogical(left, right, true); ok {
+			return res, nil
+		}
+	}
+
+	if x, ok := literalValue(left); ok {
+		if y, ok := literalValue(right); ok {
+			if res, err := binaryOp(x, y, e.Operator); err == nil {
+				if lit, ok := valueLiteral(res); ok {
+					return lit, nil
+				}
+			}
+		}
+	}
+
+	return BinaryExpr{Left: left, Right: right, Operator: e.Operator}, nil
+}
+
+// simplifyLogical applies the identities of "&&" (absorbing is false) and "||"
+// (absorbing is true); the identity operand is dropped only if the other one
+// is a boolean
+func simplifyLogical(left, right Expr, absorbing bool) (Expr, bool) {
+	if b, ok := left.(BooleanLiteralExpr); ok {
+		if b.Value == absorbing {
+			return b, true
+		}
+		if boolean(right) {
+			return right, true
+		}
+	}
+	if b, ok := right.(BooleanLiteralExpr); ok {
+		if b.Value == absorbing {
+			return b, true
+		}
+		if boolean(left) {
+			return left, true
+		}
+	}
+	return nil, false
+}
+
+// boolean returns true if the expression evaluates to a types.Boolean, if
+// it evaluates at all
+func boolean(expr Expr) bool {
+	switch e := expr.(type) {
+	case BooleanLiteralExpr:
+		return true
+	case GroupingExpr:
+		return boolean(e.Expr)
+	case UnaryExpr:
+		return e.Operator.Type == Not
+	case BinaryExpr:
+		switch e.Operator.Type {
+		case Less, LessEqual, Greater, GreaterEqual, Equal, NotEqual, And, Or:
+			return true
+		}
+	}
+	return false
+}
+
+func (o *optimizer) VisitCallExpr(e CallExpr, context VisitorContext) (interface{}, error) {
+	name := o.optimize(e.Name)
+	args := make([]Expr, len(e.Args))
+	values := make([]interface{}, len(e.Args))
+	constant := true
+	for i, arg := range e.Args {
+		args[i] = o.optimize(arg)
+		if constant {
+			values[i], constant = literalValue(args[i])
+		}
+	}
+
+	id, ok := name.(IdentifierExpr)
+	if ok && id.Expr != nil && constant {
+		// x.m(args) is m(x, args) for literals
+		var recv interface{}
+		if recv, constant = literalValue(id.Expr); constant {
+			values = append([]interface{}{recv}, values...)
+		}
+	}
+	if ok && constant && o.context != nil {
+		if m, ok := o.context.ResolveMethod(id.Name); ok {
+			if pm, ok := m.(PureMethod); ok && pm.Pure() {
+				if res, err := m.Invoke(values); err == nil {
+					if lit, ok := valueLiteral(res); ok {
+						return lit, nil
+					}
+				}
+			}
+		}
+	}
+
+	return CallExpr{Name: name, Args: args}, nil
+}
+
+// literalValue returns the value of a literal expression
+func literalValue(expr Expr) (interface{}, bool) {
+	switch e := expr.(type) {
+	case StringLiteralExpr:
+		return types.NewString(e.Value), true
+	case IntegerLiteralExpr:
+		return types.NewInteger(e.Value), true
+	case FloatLiteralExpr:
+		return types.NewFloat(e.Value), true
+	case BooleanLiteralExpr:
+		return types.NewBoolean(e.Value), true
+	case NilLiteralExpr:
+		return types.Null(), true
+	default:
+		return nil, false
+	}
+}
+
+// foldTemplate merges the literal parts of the template into its text and
+// returns a string literal if all parts are literals
+func foldTemplate(parts []Expr) Expr {
+	var res []Expr
+	var text strings.Builder
+	for _, part := range parts {
+		if value, ok := literalValue(part); ok {
+			if s, err := types.ToString(value); err == nil {
+				text.WriteString(s)
+				continue
+			}
+		}
+		if text.Len() > 0 {
+			res = append(res, StringLiteralExpr{text.String()})
+			text.Reset()
+		}
+		res = append(res, part)
+	}
+	if len(res) == 0 {
+		return StringLiteralExpr{text.String()}
+	}
+	if text.Len() > 0 {
+		res = append(res, StringLiteralExpr{text.String()})
+	}
+	return TemplateExpr{res}
+}
+
+// valueLiteral returns a literal expression for the given value
+func valueLiteral(value interface{}) (Expr, bool) {
+	switch v := value.(type) {
+	case types.String:
+		return StringLiteralExpr{string(v)}, true
+	case types.Integer:
+		return IntegerLiteralExpr{int64(v)}, true
+	case types.Float:
+		// +Inf, -Inf and NaN have no literal
+		if math.IsInf(float64(v), 0) || math.IsNaN(float64(v)) {
+			return nil, false
+		}
+		return FloatLiteralExpr{float64(v)}, true
+	case types.Boolean:
+		return BooleanLiteralExpr{bool(v)}, true
+	case bool:
+		return BooleanLiteralExpr{v}, true
+	default:
+		if types.IsNull(value) {
+			return NilLiteralExpr{}, true
+		}
+		return nil, false
+	}
+}
@@ -0,0 +1,214 @@
+package goexp
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/svstanev/goexp/types"
+)
+
+func newOptimizeTestContext() EvalContext {
+	ctx := NewEvalContext(nil)
+	ctx.AddName("x", types.Integer(3))
+	ctx.AddName("y", types.Float(1.5))
+	ctx.AddName("b", types.Boolean(true))
+	ctx.AddName("flag", true)
+	ctx.AddName("s", types.String("abc"))
+	ctx.AddPureMethod("inc", func(n types.Integer) types.Integer {
+		return n + 1
+	})
+	ctx.AddMethod("rnd", func(n types.Integer) types.Integer {
+		return n + types.Integer(rand.Intn(1))
+	})
+	return ctx
+}
+
+func TestOptimize(t *testing.T) {
+	tests := []struct {
+		expr     string
+		expected string
+	}{
+		{"1 + 2 * 3", "7"},
+		{"(((x)))", "x"},
+		{"x * (1 + 2)", "x * 3"},
+		{"-(2 ** 3) + x", "-8 + x"},
+		{"'a' + 'b' == 'ab'", "true"},
+		{"x > 1 && true", "x > 1"},
+		{"true && !x", "!x"},
+		{"(x || y) || false", "x || y"},
+		{"x && true", "x && true"},
+		{"x || false", "x || false"},
+		{"3 && true", "3 && true"},
+		{"false && x", "false"},
+		{"x && false", "false"},
+		{"true || x", "true"},
+		{"!(!(x == y))", "x == y"},
+		{"!(!x)", "!(!x)"},
+		{"!(1 < 2) || b", "false || b"},
+		{"!(1 < 2) || b == 1", "b == 1"},
+		{"count(xs, x => x > 60 * 60 && true)", "count(xs, x => x > 3600)"},
+		{"let a = 60 * 60; a > x || false", "let a = 3600; a > x"},
+		{"`${60 * 60}s` + x", "\"3600s\" + x"},
+		{"`${60 * 60}s ${x}`", "`3600s ${x}`"},
+		{"inc(inc(1)) + x", "3 + x"},
+		{"inc(x)", "inc(x)"},
+		{"rnd(1)", "rnd(1)"},
+		{"inc('a')", "inc(\"a\")"},
+		{"1 / 0", "1 / 0"},
+		{"a.b.c(1 + 1)", "a.b.c(2)"},
+		{"(1 + 1).inc() * x", "3 * x"},
+		{"x.inc()", "x.inc()"},
+	}
+
+	ctx := newOptimizeTestContext()
+	for _, test := range tests {
+		t.Run(test.expr, func(t *testing.T) {
+			expr, err := Parse(test.expr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			s, err := Print(OptimizeWith(expr, ctx))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if s != test.expected {
+				t.Errorf("Expected %s but got %s", test.expected, s)
+			}
+		})
+	}
+}
+
+// TestOptimizeDifferential checks that optimized random expressions evaluate
+// to the same values, or fail with the same errors, as the original ones
+func TestOptimizeDifferential(t *testing.T) {
+	ctx := newOptimizeTestContext()
+	r := rand.New(rand.NewSource(1))
+
+	evaluated := 0
+	for i := 0; i < 5000; i++ {
+		expr := randomExpr(r, 4)
+		optimized := OptimizeWith(expr, ctx)
+		if _, err := Print(optimized); err != nil {
+			t.Fatalf("%s: cannot print the optimized expression: %v", printExpr(expr), err)
+		}
+
+		expected, err := Eval(expr, ctx)
+		if err != nil {
+			if absorbs(expr, ctx) {
+				// "false && x" and "true || x" drop the errors of x
+				continue
+			}
+			if _, actual := Eval(optimized, ctx); actual == nil || actual.Error() != err.Error() {
+				t.Fatalf("%s: expected error %v but got %v (optimized: %s)", printExpr(expr), err, actual, printExpr(optimized))
+			}
+			continue
+		}
+		evaluated++
+
+		actual, err := Eval(optimized, ctx)
+		if err != nil {
+			t.Fatalf("%s: unexpected error %v", printExpr(expr), err)
+		}
+		if !sameValue(expected, actual) {
+			t.Fatalf("%s: expected %#v but got %#v (optimized: %s)", printExpr(expr), expected, actual, printExpr(optimized))
+		}
+	}
+	if evaluated < 500 {
+		t.Errorf("Too few expressions evaluated successfully: %d", evaluated)
+	}
+}
+
+var randomBinaryOps = []TokenType{Add, Sub, Mul, Div, Modulo, Power, Less, LessEqual, Greater, GreaterEqual, Equal, NotEqual, And, Or}
+
+func randomExpr(r *rand.Rand, depth int) Expr {
+	if depth == 0 || r.Intn(4) == 0 {
+		switch r.Intn(10) {
+		case 0:
+			return IntegerLiteralExpr{int64(r.Intn(10))}
+		case 1:
+			return FloatLiteralExpr{float64(r.Intn(100)) / 8}
+		case 2:
+			return BooleanLiteralExpr{r.Intn(2) == 0}
+		case 3:
+			return StringLiteralExpr{fmt.Sprintf("s%d", r.Intn(3))}
+		case 4:
+			return NilLiteralExpr{}
+		case 5:
+			// 1.0 / 0 or 0.0 / 0, which evaluate to +Inf and NaN
+			return BinaryExpr{
+				Left:     FloatLiteralExpr{float64(r.Intn(2))},
+				Right:    IntegerLiteralExpr{0},
+				Operator: Token{Type: Div, Lexeme: "/"},
+			}
+		default:
+			return IdentifierExpr{Name: []string{"x", "y", "b", "flag", "s"}[r.Intn(5)]}
+		}
+	}
+
+	switch r.Intn(6) {
+	case 0:
+		return GroupingExpr{randomExpr(r, depth-1)}
+	case 1:
+		op := Token{Type: Sub, Lexeme: "-"}
+		if r.Intn(2) == 0 {
+			op = Token{Type: Not, Lexeme: "!"}
+		}
+		return UnaryExpr{Value: randomExpr(r, depth-1), Operator: op}
+	case 2:
+		return CallExpr{Name: IdentifierExpr{Name: "inc"}, Args: []Expr{randomExpr(r, depth-1)}}
+	default:
+		t := randomBinaryOps[r.Intn(len(randomBinaryOps))]
+		return BinaryExpr{
+			Left:     randomExpr(r, depth-1),
+			Right:    randomExpr(r, depth-1),
+			Operator: Token{Type: t, Lexeme: ops[t]},
+		}
+	}
+}
+
+// absorbs returns true if an operand of "&&" or "||" in the expression is
+// optimized to its absorbing literal, which drops the other operand
+func absorbs(expr Expr, ctx Context) bool {
+	switch e := expr.(type) {
+	case GroupingExpr:
+		return absorbs(e.Expr, ctx)
+	case UnaryExpr:
+		return absorbs(e.Value, ctx)
+	case CallExpr:
+		for _, arg := range e.Args {
+			if absorbs(arg, ctx) {
+				return true
+			}
+		}
+	case BinaryExpr:
+		if t := e.Operator.Type; t == And || t == Or {
+			for _, operand := range []Expr{e.Left, e.Right} {
+				if b, ok := OptimizeWith(operand, ctx).(BooleanLiteralExpr); ok && b.Value == (t == Or) {
+					return true
+				}
+			}
+		}
+		return absorbs(e.Left, ctx) || absorbs(e.Right, ctx)
+	}
+	return false
+}
+
+func sameValue(x, y interface{}) bool {
+	if fx, ok := x.(types.Float); ok {
+		if fy, ok := y.(types.Float); ok && math.IsNaN(float64(fx)) && math.IsNaN(float64(fy)) {
+			return true
+		}
+	}
+	return reflect.DeepEqual(x, y)
+}
+
+func printExpr(expr Expr) string {
+	s, err := Print(expr)
+	if err != nil {
+		return err.Error()
+	}
+	return s
+}
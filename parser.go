@@ -1,6 +1,9 @@
 package goexp
 
-import "fmt"
+import (
+	"fmt"
+	"math"
+)
 
 type parseError struct {
 	token   Token
@@ -24,7 +27,14 @@ func newParser(tokens []Token) *parser {
 
 func (p *parser) parse() (Expr, error) {
 	p.current = 0
-	return p.expression()
+	expr, err := p.expression()
+	if err != nil {
+		return nil, err
+	}
+	if !p.isAtEnd() {
+		return nil, parseError{p.peek(), fmt.Sprintf("Unexpected token %q", p.peek().Lexeme)}
+	}
+	return expr, nil
 }
 
 func (p *parser) peek() Token {
@@ -73,6 +83,37 @@ func (p *parser) consume(tokenType TokenType, msg string) (token Token, err erro
 }
 
 func (p *parser) expression() (Expr, error) {
+	// expression = "let" IDENTIFIER "=" expression ";" expression | IDENTIFIER "=>" expression | logicalOr
+	if p.match(Let) {
+		name, err := p.consume(Identifier, "Expect name after 'let'.")
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.consume(Assign, "Expect '=' after name."); err != nil {
+			return nil, err
+		}
+		value, err := p.expression()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.consume(Semicolon, "Expect ';' after value."); err != nil {
+			return nil, err
+		}
+		body, err := p.expression()
+		if err != nil {
+			return nil, err
+		}
+		return LetExpr{Name: name.Lexeme, Value: value, Body: body}, nil
+	}
+	if p.check(Identifier) && p.tokens[p.current+1].Type == Arrow {
+		param := p.advance()
+		p.advance()
+		body, err := p.expression()
+		if err != nil {
+			return nil, err
+		}
+		return LambdaExpr{Param: param.Lexeme, Body: body}, nil
+	}
 	return p.logicalOr()
 }
 
@@ -257,6 +298,9 @@ func (p *parser) negate() (Expr, error) {
 	var op Token
 	if p.match(Sub) {
 		op = p.previous()
+		if lit, ok := p.negativeLiteral(); ok {
+			return lit, nil
+		}
 	}
 	expr, err := p.call()
 	if err != nil {
@@ -271,6 +315,29 @@ func (p *parser) negate() (Expr, error) {
 	return expr, nil
 }
 
+// negativeLiteral parses a number that is not followed by a call or a member
+// access, e.g. -5 but not -5.abs(), after a minus as a negative literal
+func (p *parser) negativeLiteral() (Expr, bool) {
+	if !p.check(Integer) && !p.check(Float) {
+		return nil, false
+	}
+	if next := p.tokens[p.current+1].Type; next == LeftParen || next == Period {
+		return nil, false
+	}
+	switch value := p.peek().Literal; {
+	case p.check(Integer):
+		p.advance()
+		if value == minInt64Magnitude {
+			return IntegerLiteralExpr{math.MinInt64}, true
+		}
+		return IntegerLiteralExpr{-value.(int64)}, true
+	case p.check(Float):
+		p.advance()
+		return FloatLiteralExpr{-value.(float64)}, true
+	}
+	return nil, false
+}
+
 func (p *parser) call() (Expr, error) {
 	expr, err := p.primary()
 	if err != nil {
@@ -298,15 +365,36 @@ func (p *parser) call() (Expr, error) {
 }
 
 func (p *parser) finishCall(callee Expr) (Expr, error) {
+	// arguments = argument ("," argument)*
+	// argument = (IDENTIFIER ":")? expression
 	args := make([]Expr, 0)
+	named := make(map[string]bool)
 	if !p.check(RightParen) {
 		done := false
 		for !done {
-			expr, err := p.expression()
-			if err != nil {
-				return nil, err
+			start := p.peek()
+			if p.check(Identifier) && p.tokens[p.current+1].Type == Colon {
+				name := p.advance()
+				p.advance()
+				if named[name.Lexeme] {
+					return nil, parseError{name, fmt.Sprintf("Duplicate argument %s.", name.Lexeme)}
+				}
+				named[name.Lexeme] = true
+				value, err := p.expression()
+				if err != nil {
+					return nil, err
+				}
+				args = append(args, NamedArgExpr{Name: name.Lexeme, Value: value})
+			} else {
+				expr, err := p.expression()
+				if err != nil {
+					return nil, err
+				}
+				if len(named) > 0 {
+					return nil, parseError{start, "Expect named argument after named arguments."}
+				}
+				args = append(args, expr)
 			}
-			args = append(args, expr)
 			done = !p.match(Comma)
 		}
 	}
@@ -330,7 +418,10 @@ func (p *parser) primary() (Expr, error) {
 		return NilLiteralExpr{}, nil
 	}
 	if p.match(Integer) {
-		value := p.previous().Literal.(int64)
+		value, ok := p.previous().Literal.(int64)
+		if !ok {
+			return nil, parseError{p.previous(), fmt.Sprintf("Integer %s out of range", p.previous().Lexeme)}
+		}
 		return IntegerLiteralExpr{value}, nil
 	}
 	if p.match(Float) {
@@ -344,6 +435,9 @@ func (p *parser) primary() (Expr, error) {
 	if p.match(Identifier) {
 		return IdentifierExpr{Name: p.previous().Lexeme}, nil
 	}
+	if p.match(Template) {
+		return p.template(p.previous())
+	}
 	if p.match(LeftParen) {
 		expr, err := p.expression()
 		if err != nil {
@@ -355,5 +449,36 @@ func (p *parser) primary() (Expr, error) {
 		return GroupingExpr{expr}, nil
 	}
 
-	return nil, parseError{p.previous(), "Unknown token"}
+	if p.isAtEnd() {
+		return nil, parseError{p.peek(), "Unexpected end of expression"}
+	}
+	return nil, parseError{p.peek(), fmt.Sprintf("Unexpected token %q", p.peek().Lexeme)}
+}
+
+// template parses the embedded expressions of a template string; their
+// tokens are positioned in the source of the template
+func (p *parser) template(tok Token) (Expr, error) {
+	parts, _ := tok.Literal.([]TemplatePart)
+	exprs := make([]Expr, len(parts))
+	for i, part := range parts {
+		if !part.Expr {
+			exprs[i] = StringLiteralExpr{part.Text}
+			continue
+		}
+		offset := tok.Pos + part.Pos
+		tokens, err := newScanner(part.Text).scan()
+		if err != nil {
+			if se, ok := err.(*scannerError); ok {
+				se.Pos += offset
+			}
+			return nil, err
+		}
+		for j := range tokens {
+			tokens[j].Pos += offset
+		}
+		if exprs[i], err = newParser(tokens).parse(); err != nil {
+			return nil, err
+		}
+	}
+	return TemplateExpr{exprs}, nil
 }
@@ -1,6 +1,8 @@
 package goexp
 
 import (
+	"fmt"
+	"math"
 	"testing"
 
 	"github.com/go-test/deep"
@@ -116,13 +118,125 @@ func TestParse(t *testing.T) {
 
 		{
 			"-1 + 2",
+			BinaryExpr{
+				Left:     IntegerLiteralExpr{int64(-1)},
+				Right:    IntegerLiteralExpr{int64(2)},
+				Operator: Token{Add, "+", nil, 3},
+			},
+			nil,
+		},
+
+		{
+			"-(1) ** -2.5",
 			BinaryExpr{
 				Left: UnaryExpr{
-					Value:    IntegerLiteralExpr{int64(1)},
+					Value:    GroupingExpr{IntegerLiteralExpr{int64(1)}},
 					Operator: Token{Sub, "-", nil, 0},
 				},
-				Right:    IntegerLiteralExpr{int64(2)},
-				Operator: Token{Add, "+", nil, 3},
+				Right:    FloatLiteralExpr{-2.5},
+				Operator: Token{Power, "**", nil, 5},
+			},
+			nil,
+		},
+
+		{
+			"-9223372036854775808 - -x",
+			BinaryExpr{
+				Left: IntegerLiteralExpr{math.MinInt64},
+				Right: UnaryExpr{
+					Value:    IdentifierExpr{"x", nil},
+					Operator: Token{Sub, "-", nil, 23},
+				},
+				Operator: Token{Sub, "-", nil, 21},
+			},
+			nil,
+		},
+
+		{
+			"-2(3)",
+			UnaryExpr{
+				Value: CallExpr{
+					Name: IntegerLiteralExpr{int64(2)},
+					Args: []Expr{IntegerLiteralExpr{int64(3)}},
+				},
+				Operator: Token{Sub, "-", nil, 0},
+			},
+			nil,
+		},
+
+		{
+			"count(xs, x => x > 1)",
+			CallExpr{
+				Name: IdentifierExpr{"count", nil},
+				Args: []Expr{
+					IdentifierExpr{"xs", nil},
+					LambdaExpr{
+						Param: "x",
+						Body: BinaryExpr{
+							Left:     IdentifierExpr{"x", nil},
+							Right:    IntegerLiteralExpr{int64(1)},
+							Operator: Token{Greater, ">", nil, 17},
+						},
+					},
+				},
+			},
+			nil,
+		},
+
+		{
+			"a => b => a",
+			LambdaExpr{"a", LambdaExpr{"b", IdentifierExpr{"a", nil}}},
+			nil,
+		},
+
+		{
+			"`a${x + 1}` + `${`${y}`}`",
+			BinaryExpr{
+				Left: TemplateExpr{[]Expr{
+					StringLiteralExpr{"a"},
+					BinaryExpr{
+						Left:     IdentifierExpr{"x", nil},
+						Right:    IntegerLiteralExpr{int64(1)},
+						Operator: Token{Add, "+", nil, 6},
+					},
+				}},
+				Right:    TemplateExpr{[]Expr{TemplateExpr{[]Expr{IdentifierExpr{"y", nil}}}}},
+				Operator: Token{Add, "+", nil, 12},
+			},
+			nil,
+		},
+
+		{
+			"let a = x + 1; let b = a => a; a > b",
+			LetExpr{
+				Name: "a",
+				Value: BinaryExpr{
+					Left:     IdentifierExpr{"x", nil},
+					Right:    IntegerLiteralExpr{int64(1)},
+					Operator: Token{Add, "+", nil, 10},
+				},
+				Body: LetExpr{
+					Name:  "b",
+					Value: LambdaExpr{"a", IdentifierExpr{"a", nil}},
+					Body: BinaryExpr{
+						Left:     IdentifierExpr{"a", nil},
+						Right:    IdentifierExpr{"b", nil},
+						Operator: Token{Greater, ">", nil, 33},
+					},
+				},
+			},
+			nil,
+		},
+
+		{
+			"round(x, digits: 2, mode: m => m)",
+			CallExpr{
+				Name: IdentifierExpr{"round", nil},
+				Args: []Expr{
+					IdentifierExpr{"x", nil},
+					NamedArgExpr{"digits", IntegerLiteralExpr{int64(2)}},
+					NamedArgExpr{"mode", LambdaExpr{"m", IdentifierExpr{"m", nil}}},
+				},
 			},
 			nil,
 		},
@@ -153,3 +267,47 @@ func testParse(t *testing.T, str string, expectedExpr Expr, expectedErr error) {
 		t.Error(diff)
 	}
 }
+
+func TestErrorPosition(t *testing.T) {
+	tests := []struct {
+		expr string
+		pos  int
+	}{
+		{"", 0},
+		{"1 + ", 4},
+		{"a + 'b", 6},
+		{"f(1,, 2)", 4},
+		{"1 2", 2},
+		{")", 0},
+		{"ä", 1},
+		{"1 + x => x", 6},
+		{"f(x =>)", 6},
+		{"f(a: 1, 2)", 8},
+		{"f(a: 1, a: 2)", 8},
+		{"f(a:)", 4},
+		{"a: 1", 1},
+		{"let = 1; 2", 4},
+		{"let a 1; a", 6},
+		{"let a = 1 a", 10},
+		{"let a = 1;", 10},
+		{"1 + let a = 1; a", 4},
+		{"`${1 +}`", 6},
+		{"`${}`", 3},
+		{"1 + `${x $}`", 10},
+		{"`${`${a b}`}`", 8},
+		{"1 + 9223372036854775808", 4},
+	}
+	for _, test := range tests {
+		_, err := Parse(test.expr)
+		if err == nil {
+			t.Fatalf("%s: expected an error", test.expr)
+		}
+		pos, ok := ErrorPosition(err)
+		if !ok || pos != test.pos {
+			t.Errorf("%s: expected position %d but got %d, %v (%v)", test.expr, test.pos, pos, ok, err)
+		}
+	}
+	if _, ok := ErrorPosition(fmt.Errorf("x")); ok {
+		t.Errorf("Expected no position")
+	}
+}
@@ -0,0 +1,277 @@
+package goexp
+
+import "strings"
+
+/*
+PartialEval evaluates the parts of the expression that depend only on names
+known to the context and returns the residual expression for the rest.
+
+For example, with role = 'viewer' in the context, "role == 'admin' || owner == userId"
+is reduced to "owner == userId". If the whole expression can be evaluated, the
+result is a literal expression. Known names whose values cannot be represented
+as literals are kept in the residual expression, and so are the calls to
+methods that are not pure (see PureMethod).
+*/
+func PartialEval(expr Expr, context Context) (Expr, error) {
+	p := &partialEvaluator{context}
+	return p.eval(expr)
+}
+
+type partialEvaluator struct {
+	context Context
+}
+
+func (p *partialEvaluator) eval(expr Expr) (Expr, error) {
+	res, err := expr.Accept(p, nil)
+	if err != nil {
+		return nil, err
+	}
+	return res.(Expr), nil
+}
+
+// known returns true if the root name of the identifier path can be resolved
+func (p *partialEvaluator) known(path string) bool {
+	if p.context == nil {
+		return false
+	}
+	_, ok := p.context.ResolveName(strings.SplitN(path, ".", 2)[0])
+	return ok
+}
+
+// evalLiteral evaluates the expression and returns it as a literal if possible
+func (p *partialEvaluator) evalLiteral(expr Expr) (Expr, error) {
+	value, err := Eval(expr, p.context)
+	if err != nil {
+		return nil, err
+	}
+	if lit, ok := valueLiteral(value); ok {
+		return lit, nil
+	}
+	return expr, nil
+}
+
+func (p *partialEvaluator) VisitStringLiteralExpr(e StringLiteralExpr, context VisitorContext) (interface{}, error) {
+	return e, nil
+}
+
+func (p *partialEvaluator) VisitIntegerLiteralExpr(e IntegerLiteralExpr, context VisitorContext) (interface{}, error) {
+	return e, nil
+}
+
+func (p *partialEvaluator) VisitFloatLiteralExpr(e FloatLiteralExpr, context VisitorContext) (interface{}, error) {
+	return e, nil
+}
+
+func (p *partialEvaluator) VisitBooleanLiteralExpr(e BooleanLiteralExpr, context VisitorContext) (interface{}, error) {
+	return e, nil
+}
+
+func (p *partialEvaluator) VisitNilLiteralExpr(e NilLiteralExpr, context VisitorContext) (interface{}, error) {
+	return e, nil
+}
+
+func (p *partialEvaluator) VisitGroupingExpr(e GroupingExpr, context VisitorContext) (interface{}, error) {
+	inner, err := p.eval(e.Expr)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := literalValue(inner); ok {
+		return inner, nil
+	}
+	return GroupingExpr{inner}, nil
+}
+
+// VisitLambdaExpr evaluates the body of the lambda with its parameter unknown
+func (p *partialEvaluator) VisitLambdaExpr(e LambdaExpr, context VisitorContext) (interface{}, error) {
+	inner := &partialEvaluator{}
+	if p.context != nil {
+		inner.context = shadowContext{p.context, e.Param}
+	}
+	body, err := inner.eval(e.Body)
+	if err != nil {
+		return nil, err
+	}
+	return LambdaExpr{Param: e.Param, Body: body}, nil
+}
+
+// VisitLetExpr substitutes the value in the body if it is a literal; otherwise
+// the body is evaluated with the name unknown
+func (p *partialEvaluator) VisitLetExpr(e LetExpr, context VisitorContext) (interface{}, error) {
+	value, err := p.eval(e.Value)
+	if err != nil {
+		return nil, err
+	}
+	if v, ok := literalValue(value); ok {
+		scope := NewEvalContext(p.context)
+		if err := scope.AddName(e.Name, v); err != nil {
+			return nil, err
+		}
+		return (&partialEvaluator{scope}).eval(e.Body)
+	}
+
+	inner := &partialEvaluator{}
+	if p.context != nil {
+		inner.context = shadowContext{p.context, e.Name}
+	}
+	body, err := inner.eval(e.Body)
+	if err != nil {
+		return nil, err
+	}
+	return LetExpr{Name: e.Name, Value: value, Body: body}, nil
+}
+
+func (p *partialEvaluator) VisitTemplateExpr(e TemplateExpr, context VisitorContext) (interface{}, error) {
+	parts := make([]Expr, len(e.Parts))
+	for i, part := range e.Parts {
+		var err error
+		if parts[i], err = p.eval(part); err != nil {
+			return nil, err
+		}
+	}
+	return foldTemplate(parts), nil
+}
+
+func (p *partialEvaluator) VisitNamedArgExpr(e NamedArgExpr, context VisitorContext) (interface{}, error) {
+	value, err := p.eval(e.Value)
+	if err != nil {
+		return nil, err
+	}
+	return NamedArgExpr{Name: e.Name, Value: value}, nil
+}
+
+func (p *partialEvaluator) VisitIdentifierExpr(e IdentifierExpr, context VisitorContext) (interface{}, error) {
+	if path, ok := identifierPath(e); ok {
+		if p.known(path) {
+			return p.evalLiteral(e)
+		}
+		return e, nil
+	}
+
+	inner, err := p.eval(e.Expr)
+	if err != nil {
+		return nil, err
+	}
+	e.Expr = inner
+	if _, ok := literalValue(inner); ok {
+		return p.evalLiteral(e)
+	}
+	return e, nil
+}
+
+func (p *partialEvaluator) VisitUnaryExpr(e UnaryExpr, context VisitorContext) (interface{}, error) {
+	value, err := p.eval(e.Value)
+	if err != nil {
+		return nil, err
+	}
+
+	if x, ok := literalValue(value); ok {
+		res, err := unaryOp(x, e.Operator)
+		if err != nil {
+			return nil, err
+		}
+		if lit, ok := valueLiteral(res); ok {
+			return lit, nil
+		}
+	}
+
+	return UnaryExpr{Value: value, Operator: e.Operator}, nil
+}
+
+func (p *partialEvaluator) VisitBinaryExpr(e BinaryExpr, context VisitorContext) (interface{}, error) {
+	left, err := p.eval(e.Left)
+	if err != nil {
+		return nil, err
+	}
+	right, err := p.eval(e.Right)
+	if err != nil {
+		return nil, err
+	}
+
+	switch e.Operator.Type {
+	case And:
+		if res, ok := simplifyLogical(left, right, false); ok {
+			return res, nil
+		}
+	case Or:
+		if res, ok := simplifyLogical(left, right, true); ok {
+			return res, nil
+		}
+	}
+
+	if x, ok := literalValue(left); ok {
+		if y, ok := literalValue(right); ok {
+			res, err := binaryOp(x, y, e.Operator)
+			if err != nil {
+				return nil, err
+			}
+			if lit, ok := valueLiteral(res); ok {
+				return lit, nil
+			}
+		}
+	}
+
+	return BinaryExpr{Left: left, Right: right, Operator: e.Operator}, nil
+}
+
+func (p *partialEvaluator) VisitCallExpr(e CallExpr, context VisitorContext) (interface{}, error) {
+	args := make([]Expr, len(e.Args))
+	constant := true
+	for i, arg := range e.Args {
+		var err error
+		if args[i], err = p.eval(arg); err != nil {
+			return nil, err
+		}
+		if _, ok := literalValue(args[i]); !ok {
+			constant = false
+		}
+	}
+
+	call := CallExpr{Name: e.Name, Args: args}
+	if constant && p.pure(e.Name) {
+		return p.evalLiteral(call)
+	}
+	return call, nil
+}
+
+// pure returns true if the method can be resolved without the unknown names
+// and is pure, as Optimize requires for folding calls
+func (p *partialEvaluator) pure(name Expr) bool {
+	id, ok := name.(IdentifierExpr)
+	if !ok || p.context == nil {
+		return false
+	}
+	ctx := p.context
+	if id.Expr != nil {
+		path, ok := identifierPath(id.Expr)
+		if !ok || !p.known(path) {
+			return false
+		}
+		recv, err := Eval(id.Expr, p.context)
+		if err != nil {
+			return false
+		}
+		// x.m(args) is m(x, args) for values that are not contexts
+		if c, ok := recv.(Context); ok {
+			ctx = c
+		}
+	}
+	m, ok := ctx.ResolveMethod(id.Name)
+	if !ok {
+		return false
+	}
+	pm, ok := m.(PureMethod)
+	return ok && pm.Pure()
+}
+
+// shadowContext hides a name of the context, e.g. the parameter of a lambda
+type shadowContext struct {
+	Context
+	name string
+}
+
+func (c shadowContext) ResolveName(name string) (Var, bool) {
+	if name == c.name {
+		return nil, false
+	}
+	return c.Context.ResolveName(name)
+}
@@ -0,0 +1,106 @@
+package goexp
+
+import (
+	"testing"
+
+	"github.com/svstanev/goexp/types"
+)
+
+func TestPartialEval(t *testing.T) {
+	user := NewEvalContext(nil)
+	user.AddName("id", types.Integer(7))
+
+	ctx := NewEvalContext(nil)
+	ctx.AddName("role", types.String("viewer"))
+	ctx.AddName("tenant", types.Integer(42))
+	ctx.AddName("user", user)
+	ctx.AddPureMethod("double", func(n types.Integer) types.Integer { return n * 2 })
+	ctx.Define("triple").Param("n", types.IntegerType).Returns(types.IntegerType).Pure().Impl(func(n types.Integer) types.Integer { return n * 3 })
+
+	// impure methods are never called, even with literal arguments
+	ctx.AddMethod("next", func() types.Integer { panic("next called") })
+	ctx.Define("random").Param("n", types.IntegerType).Returns(types.IntegerType).Impl(func(n types.Integer) types.Integer { panic("random called") })
+
+	tests := []struct {
+		expr     string
+		expected string
+	}{
+		{"role == 'admin' || owner == userId", "owner == userId"},
+		{"role == 'viewer' || owner == userId", "true"},
+		{"role == 'viewer' && owner == userId", "owner == userId"},
+		{"tenant == 42 && (a || b) && c", "(a || b) && c"},
+		{"tenant == 42 && c", "true && c"},
+		{"role == 'viewer' || c", "true"},
+		{"tenant + 1", "43"},
+		{"user.id == owner", "7 == owner"},
+		{"double(tenant) > limit", "84 > limit"},
+		{"double(limit) > tenant", "double(limit) > 42"},
+		{"other.id == user.id", "other.id == 7"},
+		{"unknown(1)", "unknown(1)"},
+		{"triple(tenant) + user.id.double()", "140"},
+		{"next() > x", "next() > x"},
+		{"random(tenant) > x", "random(42) > x"},
+		{"tenant.random() > x", "tenant.random() > x"},
+		{"tenant / 0.0 > x", "42 / 0.0 > x"},
+		{"-tenant < x", "-42 < x"},
+		{"count(xs, x => x.tenant == tenant)", "count(xs, x => x.tenant == 42)"},
+		{"count(xs, role => role == 'admin' && tenant > 0)", "count(xs, role => role == \"admin\")"},
+		{"f(x, limit: tenant + 1)", "f(x, limit: 43)"},
+		{"`${tenant}-${x}` == `${tenant}`", "`42-${x}` == \"42\""},
+		{"let t = tenant * 2; t > x && t > 0", "84 > x"},
+		{"let t = x * 2; t > tenant", "let t = x * 2; t > 42"},
+		{"let tenant = x; tenant > double(tenant) + double(2)", "let tenant = x; tenant > double(tenant) + 4"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.expr, func(t *testing.T) {
+			expr, err := Parse(test.expr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			residual, err := PartialEval(expr, ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			s, err := Print(residual)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if s != test.expected {
+				t.Errorf("Expected %s but got %s", test.expected, s)
+			}
+		})
+	}
+
+	expr, _ := Parse("tenant / 0 > x")
+	if _, err := PartialEval(expr, ctx); err == nil {
+		t.Error("Expected the error of the known subexpression to be reported")
+	}
+}
+
+func TestPartialEvalResidual(t *testing.T) {
+	known := NewEvalContext(nil)
+	known.AddName("role", types.String("viewer"))
+
+	all := NewEvalContext(known)
+	all.AddName("owner", types.Integer(1))
+	all.AddName("userId", types.Integer(1))
+
+	expr, _ := Parse("role == 'admin' || owner == userId")
+	residual, err := PartialEval(expr, known)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected, err := Eval(expr, all)
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual, err := Eval(residual, all)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual != expected {
+		t.Errorf("Expected %v but got %v", expected, actual)
+	}
+}
@@ -3,6 +3,8 @@ package goexp
 import (
 	"errors"
 	"fmt"
+	"math"
+	"strconv"
 	"strings"
 )
 
@@ -24,19 +26,52 @@ var ops = map[TokenType]string{
 	Or:           "||",
 }
 
-func Print(node Expr) (string, error) {
-	p := newPrinter()
+// Operator precedence levels, from the loosest to the tightest binding, as defined by the grammar
+const (
+	precLowest = iota
+	precOr
+	precAnd
+	precNot
+	precEquality
+	precComparison
+	precAddition
+	precMultiplication
+	precPower
+	precNegate
+	precCall
+)
 
-	var res interface{}
-	var err error
-	var s string
+var binaryPrecedence = map[TokenType]int{
+	Or:           precOr,
+	And:          precAnd,
+	Equal:        precEquality,
+	NotEqual:     precEquality,
+	Less:         precComparison,
+	LessEqual:    precComparison,
+	Greater:      precComparison,
+	GreaterEqual: precComparison,
+	Add:          precAddition,
+	Sub:          precAddition,
+	Mul:          precMultiplication,
+	Div:          precMultiplication,
+	Modulo:       precMultiplication,
+	Power:        precPower,
+}
 
-	context := newPrinterContext()
-	if res, err = node.Accept(p, context); err == nil {
-		s = res.(string)
-	}
+/*
+Print returns the canonical source of the expression.
 
-	return s, err
+The output can be parsed back to an equivalent expression: keywords are
+lower case, floats keep their precision, strings are escaped and parentheses
+are inserted only where the precedence and associativity of the operators
+require them (GroupingExpr nodes are not needed to print the expression correctly).
+Negative numbers are printed as negative literals, e.g. -5, and the negation
+of a number literal as -(5), since the parser reads -5 as a literal.
+*/
+func Print(node Expr) (string, error) {
+	p := newPrinter()
+	context := newPrinterContext()
+	return p.printExpr(node, context)
 }
 
 type printer struct {
@@ -55,57 +90,117 @@ func newPrinterContext() *printerContext {
 	return &printerContext{}
 }
 
-func (p *printer) VisitGroupingExpr(e GroupingExpr, context VisitorContext) (interface{}, error) {
-	s, err := p.printExpr(e.Expr, context)
-	if err != nil {
-		return "", err
+// precedence returns the precedence level of the expression
+func precedence(expr Expr) int {
+	switch e := expr.(type) {
+	case GroupingExpr:
+		return precedence(e.Expr)
+	case LambdaExpr, LetExpr:
+		// the body of a lambda or a let extends as far to the right as possible
+		return precLowest
+	case BinaryExpr:
+		return binaryPrecedence[e.Operator.Type]
+	case UnaryExpr:
+		if e.Operator.Type == Not {
+			return precNot
+		}
+		return precNegate
+	case IntegerLiteralExpr:
+		if e.Value < 0 {
+			return precNegate
+		}
+	case FloatLiteralExpr:
+		if e.Value < 0 || e.Value == 0 && math.Signbit(e.Value) {
+			return precNegate
+		}
 	}
-	return "(" + s + ")", nil
+	return precCall
+}
+
+func (p *printer) VisitGroupingExpr(e GroupingExpr, context VisitorContext) (interface{}, error) {
+	return p.printExpr(e.Expr, context)
 }
 
 func (p *printer) VisitStringLiteralExpr(e StringLiteralExpr, c VisitorContext) (interface{}, error) {
-	return fmt.Sprintf("\"%s\"", e.Value), nil
+	return quote(e.Value), nil
 }
 
 func (p *printer) VisitIntegerLiteralExpr(e IntegerLiteralExpr, context VisitorContext) (interface{}, error) {
-	return fmt.Sprintf("%d", e.Value), nil
+	return strconv.FormatInt(e.Value, 10), nil
 }
 
 func (p *printer) VisitFloatLiteralExpr(e FloatLiteralExpr, context VisitorContext) (interface{}, error) {
-	return fmt.Sprintf("%f", e.Value), nil
+	if math.IsInf(e.Value, 0) || math.IsNaN(e.Value) {
+		return nil, fmt.Errorf("Cannot print %v", e.Value)
+	}
+	s := strconv.FormatFloat(e.Value, 'g', -1, 64)
+	if !strings.ContainsAny(s, ".e") {
+		s += ".0"
+	}
+	return s, nil
 }
 
 func (p *printer) VisitBooleanLiteralExpr(e BooleanLiteralExpr, context VisitorContext) (interface{}, error) {
 	var res string
 
 	if e.Value {
-		res = "True"
+		res = "true"
 	} else {
-		res = "False"
+		res = "false"
 	}
 
 	return res, nil
 }
 
 func (p *printer) VisitNilLiteralExpr(e NilLiteralExpr, context VisitorContext) (interface{}, error) {
-	return "Nil", nil
+	return "nil", nil
 }
 
 func (p *printer) VisitUnaryExpr(e UnaryExpr, context VisitorContext) (interface{}, error) {
-	right, err := p.printExpr(e.Value, context)
+	op, ok := ops[e.Operator.Type]
+	if !ok || e.Operator.Type != Not && e.Operator.Type != Sub {
+		return nil, fmt.Errorf("Unknown unary operator %q", e.Operator.Lexeme)
+	}
+
+	// "!" applies to an equality, "-" applies to a call
+	operandPrec := precCall
+	if e.Operator.Type == Not {
+		operandPrec = precEquality
+	}
+
+	right, err := p.printOperand(e.Value, operandPrec, context)
 	if err != nil {
 		return nil, err
 	}
-	return fmt.Sprintf("%s%s", ops[e.Operator.Type], right), nil
+	if e.Operator.Type == Sub && numberLiteral(e.Value) && precedence(e.Value) == precCall {
+		// "-5" is parsed as a negative literal
+		right = "(" + right + ")"
+	}
+	return op + right, nil
+}
+
+// numberLiteral returns true if the expression is an integer or a float literal
+func numberLiteral(expr Expr) bool {
+	switch ungroup(expr).(type) {
+	case IntegerLiteralExpr, FloatLiteralExpr:
+		return true
+	}
+	return false
 }
 
 func (p *printer) VisitBinaryExpr(e BinaryExpr, context VisitorContext) (interface{}, error) {
-	left, err := p.printExpr(e.Left, context)
+	prec, ok := binaryPrecedence[e.Operator.Type]
+	if !ok {
+		return nil, fmt.Errorf("Unknown binary operator %q", e.Operator.Lexeme)
+	}
+
+	// all binary operators are left associative
+	left, err := p.printOperand(e.Left, prec, context)
 	if err != nil {
 		return nil, err
 	}
 
-	right, err := p.printExpr(e.Right, context)
+	right, err := p.printOperand(e.Right, prec+1, context)
 	if err != nil {
 		return nil, err
 	}
@@ -114,7 +209,7 @@ func (p *printer) VisitBinaryExpr(e BinaryExpr, context VisitorContext) (interfa
 }
 
 func (p *printer) VisitCallExpr(e CallExpr, context VisitorContext) (interface{}, error) {
-	name, err := p.printExpr(e.Name, context)
+	name, err := p.printOperand(e.Name, precCall, context)
 	if err != nil {
 		return nil, err
 	}
@@ -127,12 +222,20 @@ func (p *printer) VisitCallExpr(e CallExpr, context VisitorContext) (interface{}
 }
 
 func (p *printer) VisitIdentifierExpr(e IdentifierExpr, context VisitorContext) (interface{}, error) {
+	if !isIdentifier(e.Name) {
+		return nil, fmt.Errorf("Invalid identifier %q", e.Name)
+	}
+
 	var res = ""
 	if e.Expr != nil {
-		s, err := p.printExpr(e.Expr, context)
+		s, err := p.printOperand(e.Expr, precCall, context)
 		if err != nil {
 			return "", err
 		}
+		if n, ok := ungroup(e.Expr).(IntegerLiteralExpr); ok && n.Value >= 0 {
+			// "1.x" would be scanned as the float "1." followed by x
+			s = "(" + s + ")"
+		}
 		res += s
 	}
 	if len(res) > 0 {
@@ -143,7 +246,80 @@ func (p *printer) VisitIdentifierExpr(e IdentifierExpr, context VisitorContext)
 	return res, nil
 }
 
+func (p *printer) VisitLambdaExpr(e LambdaExpr, context VisitorContext) (interface{}, error) {
+	if !isIdentifier(e.Param) {
+		return nil, fmt.Errorf("Invalid identifier %q", e.Param)
+	}
+	body, err := p.printExpr(e.Body, context)
+	if err != nil {
+		return nil, err
+	}
+	return e.Param + " => " + body, nil
+}
+
+func (p *printer) VisitLetExpr(e LetExpr, context VisitorContext) (interface{}, error) {
+	if !isIdentifier(e.Name) {
+		return nil, fmt.Errorf("Invalid identifier %q", e.Name)
+	}
+	value, err := p.printExpr(e.Value, context)
+	if err != nil {
+		return nil, err
+	}
+	body, err := p.printExpr(e.Body, context)
+	if err != nil {
+		return nil, err
+	}
+	return fmt.Sprintf("let %s = %s; %s", e.Name, value, body), nil
+}
+
+func (p *printer) VisitTemplateExpr(e TemplateExpr, context VisitorContext) (interface{}, error) {
+	// adjacent text parts are escaped together, e.g. "$" and "{" as "\${"
+	var b strings.Builder
+	var text string
+	b.WriteByte('`')
+	for _, part := range e.Parts {
+		if lit, ok := part.(StringLiteralExpr); ok {
+			text += lit.Value
+			continue
+		}
+		s, err := p.printExpr(part, context)
+		if err != nil {
+			return nil, err
+		}
+		b.WriteString(templateReplacer.Replace(text) + "${" + s + "}")
+		text = ""
+	}
+	b.WriteString(templateReplacer.Replace(text) + "`")
+	return b.String(), nil
+}
+
+func (p *printer) VisitNamedArgExpr(e NamedArgExpr, context VisitorContext) (interface{}, error) {
+	if !isIdentifier(e.Name) {
+		return nil, fmt.Errorf("Invalid identifier %q", e.Name)
+	}
+	value, err := p.printExpr(e.Value, context)
+	if err != nil {
+		return nil, err
+	}
+	return e.Name + ": " + value, nil
+}
+
+// printOperand prints the expression and wraps it in parentheses if it binds looser than prec
+func (p *printer) printOperand(expr Expr, prec int, context VisitorContext) (string, error) {
+	s, err := p.printExpr(expr, context)
+	if err != nil {
+		return "", err
+	}
+	if precedence(expr) < prec {
+		s = "(" + s + ")"
+	}
+	return s, nil
+}
+
 func (p *printer) printExpr(expr Expr, context VisitorContext) (string, error) {
+	if expr == nil {
+		return "", errors.New("Unable to print nil expression")
+	}
 	res, err := expr.Accept(p, context)
 	if err != nil {
 		return "", err
@@ -175,3 +351,52 @@ func (p *printer) printMany(exprs []Expr, context VisitorContext) ([]string, err
 	}
 	return strs, nil
 }
+
+// ungroup returns the expression inside the grouping expressions
+func ungroup(expr Expr) Expr {
+	for {
+		g, ok := expr.(GroupingExpr)
+		if !ok {
+			return expr
+		}
+		expr = g.Expr
+	}
+}
+
+var quoteReplacer = strings.NewReplacer(
+	`\`, `\\`,
+	`"`, `\"`,
+	"\n", `\n`,
+	"\t", `\t`,
+	"\r", `\r`,
+)
+
+var templateReplacer = strings.NewReplacer(
+	`\`, `\\`,
+	"`", "\\`",
+	"${", `\${`,
+	"\n", `\n`,
+	"\t", `\t`,
+	"\r", `\r`,
+)
+
+// quote returns the string as a double quoted literal
+func quote(s string) string {
+	return `"` + quoteReplacer.Replace(s) + `"`
+}
+
+// isIdentifier returns true if the name can be scanned as an identifier
+func isIdentifier(name string) bool {
+	if name == "" {
+		return false
+	}
+	if _, isKeyword := keywords[strings.ToLower(name)]; isKeyword {
+		return false
+	}
+	for i, c := range name {
+		if !isAlpha(c) && (i == 0 || !isDigit(c)) {
+			return false
+		}
+	}
+	return true
+}
@@ -1,7 +1,11 @@
 package goexp
 
 import (
+	"math"
+	"math/rand"
 	"testing"
+
+	"github.com/go-test/deep"
 )
 
 var printExprTests = []struct {
@@ -58,3 +62,279 @@ func TestPrintExpr(t *testing.T) {
 		})
 	}
 }
+
+func TestPrintCanonical(t *testing.T) {
+	tests := []struct {
+		expr     Expr
+		expected string
+	}{
+		{BooleanLiteralExpr{true}, "true"},
+		{NilLiteralExpr{}, "nil"},
+		{FloatLiteralExpr{1e-9}, "1e-09"},
+		{FloatLiteralExpr{2}, "2.0"},
+		{FloatLiteralExpr{0.1}, "0.1"},
+		{StringLiteralExpr{`say "hi" \ ok` + "\n"}, `"say \"hi\" \\ ok\n"`},
+		{
+			BinaryExpr{
+				Left:     BinaryExpr{Left: IdentifierExpr{Name: "a"}, Operator: Token{Type: Add}, Right: IdentifierExpr{Name: "b"}},
+				Operator: Token{Type: Mul},
+				Right:    IdentifierExpr{Name: "c"},
+			},
+			"(a + b) * c",
+		},
+		{
+			BinaryExpr{
+				Left:     IdentifierExpr{Name: "a"},
+				Operator: Token{Type: Sub},
+				Right:    BinaryExpr{Left: IdentifierExpr{Name: "b"}, Operator: Token{Type: Sub}, Right: IdentifierExpr{Name: "c"}},
+			},
+			"a - (b - c)",
+		},
+		{
+			BinaryExpr{
+				Left:     BinaryExpr{Left: IdentifierExpr{Name: "a"}, Operator: Token{Type: Sub}, Right: IdentifierExpr{Name: "b"}},
+				Operator: Token{Type: Sub},
+				Right:    IdentifierExpr{Name: "c"},
+			},
+			"a - b - c",
+		},
+		{UnaryExpr{Value: UnaryExpr{Value: IdentifierExpr{Name: "x"}, Operator: Token{Type: Not}}, Operator: Token{Type: Not}}, "!(!x)"},
+		{UnaryExpr{Value: UnaryExpr{Value: IdentifierExpr{Name: "x"}, Operator: Token{Type: Sub}}, Operator: Token{Type: Sub}}, "-(-x)"},
+		{IntegerLiteralExpr{math.MinInt64}, "-9223372036854775808"},
+		{FloatLiteralExpr{-2.5}, "-2.5"},
+		{UnaryExpr{Value: IntegerLiteralExpr{5}, Operator: Token{Type: Sub}}, "-(5)"},
+		{UnaryExpr{Value: IntegerLiteralExpr{-5}, Operator: Token{Type: Sub}}, "-(-5)"},
+		{BinaryExpr{Left: IdentifierExpr{Name: "x"}, Operator: Token{Type: Sub}, Right: IntegerLiteralExpr{-5}}, "x - -5"},
+		{IdentifierExpr{Name: "m", Expr: FloatLiteralExpr{-0.5}}, "(-0.5).m"},
+		{
+			BinaryExpr{
+				Left:     UnaryExpr{Value: IdentifierExpr{Name: "a"}, Operator: Token{Type: Not}},
+				Operator: Token{Type: Equal},
+				Right:    IdentifierExpr{Name: "b"},
+			},
+			"(!a) == b",
+		},
+		{
+			UnaryExpr{
+				Value:    BinaryExpr{Left: IdentifierExpr{Name: "a"}, Operator: Token{Type: Equal}, Right: IdentifierExpr{Name: "b"}},
+				Operator: Token{Type: Not},
+			},
+			"!a == b",
+		},
+		{
+			IdentifierExpr{Name: "len", Expr: BinaryExpr{Left: IdentifierExpr{Name: "a"}, Operator: Token{Type: Add}, Right: IdentifierExpr{Name: "b"}}},
+			"(a + b).len",
+		},
+		{GroupingExpr{GroupingExpr{IdentifierExpr{Name: "x"}}}, "x"},
+		{
+			CallExpr{
+				Name: IdentifierExpr{Name: "count"},
+				Args: []Expr{IdentifierExpr{Name: "orders"}, LambdaExpr{Param: "o", Body: IdentifierExpr{Name: "paid", Expr: IdentifierExpr{Name: "o"}}}},
+			},
+			"count(orders, o => o.paid)",
+		},
+		{
+			BinaryExpr{
+				Left:     LambdaExpr{Param: "x", Body: IdentifierExpr{Name: "x"}},
+				Operator: Token{Type: Or},
+				Right:    LambdaExpr{Param: "y", Body: BinaryExpr{Left: IdentifierExpr{Name: "y"}, Operator: Token{Type: Or}, Right: IdentifierExpr{Name: "z"}}},
+			},
+			"(x => x) || (y => y || z)",
+		},
+		{LambdaExpr{Param: "x", Body: LambdaExpr{Param: "y", Body: IdentifierExpr{Name: "y"}}}, "x => y => y"},
+		{
+			LetExpr{Name: "a", Value: LetExpr{Name: "b", Value: IntegerLiteralExpr{1}, Body: IdentifierExpr{Name: "b"}}, Body: BinaryExpr{
+				Left:     IdentifierExpr{Name: "a"},
+				Operator: Token{Type: Mul},
+				Right:    LetExpr{Name: "c", Value: IntegerLiteralExpr{2}, Body: IdentifierExpr{Name: "c"}},
+			}},
+			"let a = let b = 1; b; a * (let c = 2; c)",
+		},
+		{
+			CallExpr{
+				Name: IdentifierExpr{Name: "round"},
+				Args: []Expr{IdentifierExpr{Name: "x"}, NamedArgExpr{Name: "digits", Value: BinaryExpr{Left: IntegerLiteralExpr{1}, Operator: Token{Type: Add}, Right: IntegerLiteralExpr{1}}}},
+			},
+			"round(x, digits: 1 + 1)",
+		},
+		{
+			TemplateExpr{[]Expr{
+				StringLiteralExpr{"a`b${c}\n"},
+				BinaryExpr{Left: IdentifierExpr{Name: "x"}, Operator: Token{Type: Add}, Right: IntegerLiteralExpr{1}},
+				TemplateExpr{[]Expr{StringLiteralExpr{"$"}, IdentifierExpr{Name: "y"}, StringLiteralExpr{"$"}, StringLiteralExpr{"{"}}},
+			}},
+			"`a\\`b\\${c}\\n${x + 1}${`$${y}\\${`}`",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.expected, func(t *testing.T) {
+			s, err := Print(test.expr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if s != test.expected {
+				t.Errorf("Expected %s but got %s", test.expected, s)
+			}
+		})
+	}
+
+	for _, expr := range []Expr{FloatLiteralExpr{math.Inf(1)}, IdentifierExpr{Name: "true"}, IdentifierExpr{Name: "a-b"}, LambdaExpr{Param: "nil", Body: NilLiteralExpr{}}, NamedArgExpr{Name: "a b", Value: NilLiteralExpr{}}, LetExpr{Name: "let", Value: NilLiteralExpr{}, Body: NilLiteralExpr{}}} {
+		if s, err := Print(expr); err == nil {
+			t.Errorf("Expected an error but got %s", s)
+		}
+	}
+}
+
+// TestPrintRoundTrip checks that printing and parsing random expressions
+// results in structurally equal expressions
+func TestPrintRoundTrip(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 5000; i++ {
+		expr := randomPrintableExpr(r, 5)
+		s, err := Print(expr)
+		if err != nil {
+			t.Fatalf("%#v: %v", expr, err)
+		}
+		parsed, err := Parse(s)
+		if err != nil {
+			t.Fatalf("%s: %v", s, err)
+		}
+		if diff := deep.Equal(normalizeExpr(parsed), normalizeExpr(expr)); diff != nil {
+			t.Fatalf("%s: %v", s, diff)
+		}
+	}
+}
+
+var printableStrings = []string{"", "abc", `a'b`, `a"b`, `a\b`, "a\nb\tc", "жаба", `\"`}
+
+func randomPrintableExpr(r *rand.Rand, depth int) Expr {
+	if depth == 0 || r.Intn(5) == 0 {
+		switch r.Intn(8) {
+		case 0:
+			switch r.Intn(4) {
+			case 0:
+				return IntegerLiteralExpr{-r.Int63()}
+			case 1:
+				return IntegerLiteralExpr{[]int64{math.MinInt64, math.MaxInt64, 0}[r.Intn(3)]}
+			}
+			return IntegerLiteralExpr{r.Int63()}
+		case 1:
+			f := r.ExpFloat64() * math.Pow(10, float64(r.Intn(40)-20))
+			if r.Intn(2) == 0 {
+				f = -f
+			}
+			return FloatLiteralExpr{f}
+		case 2:
+			return BooleanLiteralExpr{r.Intn(2) == 0}
+		case 3:
+			return StringLiteralExpr{printableStrings[r.Intn(len(printableStrings))]}
+		case 4:
+			return NilLiteralExpr{}
+		case 5:
+			return IdentifierExpr{Name: "a", Expr: IdentifierExpr{Name: "b_1"}}
+		default:
+			return IdentifierExpr{Name: []string{"x", "y", "_z", "True1"}[r.Intn(4)]}
+		}
+	}
+
+	switch r.Intn(9) {
+	case 0:
+		return GroupingExpr{randomPrintableExpr(r, depth-1)}
+	case 1:
+		op := Token{Type: Sub}
+		if r.Intn(2) == 0 {
+			op = Token{Type: Not}
+		}
+		return UnaryExpr{Value: randomPrintableExpr(r, depth-1), Operator: op}
+	case 2:
+		args := make([]Expr, r.Intn(3))
+		for i := range args {
+			args[i] = randomPrintableExpr(r, depth-1)
+		}
+		if len(args) > 0 && r.Intn(2) == 0 {
+			args[len(args)-1] = NamedArgExpr{Name: "a", Value: args[len(args)-1]}
+		}
+		var receiver Expr
+		if r.Intn(2) == 0 {
+			receiver = randomPrintableExpr(r, depth-1)
+		}
+		return CallExpr{Name: IdentifierExpr{Name: "f", Expr: receiver}, Args: args}
+	case 3:
+		return IdentifierExpr{Name: "m", Expr: randomPrintableExpr(r, depth-1)}
+	case 4:
+		return LambdaExpr{Param: "x", Body: randomPrintableExpr(r, depth-1)}
+	case 5:
+		return LetExpr{Name: "v", Value: randomPrintableExpr(r, depth-1), Body: randomPrintableExpr(r, depth-1)}
+	case 6:
+		parts := make([]Expr, r.Intn(4))
+		for i := range parts {
+			if r.Intn(2) == 0 {
+				parts[i] = StringLiteralExpr{printableStrings[r.Intn(len(printableStrings))] + "${`"}
+			} else {
+				parts[i] = randomPrintableExpr(r, depth-1)
+			}
+		}
+		return TemplateExpr{parts}
+	default:
+		t := randomBinaryOps[r.Intn(len(randomBinaryOps))]
+		return BinaryExpr{
+			Left:     randomPrintableExpr(r, depth-1),
+			Right:    randomPrintableExpr(r, depth-1),
+			Operator: Token{Type: t},
+		}
+	}
+}
+
+// normalizeExpr drops the grouping expressions and the token details other than the type
+func normalizeExpr(expr Expr) Expr {
+	switch e := expr.(type) {
+	case GroupingExpr:
+		return normalizeExpr(e.Expr)
+	case BinaryExpr:
+		return BinaryExpr{Left: normalizeExpr(e.Left), Right: normalizeExpr(e.Right), Operator: Token{Type: e.Operator.Type}}
+	case UnaryExpr:
+		return UnaryExpr{Value: normalizeExpr(e.Value), Operator: Token{Type: e.Operator.Type}}
+	case IdentifierExpr:
+		if e.Expr != nil {
+			e.Expr = normalizeExpr(e.Expr)
+		}
+		return e
+	case CallExpr:
+		args := make([]Expr, len(e.Args))
+		for i, arg := range e.Args {
+			args[i] = normalizeExpr(arg)
+		}
+		return CallExpr{Name: normalizeExpr(e.Name), Args: args}
+	case LambdaExpr:
+		return LambdaExpr{Param: e.Param, Body: normalizeExpr(e.Body)}
+	case NamedArgExpr:
+		return NamedArgExpr{Name: e.Name, Value: normalizeExpr(e.Value)}
+	case LetExpr:
+		return LetExpr{Name: e.Name, Value: normalizeExpr(e.Value), Body: normalizeExpr(e.Body)}
+	case TemplateExpr:
+		// adjacent text parts are printed as one and empty ones are not printed
+		var parts []Expr
+		for _, part := range e.Parts {
+			part = normalizeExpr(part)
+			lit, ok := part.(StringLiteralExpr)
+			if !ok {
+				parts = append(parts, part)
+				continue
+			}
+			if lit.Value == "" {
+				continue
+			}
+			if n := len(parts); n > 0 {
+				if prev, ok := parts[n-1].(StringLiteralExpr); ok {
+					parts[n-1] = StringLiteralExpr{prev.Value + lit.Value}
+					continue
+				}
+			}
+			parts = append(parts, part)
+		}
+		return TemplateExpr{parts}
+	default:
+		return expr
+	}
+}
@@ -13,8 +13,13 @@ var keywords = map[string]TokenType{
 	"and":   And,
 	"or":    Or,
 	"not":   Not,
+	"let":   Let,
 }
 
+// minInt64Magnitude is the literal of the absolute value of the smallest
+// int64, e.g. in -9223372036854775808
+const minInt64Magnitude uint64 = 1 << 63
+
 type scannerError struct {
 	Message string
 	Pos     int
@@ -38,7 +43,7 @@ func newScanner(source string) *scanner {
 		source:  []rune(source),
 		start:   0,
 		current: 0,
-		length:  len(source),
+		length:  len([]rune(source)),
 		tokens:  make([]Token, 0),
 	}
 }
@@ -46,7 +51,7 @@ func newScanner(source string) *scanner {
 func (s *scanner) scan() ([]Token, error) {
 	s.reset()
 
-	for !s.isAtEnd() {
+	for !s.isAtEnd() && s.err == nil {
 		s.start = s.current
 		s.scanToken()
 	}
@@ -73,6 +78,8 @@ func (s *scanner) scanToken() {
 		s.addToken(LeftBrace, nil)
 	case '}':
 		s.addToken(RightBrace, nil)
+	case '`':
+		s.readTemplate()
 
 	case '+':
 		s.addToken(Add, nil)
@@ -98,6 +105,10 @@ func (s *scanner) scanToken() {
 
 	case ',':
 		s.addToken(Comma, nil)
+	case ':':
+		s.addToken(Colon, nil)
+	case ';':
+		s.addToken(Semicolon, nil)
 
 	case '!':
 		if s.match('=') {
@@ -109,6 +120,10 @@ func (s *scanner) scanToken() {
 	case '=':
 		if s.match('=') {
 			s.addToken(Equal, nil)
+		} else if s.match('>') {
+			s.addToken(Arrow, nil)
+		} else {
+			s.addToken(Assign, nil)
 		}
 
 	case '<':
@@ -179,6 +194,20 @@ func (s *scanner) readNumber() {
 		}
 	}
 
+	if c := s.peek(); c == 'e' || c == 'E' {
+		next := s.peekNext()
+		if isDigit(next) || (next == '+' || next == '-') && s.current+2 < s.length && isDigit(s.source[s.current+2]) {
+			isFloat = true
+			s.advance()
+			if next == '+' || next == '-' {
+				s.advance()
+			}
+			for isDigit(s.peek()) {
+				s.advance()
+			}
+		}
+	}
+
 	str := string(s.source[s.start:s.current])
 
 	if isFloat {
@@ -190,7 +219,10 @@ func (s *scanner) readNumber() {
 		}
 	} else {
 		n, err := strconv.ParseInt(str, 10, 64)
-		if err != nil {
+		if u, _ := strconv.ParseUint(str, 10, 64); err != nil && u == minInt64Magnitude {
+			// only valid after a minus, which the parser checks
+			s.addToken(Integer, u)
+		} else if err != nil {
 			s.error("Invalid floating point number: %s (%s)", str, err.Error())
 		} else {
 			s.addToken(Integer, n)
@@ -210,18 +242,142 @@ func (s *scanner) readIdentifier() {
 	s.addToken(tokenType, nil)
 }
 
+var escapes = map[rune]rune{
+	'\\': '\\',
+	'\'': '\'',
+	'"':  '"',
+	'n':  '\n',
+	't':  '\t',
+	'r':  '\r',
+}
+
 func (s *scanner) readStringLiteral(term rune) {
-	for (s.peek() != term || s.source[s.current-1] == '\\') && !s.isAtEnd() {
-		s.advance()
+	var str []rune
+	for s.peek() != term && !s.isAtEnd() {
+		c := s.advance()
+		if c == '\\' && !s.isAtEnd() {
+			escaped, ok := escapes[s.peek()]
+			if !ok {
+				s.current++
+				s.error("Invalid escape sequence \\%c", s.source[s.current-1])
+				return
+			}
+			s.advance()
+			c = escaped
+		}
+		str = append(str, c)
 	}
 
 	if s.isAtEnd() {
 		s.error("Unterminated string")
 	} else {
 		s.advance()
-		str := string(s.source[s.start+1 : s.current-1])
-		s.addToken(String, str)
+		s.addToken(String, string(str))
+	}
+}
+
+// TemplatePart is a part of a template string token: literal text, or the
+// source of an embedded ${expression} starting Pos runes after the start of the token
+type TemplatePart struct {
+	Text string
+	Expr bool
+	Pos  int
+}
+
+// readTemplate reads a template string; the literal of the token is the list of its parts
+func (s *scanner) readTemplate() {
+	var parts []TemplatePart
+	var text []rune
+	for s.peek() != '`' && !s.isAtEnd() {
+		c := s.advance()
+		switch {
+		case c == '\\' && !s.isAtEnd():
+			escaped, ok := escapes[s.peek()]
+			if !ok && (s.peek() == '`' || s.peek() == '$') {
+				escaped, ok = s.peek(), true
+			}
+			if !ok {
+				s.current++
+				s.error("Invalid escape sequence \\%c", s.source[s.current-1])
+				return
+			}
+			s.advance()
+			text = append(text, escaped)
+		case c == '$' && s.peek() == '{':
+			start := s.current + 1
+			end := s.skipExpression(start)
+			if end < 0 {
+				s.current = s.length
+				s.error("Unterminated template expression")
+				return
+			}
+			if len(text) > 0 {
+				parts = append(parts, TemplatePart{Text: string(text)})
+				text = nil
+			}
+			parts = append(parts, TemplatePart{Text: string(s.source[start:end]), Expr: true, Pos: start - s.start})
+			s.current = end + 1
+		default:
+			text = append(text, c)
+		}
+	}
+
+	if s.isAtEnd() {
+		s.error("Unterminated template")
+		return
+	}
+	s.advance()
+	if len(text) > 0 {
+		parts = append(parts, TemplatePart{Text: string(text)})
+	}
+	s.addToken(Template, parts)
+}
+
+// skipExpression returns the position of the "}" closing the embedded
+// expression starting at i, skipping nested braces, strings and templates
+func (s *scanner) skipExpression(i int) int {
+	depth := 0
+	for ; i < s.length; i++ {
+		switch c := s.source[i]; c {
+		case '\'', '"':
+			for i++; i < s.length && s.source[i] != c; i++ {
+				if s.source[i] == '\\' {
+					i++
+				}
+			}
+		case '`':
+			if i = s.skipTemplate(i + 1); i < 0 {
+				return -1
+			}
+		case '{':
+			depth++
+		case '}':
+			if depth == 0 {
+				return i
+			}
+			depth--
+		}
+	}
+	return -1
+}
+
+// skipTemplate returns the position of the "`" closing the template starting at i
+func (s *scanner) skipTemplate(i int) int {
+	for ; i < s.length; i++ {
+		switch s.source[i] {
+		case '\\':
+			i++
+		case '`':
+			return i
+		case '$':
+			if i+1 < s.length && s.source[i+1] == '{' {
+				if i = s.skipExpression(i + 2); i < 0 {
+					return -1
+				}
+			}
+		}
 	}
+	return -1
 }
 
 func (s *scanner) error(message string, args ...interface{}) {
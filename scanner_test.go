@@ -46,13 +46,20 @@ func TestScan(t *testing.T) {
 		{"1", []Token{Token{Integer, string("1"), int64(1), 0}, Token{Type: EOF, Pos: 1}}, nil},
 		{"123", []Token{Token{Integer, "123", int64(123), 0}, Token{Type: EOF, Pos: 3}}, nil},
 		{"1.23", []Token{Token{Float, "1.23", float64(1.23), 0}, Token{Type: EOF, Pos: 4}}, nil},
+		{"1e-9", []Token{Token{Float, "1e-9", float64(1e-9), 0}, Token{Type: EOF, Pos: 4}}, nil},
+		{"2.5E+3", []Token{Token{Float, "2.5E+3", float64(2500), 0}, Token{Type: EOF, Pos: 6}}, nil},
+		{"1e", []Token{Token{Integer, "1", int64(1), 0}, Token{Identifier, "e", nil, 1}, Token{Type: EOF, Pos: 2}}, nil},
 		{"''", []Token{Token{String, "''", "", 0}, Token{Type: EOF, Pos: 2}}, nil},
 		{"'abc'", []Token{Token{String, "'abc'", "abc", 0}, Token{Type: EOF, Pos: 5}}, nil},
-		{"'ab\\'c'", []Token{Token{String, "'ab\\'c'", "ab\\'c", 0}, Token{Type: EOF, Pos: 7}}, nil},
+		{"'ab\\'c'", []Token{Token{String, "'ab\\'c'", "ab'c", 0}, Token{Type: EOF, Pos: 7}}, nil},
 		{"\"\"", []Token{Token{String, "\"\"", "", 0}, Token{Type: EOF, Pos: 2}}, nil},
 		{"\"abc\"", []Token{Token{String, "\"abc\"", "abc", 0}, Token{Type: EOF, Pos: 5}}, nil},
-		{"\"ab\\\"c\"", []Token{Token{String, "\"ab\\\"c\"", "ab\\\"c", 0}, Token{Type: EOF, Pos: 7}}, nil},
+		{"\"ab\\\"c\"", []Token{Token{String, "\"ab\\\"c\"", "ab\"c", 0}, Token{Type: EOF, Pos: 7}}, nil},
+		{"'a\\\\'", []Token{Token{String, "'a\\\\'", "a\\", 0}, Token{Type: EOF, Pos: 5}}, nil},
+		{"'a\\nb'", []Token{Token{String, "'a\\nb'", "a\nb", 0}, Token{Type: EOF, Pos: 6}}, nil},
+		{"'ж'", []Token{Token{String, "'ж'", "ж", 0}, Token{Type: EOF, Pos: 3}}, nil},
 		{"'ab", []Token{}, &scannerError{Message: "Unterminated string", Pos: 3}},
+		{"'a\\x'", []Token{}, &scannerError{Message: "Invalid escape sequence \\x", Pos: 4}},
 		{"foo", []Token{Token{Identifier, "foo", nil, 0}, Token{Type: EOF, Pos: 3}}, nil},
 		{"<", []Token{Token{Less, "<", nil, 0}, Token{Type: EOF, Pos: 1}}, nil},
 		{"<=", []Token{Token{LessEqual, "<=", nil, 0}, Token{Type: EOF, Pos: 2}}, nil},
@@ -60,6 +67,18 @@ func TestScan(t *testing.T) {
 		{">=", []Token{Token{GreaterEqual, ">=", nil, 0}, Token{Type: EOF, Pos: 2}}, nil},
 		{"==", []Token{Token{Equal, "==", nil, 0}, Token{Type: EOF, Pos: 2}}, nil},
 		{"!=", []Token{Token{NotEqual, "!=", nil, 0}, Token{Type: EOF, Pos: 2}}, nil},
+		{"=>", []Token{Token{Arrow, "=>", nil, 0}, Token{Type: EOF, Pos: 2}}, nil},
+		{"let a = 1;", []Token{Token{Let, "let", nil, 0}, Token{Identifier, "a", nil, 4}, Token{Assign, "=", nil, 6}, Token{Integer, "1", int64(1), 8}, Token{Semicolon, ";", nil, 9}, Token{Type: EOF, Pos: 10}}, nil},
+		{"`a${x}b`", []Token{Token{Template, "`a${x}b`", []TemplatePart{{"a", false, 0}, {"x", true, 4}, {"b", false, 0}}, 0}, Token{Type: EOF, Pos: 8}}, nil},
+		{"`${f({a: '}'})}`", []Token{Token{Template, "`${f({a: '}'})}`", []TemplatePart{{"f({a: '}'})", true, 3}}, 0}, Token{Type: EOF, Pos: 16}}, nil},
+		{"`${`${x}`}`", []Token{Token{Template, "`${`${x}`}`", []TemplatePart{{"`${x}`", true, 3}}, 0}, Token{Type: EOF, Pos: 11}}, nil},
+		{"`\\${x} \\` \\\\`", []Token{Token{Template, "`\\${x} \\` \\\\`", []TemplatePart{{"${x} ` \\", false, 0}}, 0}, Token{Type: EOF, Pos: 13}}, nil},
+		{"``", []Token{Token{Template, "``", []TemplatePart(nil), 0}, Token{Type: EOF, Pos: 2}}, nil},
+		{"`ab", []Token{}, &scannerError{Message: "Unterminated template", Pos: 3}},
+		{"`a${x", []Token{}, &scannerError{Message: "Unterminated template expression", Pos: 5}},
+		{"`${'}`", []Token{}, &scannerError{Message: "Unterminated template expression", Pos: 6}},
+		{"`\\x`", []Token{}, &scannerError{Message: "Invalid escape sequence \\x", Pos: 3}},
+		{"a:", []Token{Token{Identifier, "a", nil, 0}, Token{Colon, ":", nil, 1}, Token{Type: EOF, Pos: 2}}, nil},
 		{"+", []Token{Token{Add, "+", nil, 0}, Token{Type: EOF, Pos: 1}}, nil},
 		{"+++", []Token{Token{Add, "+", nil, 0}, Token{Add, "+", nil, 1}, Token{Add, "+", nil, 2}, Token{Type: EOF, Pos: 3}}, nil},
 		{"-", []Token{Token{Sub, "-", nil, 0}, Token{Type: EOF, Pos: 1}}, nil},
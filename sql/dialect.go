@@ -0,0 +1,87 @@
+package sql
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Dialect describes the syntax that differs between the SQL databases
+type Dialect interface {
+	// Placeholder returns the placeholder of the n-th (1-based) argument
+	Placeholder(n int) string
+
+	// QuoteIdentifier quotes a table or column name
+	QuoteIdentifier(name string) string
+
+	// Concat returns the concatenation of two string expressions
+	Concat(left, right string) string
+
+	// Power returns left raised to the power of right
+	Power(left, right string) (string, error)
+}
+
+var (
+	// PostgreSQL uses $1, $2, ... placeholders
+	PostgreSQL Dialect = postgres{}
+
+	// MySQL uses ? placeholders
+	MySQL Dialect = mysql{}
+
+	// SQLite uses ?1, ?2, ... placeholders
+	SQLite Dialect = sqlite{}
+)
+
+type postgres struct{}
+
+func (postgres) Placeholder(n int) string {
+	return fmt.Sprintf("$%d", n)
+}
+
+func (postgres) QuoteIdentifier(name string) string {
+	return `"` + strings.Replace(name, `"`, `""`, -1) + `"`
+}
+
+func (postgres) Concat(left, right string) string {
+	return left + " || " + right
+}
+
+func (postgres) Power(left, right string) (string, error) {
+	return fmt.Sprintf("POWER(%s, %s)", left, right), nil
+}
+
+type mysql struct{}
+
+func (mysql) Placeholder(n int) string {
+	return "?"
+}
+
+func (mysql) QuoteIdentifier(name string) string {
+	return "`" + strings.Replace(name, "`", "``", -1) + "`"
+}
+
+func (mysql) Concat(left, right string) string {
+	return fmt.Sprintf("CONCAT(%s, %s)", left, right)
+}
+
+func (mysql) Power(left, right string) (string, error) {
+	return fmt.Sprintf("POWER(%s, %s)", left, right), nil
+}
+
+type sqlite struct{}
+
+func (sqlite) Placeholder(n int) string {
+	return fmt.Sprintf("?%d", n)
+}
+
+func (sqlite) QuoteIdentifier(name string) string {
+	return `"` + strings.Replace(name, `"`, `""`, -1) + `"`
+}
+
+func (sqlite) Concat(left, right string) string {
+	return left + " || " + right
+}
+
+func (sqlite) Power(left, right string) (string, error) {
+	// POWER is only available when SQLite is built with the math functions
+	return "", fmt.Errorf("The ** operator is not supported by SQLite")
+}
@@ -0,0 +1,285 @@
+/*
+Package sql translates goexp expressions to parameterized SQL WHERE clauses.
+
+	expr, _ := goexp.Parse("age >= 18 && name != nil")
+	where, args, err := sql.Where(expr, sql.PostgreSQL, nil)
+	// where: `("age" >= $1) AND ("name" IS NOT NULL)`, args: [18]
+*/
+package sql
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/svstanev/goexp"
+)
+
+// ColumnMapper maps the path of an identifier (e.g. ["user", "age"] for user.age) to a column expression
+type ColumnMapper func(path []string) (string, error)
+
+// UnsupportedError is returned for expressions that have no SQL equivalent
+type UnsupportedError struct {
+	Expr   goexp.Expr
+	Reason string
+}
+
+func (err UnsupportedError) Error() string {
+	s, e := goexp.Print(err.Expr)
+	if e != nil {
+		s = fmt.Sprintf("%T", err.Expr)
+	}
+	return fmt.Sprintf("Cannot translate %s to SQL: %s", s, err.Reason)
+}
+
+// Where translates the expression to a SQL condition and the arguments of its placeholders
+func Where(expr goexp.Expr, dialect Dialect, columns ColumnMapper) (string, []interface{}, error) {
+	return NewVisitor(dialect, columns).Where(expr)
+}
+
+/*
+Visitor translates expressions to SQL.
+
+Literals are passed as arguments, comparisons with nil are translated to
+IS [NOT] NULL and "+" is translated to a string concatenation when one of its
+operands is a string literal (or a concatenation). Calls are translated only
+for the methods listed in Functions.
+
+A Visitor is not safe for concurrent use.
+*/
+type Visitor struct {
+	Dialect Dialect
+
+	// Columns maps the identifiers to columns; by default the quoted names are joined with "."
+	Columns ColumnMapper
+
+	// Functions maps the names of the methods that may be called to SQL functions
+	Functions map[string]string
+
+	args []interface{}
+}
+
+// NewVisitor returns a Visitor for the given dialect and column mapper
+func NewVisitor(dialect Dialect, columns ColumnMapper) *Visitor {
+	return &Visitor{
+		Dialect: dialect,
+		Columns: columns,
+	}
+}
+
+// Where translates the expression to a SQL condition and the arguments of its placeholders
+func (v *Visitor) Where(expr goexp.Expr) (string, []interface{}, error) {
+	v.args = nil
+	s, err := v.translate(expr)
+	if err != nil {
+		return "", nil, err
+	}
+	return s, v.args, nil
+}
+
+func (v *Visitor) translate(expr goexp.Expr) (string, error) {
+	res, err := expr.Accept(v, nil)
+	if err != nil {
+		return "", err
+	}
+	return res.(string), nil
+}
+
+// operand translates a subexpression and wraps it in parentheses if it is compound
+func (v *Visitor) operand(expr goexp.Expr) (string, error) {
+	s, err := v.translate(expr)
+	if err != nil {
+		return "", err
+	}
+	switch ungroup(expr).(type) {
+	case goexp.BinaryExpr, goexp.UnaryExpr:
+		s = "(" + s + ")"
+	}
+	return s, nil
+}
+
+func (v *Visitor) arg(value interface{}) (interface{}, error) {
+	v.args = append(v.args, value)
+	return v.Dialect.Placeholder(len(v.args)), nil
+}
+
+func (v *Visitor) VisitStringLiteralExpr(e goexp.StringLiteralExpr, context goexp.VisitorContext) (interface{}, error) {
+	return v.arg(e.Value)
+}
+
+func (v *Visitor) VisitIntegerLiteralExpr(e goexp.IntegerLiteralExpr, context goexp.VisitorContext) (interface{}, error) {
+	return v.arg(e.Value)
+}
+
+func (v *Visitor) VisitFloatLiteralExpr(e goexp.FloatLiteralExpr, context goexp.VisitorContext) (interface{}, error) {
+	return v.arg(e.Value)
+}
+
+func (v *Visitor) VisitBooleanLiteralExpr(e goexp.BooleanLiteralExpr, context goexp.VisitorContext) (interface{}, error) {
+	return v.arg(e.Value)
+}
+
+func (v *Visitor) VisitNilLiteralExpr(e goexp.NilLiteralExpr, context goexp.VisitorContext) (interface{}, error) {
+	return "NULL", nil
+}
+
+func (v *Visitor) VisitGroupingExpr(e goexp.GroupingExpr, context goexp.VisitorContext) (interface{}, error) {
+	return v.translate(e.Expr)
+}
+
+func (v *Visitor) VisitIdentifierExpr(e goexp.IdentifierExpr, context goexp.VisitorContext) (interface{}, error) {
+	path, ok := goexp.Path(e)
+	if !ok {
+		return nil, UnsupportedError{e, "member access is supported only on names"}
+	}
+	if v.Columns != nil {
+		return v.Columns(path)
+	}
+	quoted := make([]string, len(path))
+	for i, name := range path {
+		quoted[i] = v.Dialect.QuoteIdentifier(name)
+	}
+	return strings.Join(quoted, "."), nil
+}
+
+func (v *Visitor) VisitLambdaExpr(e goexp.LambdaExpr, context goexp.VisitorContext) (interface{}, error) {
+	return nil, UnsupportedError{e, "lambdas are not supported"}
+}
+
+func (v *Visitor) VisitLetExpr(e goexp.LetExpr, context goexp.VisitorContext) (interface{}, error) {
+	return nil, UnsupportedError{e, "let bindings are not supported"}
+}
+
+func (v *Visitor) VisitTemplateExpr(e goexp.TemplateExpr, context goexp.VisitorContext) (interface{}, error) {
+	return nil, UnsupportedError{e, "template strings are not supported"}
+}
+
+func (v *Visitor) VisitNamedArgExpr(e goexp.NamedArgExpr, context goexp.VisitorContext) (interface{}, error) {
+	return nil, UnsupportedError{e, "named arguments are not supported"}
+}
+
+func (v *Visitor) VisitUnaryExpr(e goexp.UnaryExpr, context goexp.VisitorContext) (interface{}, error) {
+	value, err := v.operand(e.Value)
+	if err != nil {
+		return nil, err
+	}
+	switch e.Operator.Type {
+	case goexp.Not:
+		return "NOT " + value, nil
+	case goexp.Sub:
+		return "-" + value, nil
+	default:
+		return nil, UnsupportedError{e, "unknown operator"}
+	}
+}
+
+var operators = map[goexp.TokenType]string{
+	goexp.And:          "AND",
+	goexp.Or:           "OR",
+	goexp.Equal:        "=",
+	goexp.NotEqual:     "<>",
+	goexp.Less:         "<",
+	goexp.LessEqual:    "<=",
+	goexp.Greater:      ">",
+	goexp.GreaterEqual: ">=",
+	goexp.Add:          "+",
+	goexp.Sub:          "-",
+	goexp.Mul:          "*",
+	goexp.Div:          "/",
+	goexp.Modulo:       "%",
+}
+
+func (v *Visitor) VisitBinaryExpr(e goexp.BinaryExpr, context goexp.VisitorContext) (interface{}, error) {
+	switch e.Operator.Type {
+	case goexp.Equal, goexp.NotEqual:
+		if s, ok, err := v.nullCheck(e); ok || err != nil {
+			return s, err
+		}
+	}
+
+	left, err := v.operand(e.Left)
+	if err != nil {
+		return nil, err
+	}
+	right, err := v.operand(e.Right)
+	if err != nil {
+		return nil, err
+	}
+
+	switch e.Operator.Type {
+	case goexp.Add:
+		if isString(e.Left) || isString(e.Right) {
+			return v.Dialect.Concat(left, right), nil
+		}
+	case goexp.Power:
+		return v.Dialect.Power(left, right)
+	}
+
+	op, ok := operators[e.Operator.Type]
+	if !ok {
+		return nil, UnsupportedError{e, "unknown operator"}
+	}
+	return fmt.Sprintf("%s %s %s", left, op, right), nil
+}
+
+// nullCheck translates comparisons with nil to IS [NOT] NULL
+func (v *Visitor) nullCheck(e goexp.BinaryExpr) (string, bool, error) {
+	var operand goexp.Expr
+	if _, ok := ungroup(e.Right).(goexp.NilLiteralExpr); ok {
+		operand = e.Left
+	} else if _, ok := ungroup(e.Left).(goexp.NilLiteralExpr); ok {
+		operand = e.Right
+	} else {
+		return "", false, nil
+	}
+
+	s, err := v.operand(operand)
+	if err != nil {
+		return "", true, err
+	}
+	if e.Operator.Type == goexp.Equal {
+		return s + " IS NULL", true, nil
+	}
+	return s + " IS NOT NULL", true, nil
+}
+
+func (v *Visitor) VisitCallExpr(e goexp.CallExpr, context goexp.VisitorContext) (interface{}, error) {
+	id, ok := e.Name.(goexp.IdentifierExpr)
+	if !ok || id.Expr != nil {
+		return nil, UnsupportedError{e, "only calls to functions are supported"}
+	}
+	fn, ok := v.Functions[id.Name]
+	if !ok {
+		return nil, UnsupportedError{e, fmt.Sprintf("unknown function %s", id.Name)}
+	}
+
+	args := make([]string, len(e.Args))
+	for i, arg := range e.Args {
+		var err error
+		if args[i], err = v.translate(arg); err != nil {
+			return nil, err
+		}
+	}
+	return fmt.Sprintf("%s(%s)", fn, strings.Join(args, ", ")), nil
+}
+
+// isString returns true if the expression is known to be a string, i.e. a string literal or a concatenation
+func isString(expr goexp.Expr) bool {
+	switch e := ungroup(expr).(type) {
+	case goexp.StringLiteralExpr:
+		return true
+	case goexp.BinaryExpr:
+		return e.Operator.Type == goexp.Add && (isString(e.Left) || isString(e.Right))
+	default:
+		return false
+	}
+}
+
+func ungroup(expr goexp.Expr) goexp.Expr {
+	for {
+		g, ok := expr.(goexp.GroupingExpr)
+		if !ok {
+			return expr
+		}
+		expr = g.Expr
+	}
+}
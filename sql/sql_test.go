@@ -0,0 +1,99 @@
+package sql
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/go-test/deep"
+	"github.com/svstanev/goexp"
+)
+
+func TestWhere(t *testing.T) {
+	tests := []struct {
+		expr    string
+		dialect Dialect
+		where   string
+		args    []interface{}
+	}{
+		{"age >= 18 && name != nil", PostgreSQL, `("age" >= $1) AND ("name" IS NOT NULL)`, []interface{}{int64(18)}},
+		{"nil == user.email", PostgreSQL, `"user"."email" IS NULL`, nil},
+		{"a == 1 || !(b < 2.5)", PostgreSQL, `("a" = $1) OR (NOT ("b" < $2))`, []interface{}{int64(1), 2.5}},
+		{"name + ' ' + surname == 'John Doe'", PostgreSQL, `(("name" || $1) || "surname") = $2`, []interface{}{" ", "John Doe"}},
+		{"name + ' ' == 'x'", MySQL, "(CONCAT(`name`, ?)) = ?", []interface{}{" ", "x"}},
+		{"a + b * 2 != -c", MySQL, "(`a` + (`b` * ?)) <> (-`c`)", []interface{}{int64(2)}},
+		{"a % 2 == 0 && flag == true", SQLite, `(("a" % ?1) = ?2) AND ("flag" = ?3)`, []interface{}{int64(2), int64(0), true}},
+		{"x ** 2 > 4", PostgreSQL, `(POWER("x", $1)) > $2`, []interface{}{int64(2), int64(4)}},
+		{"lower(name) == 'bob'", PostgreSQL, `LOWER("name") = $1`, []interface{}{"bob"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.expr, func(t *testing.T) {
+			expr, err := goexp.Parse(test.expr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			v := NewVisitor(test.dialect, nil)
+			v.Functions = map[string]string{"lower": "LOWER"}
+			where, args, err := v.Where(expr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if where != test.where {
+				t.Errorf("Expected %s but got %s", test.where, where)
+			}
+			if diff := deep.Equal(args, test.args); diff != nil {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestWhereColumnMapper(t *testing.T) {
+	columns := func(path []string) (string, error) {
+		if len(path) == 2 && path[0] == "user" {
+			return "u." + path[1], nil
+		}
+		return "", fmt.Errorf("Unknown field %s", strings.Join(path, "."))
+	}
+
+	expr, _ := goexp.Parse("user.age > 18")
+	where, _, err := Where(expr, PostgreSQL, columns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "u.age > $1"; where != expected {
+		t.Errorf("Expected %s but got %s", expected, where)
+	}
+
+	expr, _ = goexp.Parse("secret == 1")
+	if _, _, err := Where(expr, PostgreSQL, columns); err == nil || err.Error() != "Unknown field secret" {
+		t.Errorf("Expected the mapper error but got %v", err)
+	}
+}
+
+func TestWhereUnsupported(t *testing.T) {
+	tests := []struct {
+		expr    string
+		dialect Dialect
+		err     string
+	}{
+		{"max(a, b) > 1", PostgreSQL, "Cannot translate max(a, b) to SQL: unknown function max"},
+		{"a.b() > 1", PostgreSQL, "Cannot translate a.b() to SQL: only calls to functions are supported"},
+		{"f(a).b > 1", PostgreSQL, "Cannot translate f(a).b to SQL: member access is supported only on names"},
+		{"`${a}` == 'x'", PostgreSQL, "Cannot translate `${a}` to SQL: template strings are not supported"},
+		{"a ** 2 > 1", SQLite, "The ** operator is not supported by SQLite"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.expr, func(t *testing.T) {
+			expr, err := goexp.Parse(test.expr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, _, err := Where(expr, test.dialect, nil); err == nil || err.Error() != test.err {
+				t.Errorf(`Expected "%s" error but got "%v"`, test.err, err)
+			}
+		})
+	}
+}
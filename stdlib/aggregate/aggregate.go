@@ -0,0 +1,331 @@
+/*
+Package aggregate is an opt-in library of aggregate functions over lists for
+goexp expressions.
+
+Install adds the following methods to a context:
+
+	sum(list[, fn])  avg(list[, fn])  stddev(list[, fn])
+	percentile(list, p[, fn])  median(list[, fn])
+	count(list[, pred])  distinct(list[, fn])  groupBy(list, fn)
+	minBy(list, fn)  maxBy(list, fn)
+
+A list is any Go slice or array, e.g. a []float64 or a []goexp.Context, and
+the member of a list is the list of the members of its elements, so the prices
+of a list of items are items.price. The optional fn is a lambda applied to the
+elements before they are aggregated:
+
+	sum(items, i => i.price * i.quantity)
+	count(orders, o => o.paid)
+
+Go numbers, strings, booleans and times are converted to the corresponding
+values of the types package. sum returns an Integer if all values are
+Integers and a Float otherwise, like the arithmetic operators; avg, stddev (the
+standard deviation of the population), percentile and median always return a
+Float. Nil values are skipped by the numeric functions and by minBy and maxBy.
+
+Aggregating an empty list is well defined: sum and count are 0, distinct and
+groupBy are empty lists and the others are nil.
+
+sum and avg accept numbers as well as lists, e.g. sum(1, 2, 3). They are the
+sum and avg of the math package, so both packages may be installed in the same
+context: the second one does not add them again.
+*/
+package aggregate
+
+import (
+	"fmt"
+	gomath "math"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/svstanev/goexp"
+	"github.com/svstanev/goexp/internal/stdlib"
+	"github.com/svstanev/goexp/types"
+)
+
+var (
+	anyType = types.AnyType
+	list    = types.ListOf(types.AnyType)
+	float   = types.FloatType
+	group   = types.ObjectOf(map[string]*types.Type{"key": anyType, "items": list}, nil)
+)
+
+var functions = []stdlib.Function{
+	stdlib.Sum,
+	stdlib.Avg,
+	{Name: "stddev", Fn: stddev, Sig: stdlib.Optional(float, list, anyType)},
+	{Name: "percentile", Fn: percentile, Sig: stdlib.Optional(float, list, types.NumberType, anyType)},
+	{Name: "median", Fn: median, Sig: stdlib.Optional(float, list, anyType)},
+	{Name: "count", Fn: count, Sig: stdlib.Optional(types.IntegerType, list, anyType)},
+	{Name: "distinct", Fn: distinct, Sig: stdlib.Optional(list, list, anyType)},
+	{Name: "groupBy", Fn: groupBy, Sig: stdlib.Signature(types.ListOf(group), list, anyType)},
+	{Name: "minBy", Fn: by("minBy", -1), Sig: stdlib.Signature(anyType, list, anyType)},
+	{Name: "maxBy", Fn: by("maxBy", 1), Sig: stdlib.Signature(anyType, list, anyType)},
+}
+
+// Install adds the methods of the package to the context; it fails if any of
+// their names is already defined by the context, except for sum and avg,
+// which are not added again
+func Install(ctx goexp.EvalContext) error {
+	return stdlib.Install(ctx, functions)
+}
+
+// Schema returns the signatures of the methods added by Install
+func Schema() *types.Schema {
+	return stdlib.Schema(functions)
+}
+
+// stddev returns the standard deviation of the population of the numbers of a list
+func stddev(items interface{}, fn ...interface{}) (interface{}, error) {
+	values, err := stdlib.Collect("stddev", 2, items, fn)
+	if err != nil {
+		return nil, err
+	}
+	nums, _, err := stdlib.Numbers("stddev", values)
+	if err != nil {
+		return nil, err
+	}
+	if len(nums) == 0 {
+		return types.Null(), nil
+	}
+	xs := stdlib.Floats(nums)
+	m := stdlib.Mean(xs)
+	var v float64
+	for _, x := range xs {
+		v += (x - m) * (x - m)
+	}
+	return stdlib.Result("stddev", gomath.Sqrt(v/float64(len(xs))))
+}
+
+// percentile returns the p-th percentile (0 <= p <= 100) of the numbers of a
+// list, interpolating linearly between the closest ranks
+func percentile(items, p interface{}, fn ...interface{}) (interface{}, error) {
+	var rank float64
+	switch v := p.(type) {
+	case types.Integer:
+		rank = float64(v)
+	case types.Float:
+		rank = float64(v)
+	default:
+		return nil, fmt.Errorf("Invalid argument for percentile: expected a number but got %T", p)
+	}
+	if !(rank >= 0 && rank <= 100) {
+		return nil, fmt.Errorf("Invalid argument for percentile: expected a percentage between 0 and 100 but got %v", rank)
+	}
+	return quantile("percentile", 3, items, rank/100, fn)
+}
+
+// median returns the middle value of the numbers of a list
+func median(items interface{}, fn ...interface{}) (interface{}, error) {
+	return quantile("median", 2, items, 0.5, fn)
+}
+
+func quantile(name string, params int, items interface{}, q float64, fn []interface{}) (interface{}, error) {
+	values, err := stdlib.Collect(name, params, items, fn)
+	if err != nil {
+		return nil, err
+	}
+	nums, _, err := stdlib.Numbers(name, values)
+	if err != nil {
+		return nil, err
+	}
+	if len(nums) == 0 {
+		return types.Null(), nil
+	}
+	xs := stdlib.Floats(nums)
+	sort.Float64s(xs)
+	pos := q * float64(len(xs)-1)
+	lo := int(gomath.Floor(pos))
+	if lo == len(xs)-1 {
+		return stdlib.Result(name, xs[lo])
+	}
+	return stdlib.Result(name, xs[lo]+(pos-float64(lo))*(xs[lo+1]-xs[lo]))
+}
+
+// count returns the number of elements of a list, or the number of elements the predicate is true for
+func count(items interface{}, pred ...interface{}) (interface{}, error) {
+	elems, err := stdlib.Elements("count", items)
+	if err != nil {
+		return nil, err
+	}
+	if len(pred) == 0 {
+		return types.Integer(len(elems)), nil
+	}
+	if len(pred) > 1 {
+		return nil, stdlib.TooManyArguments(2, len(pred)+1)
+	}
+	f, err := stdlib.Lambda("count", pred[0])
+	if err != nil {
+		return nil, err
+	}
+	n := 0
+	for _, elem := range elems {
+		res, err := f.Call(elem)
+		if err != nil {
+			return nil, err
+		}
+		b, ok := types.FromGo(res).(types.Boolean)
+		if !ok {
+			return nil, fmt.Errorf("Invalid result of the predicate of count: expected a Boolean but got %T", res)
+		}
+		if b {
+			n++
+		}
+	}
+	return types.Integer(n), nil
+}
+
+// distinct returns the distinct values of a list in the order of their first
+// appearance; numbers are equal if their values are, e.g. 1 and 1.0
+func distinct(items interface{}, fn ...interface{}) (interface{}, error) {
+	values, err := stdlib.Collect("distinct", 2, items, fn)
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[interface{}]bool)
+	res := make([]interface{}, 0)
+	for _, v := range values {
+		k, err := key("distinct", v)
+		if err != nil {
+			return nil, err
+		}
+		if !seen[k] {
+			seen[k] = true
+			res = append(res, v)
+		}
+	}
+	return res, nil
+}
+
+// groupBy returns the groups of the elements of a list with equal fn(element)
+// in the order of their first appearance. Every group is a context with the
+// names key and items, e.g. groupBy(items, i => i.category).key.
+func groupBy(items, fn interface{}) (interface{}, error) {
+	elems, err := stdlib.Elements("groupBy", items)
+	if err != nil {
+		return nil, err
+	}
+	f, err := stdlib.Lambda("groupBy", fn)
+	if err != nil {
+		return nil, err
+	}
+	type group struct {
+		key   interface{}
+		items []interface{}
+	}
+	var groups []*group
+	index := make(map[interface{}]*group)
+	for _, elem := range elems {
+		res, err := f.Call(elem)
+		if err != nil {
+			return nil, err
+		}
+		v := types.FromGo(res)
+		k, err := key("groupBy", v)
+		if err != nil {
+			return nil, err
+		}
+		g, ok := index[k]
+		if !ok {
+			g = &group{key: v}
+			index[k] = g
+			groups = append(groups, g)
+		}
+		g.items = append(g.items, elem)
+	}
+
+	res := make([]interface{}, len(groups))
+	for i, g := range groups {
+		ctx := goexp.NewEvalContext(nil)
+		ctx.AddName("key", g.key)
+		ctx.AddName("items", g.items)
+		res[i] = ctx
+	}
+	return res, nil
+}
+
+// by returns a method returning the first element of a list with the least
+// (sign -1) or the greatest (sign 1) fn(element)
+func by(name string, sign int) func(items, fn interface{}) (interface{}, error) {
+	return func(items, fn interface{}) (interface{}, error) {
+		elems, err := stdlib.Elements(name, items)
+		if err != nil {
+			return nil, err
+		}
+		f, err := stdlib.Lambda(name, fn)
+		if err != nil {
+			return nil, err
+		}
+		var best, bestKey interface{} = types.Null(), nil
+		for _, elem := range elems {
+			res, err := f.Call(elem)
+			if err != nil {
+				return nil, err
+			}
+			k := types.FromGo(res)
+			if types.IsNull(k) {
+				continue
+			}
+			if bestKey != nil {
+				c, ok := k.(types.Comparer)
+				if !ok {
+					return nil, fmt.Errorf("Invalid argument for %s: %T cannot be ordered", name, k)
+				}
+				n, err := c.Compare(bestKey)
+				if err != nil {
+					return nil, fmt.Errorf("Invalid argument for %s: cannot compare %T and %T", name, k, bestKey)
+				}
+				if n*sign <= 0 {
+					continue
+				}
+			}
+			best, bestKey = elem, k
+		}
+		return best, nil
+	}
+}
+
+// key returns a comparable key of the value that is equal for equal values
+func key(name string, v interface{}) (interface{}, error) {
+	switch x := v.(type) {
+	case types.Float:
+		if f := float64(x); f == gomath.Trunc(f) && f >= -(1<<63) && f < 1<<63 {
+			return types.Integer(f), nil
+		}
+	case types.Date:
+		t := time.Time(x)
+		return [2]int64{t.Unix(), int64(t.Nanosecond())}, nil
+	}
+	if !comparable(reflect.ValueOf(v)) {
+		return nil, fmt.Errorf("Invalid argument for %s: %T cannot be compared", name, v)
+	}
+	return v, nil
+}
+
+// comparable reports whether the value can be a map key. Unlike
+// reflect.Type.Comparable it checks the dynamic values of the interfaces in
+// structs and arrays, which panic when they hold slices, maps or functions.
+func comparable(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Invalid:
+		return true
+	case reflect.Interface:
+		return v.IsNil() || comparable(v.Elem())
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if !comparable(v.Field(i)) {
+				return false
+			}
+		}
+		return true
+	case reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if !comparable(v.Index(i)) {
+				return false
+			}
+		}
+		return true
+	}
+	return v.Type().Comparable()
+}
@@ -0,0 +1,249 @@
+package aggregate
+
+import (
+	gomath "math"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/svstanev/goexp"
+	"github.com/svstanev/goexp/stdlib/math"
+	"github.com/svstanev/goexp/types"
+)
+
+func item(price interface{}, quantity int, category string, paid bool) goexp.Context {
+	ctx := goexp.NewEvalContext(nil)
+	ctx.AddName("price", price)
+	ctx.AddName("quantity", types.Integer(quantity))
+	ctx.AddName("category", types.String(category))
+	ctx.AddName("paid", types.Boolean(paid))
+	return ctx
+}
+
+// box is comparable by its type but panics as a map key when v holds a slice
+type box struct {
+	v interface{}
+}
+
+func newContext(t *testing.T) goexp.EvalContext {
+	ctx := goexp.NewEvalContext(nil)
+	if err := Install(ctx); err != nil {
+		t.Fatal(err)
+	}
+	ctx.AddName("items", []goexp.Context{
+		item(types.Integer(10), 2, "book", true),
+		item(types.Float(2.5), 4, "pen", false),
+		item(types.Integer(20), 1, "book", true),
+	})
+	ctx.AddName("scores", []int{90, 75, 60, 100})
+	ctx.AddName("samples", []float64{2, 4, 4, 4, 5, 5, 7, 9})
+	ctx.AddName("tags", []string{"a", "b", "a", "c", "b"})
+	ctx.AddName("mixed", []interface{}{1, 1.0, uint8(2), nil, nil})
+	ctx.AddName("dates", [2]time.Time{time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)})
+	ctx.AddName("empty", []float64{})
+	ctx.AddName("nested", [][]int{{1}, {1}})
+	ctx.AddName("boxed", []box{{1}, {[]int{1}}})
+	ctx.AddName("boxes", []box{{1}, {"a"}, {1}})
+	ctx.AddName("unordered", []interface{}{"a", 1})
+	ctx.AddName("big", []int64{gomath.MaxInt64, 1})
+	ctx.AddName("threshold", types.Integer(80))
+	return ctx
+}
+
+func TestAggregate(t *testing.T) {
+	tests := []struct {
+		expr     string
+		expected interface{}
+	}{
+		{"sum(items.price)", types.Float(32.5)},
+		{"sum(items, i => i.price * i.quantity)", types.Float(50)},
+		{"sum(scores)", types.Integer(325)},
+		{"scores.sum()", types.Integer(325)},
+		{"sum(mixed)", types.Float(4)},
+		{"sum(1, 2, 3)", types.Integer(6)},
+		{"sum(1, 2.5)", types.Float(3.5)},
+		{"sum(items, i => count(items, j => j.price < i.price))", types.Integer(3)},
+		{"avg(scores)", types.Float(81.25)},
+		{"avg(1, 2)", types.Float(1.5)},
+		{"avg(2.5)", types.Float(2.5)},
+		{"avg(items, i => i.quantity)", types.Float(7.0 / 3)},
+		{"stddev(samples)", types.Float(2)},
+		{"stddev(scores, s => 1)", types.Float(0)},
+		{"percentile(scores, 50)", types.Float(82.5)},
+		{"percentile(scores, 0)", types.Float(60)},
+		{"percentile(scores, 100)", types.Float(100)},
+		{"percentile(scores, 90)", types.Float(97)},
+		{"percentile(items, 50, i => i.quantity)", types.Float(2)},
+		{"median(scores)", types.Float(82.5)},
+		{"median(samples)", types.Float(4.5)},
+
+		{"count(items)", types.Integer(3)},
+		{"count(items, i => i.paid)", types.Integer(2)},
+		{"count(scores, s => s > threshold)", types.Integer(2)},
+		{"distinct(tags)", []interface{}{types.String("a"), types.String("b"), types.String("c")}},
+		{"distinct(mixed)", []interface{}{types.Integer(1), types.Integer(2), types.Null()}},
+		{"distinct(items, i => i.category)", []interface{}{types.String("book"), types.String("pen")}},
+		{"groupBy(items, i => i.category).key", []interface{}{types.String("book"), types.String("pen")}},
+		{"count(groupBy(scores, s => s >= threshold))", types.Integer(2)},
+		{"groupBy(items, i => i.category).items.price", []interface{}{
+			[]interface{}{types.Integer(10), types.Integer(20)},
+			[]interface{}{types.Float(2.5)},
+		}},
+		{"minBy(items, i => i.price).category", types.String("pen")},
+		{"maxBy(items, i => i.price).price", types.Integer(20)},
+		{"maxBy(items, i => i.category).price", types.Float(2.5)},
+		{"minBy(items, i => i.category).price", types.Integer(10)},
+		{"minBy(dates, d => d)", types.Date(time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC))},
+		{"maxBy(mixed, x => x)", types.Integer(2)},
+
+		{"sum(empty)", types.Integer(0)},
+		{"sum()", types.Integer(0)},
+		{"count(empty)", types.Integer(0)},
+		{"avg(empty)", types.Null()},
+		{"stddev(empty)", types.Null()},
+		{"median(empty)", types.Null()},
+		{"percentile(empty, 10)", types.Null()},
+		{"distinct(empty)", []interface{}{}},
+		{"groupBy(empty, x => x)", []interface{}{}},
+		{"distinct(boxes)", []interface{}{box{1}, box{"a"}}},
+		{"minBy(empty, x => x)", types.Null()},
+		{"maxBy(mixed, x => nil)", types.Null()},
+	}
+
+	ctx := newContext(t)
+	for _, test := range tests {
+		t.Run(test.expr, func(t *testing.T) {
+			res, err := goexp.EvalString(test.expr, ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(res, test.expected) {
+				t.Errorf("Expected %#v but got %#v", test.expected, res)
+			}
+		})
+	}
+}
+
+func TestAggregateErrors(t *testing.T) {
+	tests := []struct {
+		expr string
+		err  string
+	}{
+		{"sum(tags)", "Invalid argument for sum: expected a number but got types.String"},
+		{"sum(1, 'a')", "Invalid argument for sum: expected a number but got types.String"},
+		{"sum(big)", "Integer overflow in sum"},
+		{"sum(1, true)", "Invalid argument for sum: expected a number but got types.Boolean"},
+		{"sum(scores, s => s, 1)", "Invalid number of arguments: expected at most 2 but got 3"},
+		{"avg(scores, s => 0.0 / 0)", "Invalid argument for avg: NaN"},
+		{"percentile(scores, 101)", "Invalid argument for percentile: expected a percentage between 0 and 100 but got 101"},
+		{"percentile(scores, '1')", "Invalid argument for percentile: expected a number but got types.String"},
+		{"percentile(scores, 1, s => s, 2)", "Invalid number of arguments: expected at most 3 but got 4"},
+		{"count(1)", "Invalid argument for count: expected a list but got types.Integer"},
+		{"count(items, 1)", "Invalid argument for count: expected a lambda but got types.Integer"},
+		{"count(items, i => i.price)", "Invalid result of the predicate of count: expected a Boolean but got types.Integer"},
+		{"count(items, i => i.missing)", "missing not defined"},
+		{"distinct(nested)", "Invalid argument for distinct: []int cannot be compared"},
+		{"distinct(boxed)", "Invalid argument for distinct: aggregate.box cannot be compared"},
+		{"groupBy(boxed, b => b)", "Invalid argument for groupBy: aggregate.box cannot be compared"},
+		{"groupBy(items)", "Invalid number of arguments: expected 2 but got 1"},
+		{"maxBy(unordered, x => x)", "Invalid argument for maxBy: cannot compare types.Integer and types.String"},
+	}
+
+	ctx := newContext(t)
+	for _, test := range tests {
+		t.Run(test.expr, func(t *testing.T) {
+			res, err := goexp.EvalString(test.expr, ctx)
+			if err == nil {
+				t.Fatalf("Expected error %q but got %v", test.err, res)
+			}
+			if err.Error() != test.err {
+				t.Errorf("Expected error %q but got %q", test.err, err)
+			}
+		})
+	}
+}
+
+func TestSchema(t *testing.T) {
+	schema := Schema()
+	item := types.ObjectOf(map[string]*types.Type{"price": types.NumberType, "category": types.StringType}, nil)
+	schema.Names = map[string]*types.Type{"items": types.ListOf(item), "name": types.StringType}
+
+	tests := []struct {
+		expr     string
+		expected string
+		err      string
+	}{
+		{"sum(items.price) * 2", "Number", ""},
+		{"avg(items, i => i.price) > 1", "Boolean", ""},
+		{"count(items, i => i.price > 10) + 1", "Integer", ""},
+		{"groupBy(items, i => i.category).key", "List<Any>", ""},
+		{"percentile(items.price, 90)", "Float", ""},
+		{"count(name)", "Integer", "Type Error: Cannot use String as List<Any> in argument 1 of count"},
+		{"minBy(items)", "Any", "Type Error: Invalid number of arguments for minBy: expected 2 but got 1"},
+	}
+
+	for _, test := range tests {
+		expr, err := goexp.Parse(test.expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res, err := goexp.Check(expr, schema)
+		if res.String() != test.expected {
+			t.Errorf("%s: expected %s but got %s", test.expr, test.expected, res)
+		}
+		if s := errString(err); s != test.err {
+			t.Errorf("%s: expected error %q but got %q", test.expr, test.err, s)
+		}
+	}
+}
+
+// TestInstallWithMath checks that the math package, which adds the same sum
+// and avg, can be installed in the same context in either order
+func TestInstallWithMath(t *testing.T) {
+	for _, mathFirst := range []bool{true, false} {
+		ctx := goexp.NewEvalContext(nil)
+		ctx.AddName("scores", []float64{81.25})
+		installs := []func(goexp.EvalContext) error{math.Install, Install}
+		if !mathFirst {
+			installs[0], installs[1] = installs[1], installs[0]
+		}
+		for _, install := range installs {
+			if err := install(ctx); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := Install(ctx); err == nil {
+			t.Error("Expected an error installing the methods twice")
+		}
+		res, err := goexp.EvalString("round(avg(scores), 1) + sum(2, 3)", ctx)
+		if err != nil || res != types.Float(86.3) {
+			t.Errorf("Expected 86.3 but got %v, %v", res, err)
+		}
+	}
+}
+
+// TestInstallShared checks that sum and avg defined by the context are not
+// replaced, while the other names still may not be redefined
+func TestInstallShared(t *testing.T) {
+	ctx := goexp.NewEvalContext(nil)
+	ctx.AddMethod("sum", func() types.Integer { return 42 })
+	if err := Install(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if res, err := goexp.EvalString("sum()", ctx); err != nil || res != types.Integer(42) {
+		t.Errorf("Expected 42 but got %v, %v", res, err)
+	}
+
+	child := goexp.NewEvalContext(ctx)
+	child.AddMethod("count", func() types.Integer { return 0 })
+	if err := Install(child); err == nil {
+		t.Error("Expected an error installing count again")
+	}
+}
+
+func errString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
@@ -0,0 +1,367 @@
+/*
+Package math is an opt-in library of math functions for goexp expressions.
+
+Install adds the constants pi and e and the following methods to a context:
+
+	abs(x)  floor(x)  ceil(x)  round(x, digits = 0)  sign(x)
+	min(x, ...)  max(x, ...)  clamp(x, lo, hi)  sum(...)  avg(...)
+	sqrt(x)  log(x)  log10(x)  exp(x)
+	sin(x)  cos(x)  tan(x)  asin(x)  acos(x)  atan(x)  atan2(y, x)
+
+The arguments may be Integer or Float values. The functions that do not need
+to change the type of their arguments (abs, floor, ceil, round, sign, min, max,
+clamp and sum) return an Integer if all arguments are Integers and a Float
+otherwise, like the arithmetic operators; the others always return a Float.
+NaN arguments, arguments outside of the domain of a function and results that
+are not finite are reported as errors. The digits of round may be passed by
+name, e.g. round(price, digits: 2).
+
+sum and avg are shared with the aggregate package, so they also accept a list,
+e.g. sum(prices), and both packages may be installed in the same context: the
+second one does not add them again. The sum of no numbers is 0 and their avg
+is nil.
+*/
+package math
+
+import (
+	"fmt"
+	gomath "math"
+
+	"github.com/svstanev/goexp"
+	"github.com/svstanev/goexp/internal/stdlib"
+	"github.com/svstanev/goexp/types"
+)
+
+var constants = []struct {
+	name  string
+	value types.Float
+}{
+	{"pi", types.Float(gomath.Pi)},
+	{"e", types.Float(gomath.E)},
+}
+
+var (
+	number  = []*types.Type{types.NumberType}
+	numbers = []*types.Type{types.NumberType, types.NumberType}
+)
+
+// unary returns the signature of a method of a single number
+func unary(returns *types.Type) *types.Signature {
+	return &types.Signature{Params: number, Returns: returns}
+}
+
+// variadic returns the signature of a method of one or more numbers
+func variadic(returns *types.Type) *types.Signature {
+	return &types.Signature{Params: numbers, Variadic: true, Returns: returns}
+}
+
+// roundSignature is the signature of round, which is added with Define so that
+// digits may be passed by name, e.g. round(x, digits: 2)
+var roundSignature = &types.Signature{
+	Params:   []*types.Type{types.NumberType, types.IntegerType},
+	Names:    []string{"x", "digits"},
+	Optional: 1,
+	Returns:  types.NumberType,
+}
+
+var functions = []stdlib.Function{
+	{Name: "abs", Fn: abs, Sig: unary(types.NumberType)},
+	{Name: "floor", Fn: roundFunc("floor", gomath.Floor), Sig: unary(types.NumberType)},
+	{Name: "ceil", Fn: roundFunc("ceil", gomath.Ceil), Sig: unary(types.NumberType)},
+	{Name: "sign", Fn: sign, Sig: unary(types.IntegerType)},
+	{Name: "min", Fn: extremum("min", -1), Sig: variadic(types.NumberType)},
+	{Name: "max", Fn: extremum("max", 1), Sig: variadic(types.NumberType)},
+	{Name: "clamp", Fn: clamp, Sig: &types.Signature{Params: []*types.Type{types.NumberType, types.NumberType, types.NumberType}, Returns: types.NumberType}},
+	stdlib.Sum,
+	stdlib.Avg,
+	{Name: "sqrt", Fn: floatFunc("sqrt", gomath.Sqrt, nonNegative), Sig: unary(types.FloatType)},
+	{Name: "log", Fn: floatFunc("log", gomath.Log, positive), Sig: unary(types.FloatType)},
+	{Name: "log10", Fn: floatFunc("log10", gomath.Log10, positive), Sig: unary(types.FloatType)},
+	{Name: "exp", Fn: floatFunc("exp", gomath.Exp, nil), Sig: unary(types.FloatType)},
+	{Name: "sin", Fn: floatFunc("sin", gomath.Sin, finite), Sig: unary(types.FloatType)},
+	{Name: "cos", Fn: floatFunc("cos", gomath.Cos, finite), Sig: unary(types.FloatType)},
+	{Name: "tan", Fn: floatFunc("tan", gomath.Tan, finite), Sig: unary(types.FloatType)},
+	{Name: "asin", Fn: floatFunc("asin", gomath.Asin, unit), Sig: unary(types.FloatType)},
+	{Name: "acos", Fn: floatFunc("acos", gomath.Acos, unit), Sig: unary(types.FloatType)},
+	{Name: "atan", Fn: floatFunc("atan", gomath.Atan, nil), Sig: unary(types.FloatType)},
+	{Name: "atan2", Fn: atan2, Sig: &types.Signature{Params: numbers, Returns: types.FloatType}},
+}
+
+// Install adds the constants and the methods of the package to the context;
+// it fails if any of their names is already defined by the context, except
+// for sum and avg, which are not added again
+func Install(ctx goexp.EvalContext) error {
+	for _, c := range constants {
+		if err := ctx.AddName(c.name, c.value); err != nil {
+			return err
+		}
+	}
+	if err := stdlib.Install(ctx, functions); err != nil {
+		return err
+	}
+	return ctx.Define("round").
+		Param("x", types.NumberType).
+		Param("digits", types.IntegerType).Optional(0).
+		Returns(types.NumberType).
+		Doc("Rounds x half away from zero to the given number of decimal digits").
+		Pure().
+		Impl(round)
+}
+
+// Schema returns the types of the constants and the signatures of the methods added by Install
+func Schema() *types.Schema {
+	s := stdlib.Schema(functions)
+	s.Names = map[string]*types.Type{}
+	for _, c := range constants {
+		s.Names[c.name] = types.FloatType
+	}
+	s.Methods["round"] = roundSignature
+	return s
+}
+
+// domain describes the arguments a function is defined for
+type domain struct {
+	contains func(x float64) bool
+	desc     string
+}
+
+var (
+	positive    = &domain{func(x float64) bool { return x > 0 }, "a positive number"}
+	nonNegative = &domain{func(x float64) bool { return x >= 0 }, "a non-negative number"}
+	unit        = &domain{func(x float64) bool { return x >= -1 && x <= 1 }, "a number between -1 and 1"}
+	finite      = &domain{func(x float64) bool { return !gomath.IsInf(x, 0) }, "a finite number"}
+)
+
+// floatFunc returns a method applying f to its argument as a float64
+func floatFunc(name string, f func(float64) float64, d *domain) func(x interface{}) (interface{}, error) {
+	return func(x interface{}) (interface{}, error) {
+		v, err := toFloat(name, x)
+		if err != nil {
+			return nil, err
+		}
+		if d != nil && !d.contains(v) {
+			return nil, fmt.Errorf("Invalid argument for %s: expected %s but got %v", name, d.desc, v)
+		}
+		return stdlib.Result(name, f(v))
+	}
+}
+
+// roundFunc returns a method rounding Floats with f; Integers are returned unchanged
+func roundFunc(name string, f func(float64) float64) func(x interface{}) (interface{}, error) {
+	return func(x interface{}) (interface{}, error) {
+		if n, ok := x.(types.Integer); ok {
+			return n, nil
+		}
+		v, err := toFloat(name, x)
+		if err != nil {
+			return nil, err
+		}
+		return stdlib.Result(name, f(v))
+	}
+}
+
+func abs(x interface{}) (interface{}, error) {
+	if n, ok := x.(types.Integer); ok {
+		if n == gomath.MinInt64 {
+			return nil, fmt.Errorf("Integer overflow in abs")
+		}
+		if n < 0 {
+			return -n, nil
+		}
+		return n, nil
+	}
+	v, err := toFloat("abs", x)
+	if err != nil {
+		return nil, err
+	}
+	return types.Float(gomath.Abs(v)), nil
+}
+
+func sign(x interface{}) (interface{}, error) {
+	v, err := toFloat("sign", x)
+	switch {
+	case err != nil:
+		return nil, err
+	case v > 0:
+		return types.Integer(1), nil
+	case v < 0:
+		return types.Integer(-1), nil
+	default:
+		return types.Integer(0), nil
+	}
+}
+
+/*
+round rounds x half away from zero to the given number of decimal digits (0 by
+default). Negative digits round to tens, hundreds and so on, which is the only
+rounding Integers need.
+*/
+func round(x interface{}, digits types.Integer) (interface{}, error) {
+	d := int64(digits)
+	if n, ok := x.(types.Integer); ok {
+		return roundInteger(n, d)
+	}
+	v, err := toFloat("round", x)
+	if err != nil {
+		return nil, err
+	}
+	if d == 0 {
+		return stdlib.Result("round", gomath.Round(v))
+	}
+	p := gomath.Pow(10, float64(d))
+	if gomath.IsInf(p, 0) || p == 0 || gomath.IsInf(v*p, 0) {
+		// more digits than a float64 has, or fewer than the integral part has
+		if d > 0 {
+			return types.Float(v), nil
+		}
+		return types.Float(0), nil
+	}
+	return stdlib.Result("round", gomath.Round(v*p)/p)
+}
+
+func roundInteger(n types.Integer, digits int64) (interface{}, error) {
+	if digits >= 0 {
+		return n, nil
+	}
+	if digits < -18 {
+		return types.Integer(0), nil
+	}
+	p := int64(1)
+	for i := int64(0); i < -digits; i++ {
+		p *= 10
+	}
+	q, r := int64(n)/p, int64(n)%p
+	if r >= p-r {
+		q++
+	} else if -r >= p+r {
+		q--
+	}
+	if q > gomath.MaxInt64/p || q < gomath.MinInt64/p {
+		return nil, fmt.Errorf("Integer overflow in round")
+	}
+	return types.Integer(q * p), nil
+}
+
+// extremum returns a method returning the argument that compares to all others as dir, i.e. -1 for min and 1 for max
+func extremum(name string, dir int) func(x interface{}, xs ...interface{}) (interface{}, error) {
+	return func(x interface{}, xs ...interface{}) (interface{}, error) {
+		args := append([]interface{}{x}, xs...)
+		nums, err := toNumbers(name, args)
+		if err != nil {
+			return nil, err
+		}
+		res := nums[0]
+		for _, n := range nums[1:] {
+			if n.compare(res) == dir {
+				res = n
+			}
+		}
+		return res.value(), nil
+	}
+}
+
+func clamp(x, lo, hi interface{}) (interface{}, error) {
+	nums, err := toNumbers("clamp", []interface{}{x, lo, hi})
+	if err != nil {
+		return nil, err
+	}
+	if nums[1].compare(nums[2]) > 0 {
+		return nil, fmt.Errorf("Invalid arguments for clamp: lower bound %v is greater than upper bound %v", nums[1], nums[2])
+	}
+	res := nums[0]
+	if res.compare(nums[1]) < 0 {
+		res = nums[1]
+	} else if res.compare(nums[2]) > 0 {
+		res = nums[2]
+	}
+	return res.value(), nil
+}
+
+func atan2(y, x interface{}) (interface{}, error) {
+	fy, err := toFloat("atan2", y)
+	if err != nil {
+		return nil, err
+	}
+	fx, err := toFloat("atan2", x)
+	if err != nil {
+		return nil, err
+	}
+	return stdlib.Result("atan2", gomath.Atan2(fy, fx))
+}
+
+// num is an Integer or a Float argument; f is set for both
+type num struct {
+	isFloat bool
+	i       int64
+	f       float64
+}
+
+func (n num) compare(other num) int {
+	if !n.isFloat && !other.isFloat {
+		switch {
+		case n.i < other.i:
+			return -1
+		case n.i > other.i:
+			return 1
+		}
+		return 0
+	}
+	switch {
+	case n.f < other.f:
+		return -1
+	case n.f > other.f:
+		return 1
+	}
+	return 0
+}
+
+func (n num) String() string {
+	if n.isFloat {
+		return fmt.Sprint(n.f)
+	}
+	return fmt.Sprint(n.i)
+}
+
+func (n num) value() interface{} {
+	if n.isFloat {
+		return types.Float(n.f)
+	}
+	return types.Integer(n.i)
+}
+
+// toNumbers converts the arguments to nums; if any of them is a Float all are Floats
+func toNumbers(name string, args []interface{}) ([]num, error) {
+	nums := make([]num, len(args))
+	anyFloat := false
+	for i, arg := range args {
+		if n, ok := arg.(types.Integer); ok {
+			nums[i] = num{i: int64(n), f: float64(n)}
+			continue
+		}
+		f, err := toFloat(name, arg)
+		if err != nil {
+			return nil, err
+		}
+		nums[i] = num{isFloat: true, f: f}
+		anyFloat = true
+	}
+	if anyFloat {
+		for i := range nums {
+			nums[i].isFloat = true
+		}
+	}
+	return nums, nil
+}
+
+func toFloat(name string, x interface{}) (float64, error) {
+	switch v := x.(type) {
+	case types.Integer:
+		return float64(v), nil
+	case types.Float:
+		if gomath.IsNaN(float64(v)) {
+			return 0, fmt.Errorf("Invalid argument for %s: NaN", name)
+		}
+		return float64(v), nil
+	}
+	return 0, fmt.Errorf("Invalid argument for %s: expected a number but got %T", name, x)
+}
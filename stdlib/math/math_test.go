@@ -0,0 +1,210 @@
+package math
+
+import (
+	gomath "math"
+	"reflect"
+	"testing"
+
+	"github.com/svstanev/goexp"
+	"github.com/svstanev/goexp/types"
+)
+
+func newContext(t *testing.T) goexp.EvalContext {
+	ctx := goexp.NewEvalContext(nil)
+	if err := Install(ctx); err != nil {
+		t.Fatal(err)
+	}
+	ctx.AddName("big", types.Integer(gomath.MaxInt64))
+	ctx.AddName("small", types.Integer(gomath.MinInt64))
+	ctx.AddName("nan", types.Float(gomath.NaN()))
+	ctx.AddName("inf", types.Float(gomath.Inf(1)))
+	ctx.AddName("prices", []float64{1.5, 2.5})
+	return ctx
+}
+
+func TestMath(t *testing.T) {
+	tests := []struct {
+		expr     string
+		expected interface{}
+	}{
+		{"pi", types.Float(gomath.Pi)},
+		{"e", types.Float(gomath.E)},
+
+		{"abs(-3)", types.Integer(3)},
+		{"abs(3)", types.Integer(3)},
+		{"abs(-2.5)", types.Float(2.5)},
+		{"sign(-2.5)", types.Integer(-1)},
+		{"sign(0)", types.Integer(0)},
+		{"sign(7)", types.Integer(1)},
+
+		{"floor(2.7)", types.Float(2)},
+		{"floor(-2.2)", types.Float(-3)},
+		{"floor(5)", types.Integer(5)},
+		{"ceil(2.1)", types.Float(3)},
+		{"ceil(-2.7)", types.Float(-2)},
+		{"ceil(5)", types.Integer(5)},
+
+		{"round(2.5)", types.Float(3)},
+		{"round(-2.5)", types.Float(-3)},
+		{"round(2.345, 2)", types.Float(2.35)},
+		{"round(1234.5, -2)", types.Float(1200)},
+		{"round(1.5, 400)", types.Float(1.5)},
+		{"round(7)", types.Integer(7)},
+		{"round(7, 2)", types.Integer(7)},
+		{"round(1250, -2)", types.Integer(1300)},
+		{"round(1249, -2)", types.Integer(1200)},
+		{"round(-1250, -2)", types.Integer(-1300)},
+		{"round(-1249, -2)", types.Integer(-1200)},
+		{"round(1234, -19)", types.Integer(0)},
+
+		{"min(3, 1, 2)", types.Integer(1)},
+		{"min(3)", types.Integer(3)},
+		{"min(3, 1.5)", types.Float(1.5)},
+		{"min(1, 1.5)", types.Float(1)},
+		{"max(3, 1, 2)", types.Integer(3)},
+		{"max(big, 1)", types.Integer(gomath.MaxInt64)},
+		{"max(-1.5, -2)", types.Float(-1.5)},
+
+		{"clamp(5, 1, 3)", types.Integer(3)},
+		{"clamp(-5, 1, 3)", types.Integer(1)},
+		{"clamp(2, 1, 3)", types.Integer(2)},
+		{"clamp(2, 1.5, 3)", types.Float(2)},
+
+		{"sum()", types.Integer(0)},
+		{"sum(1, 2, 3)", types.Integer(6)},
+		{"sum(1, 2.5)", types.Float(3.5)},
+		{"sum(big, small)", types.Integer(-1)},
+		{"sum(prices)", types.Float(4)},
+		{"avg(1, 2)", types.Float(1.5)},
+		{"avg(big, big)", types.Float(gomath.MaxInt64)},
+		{"avg(2.5)", types.Float(2.5)},
+		{"avg()", types.Null()},
+
+		{"sqrt(16)", types.Float(4)},
+		{"sqrt(0)", types.Float(0)},
+		{"log(e)", types.Float(1)},
+		{"log10(1000)", types.Float(3)},
+		{"exp(0)", types.Float(1)},
+		{"sin(0)", types.Float(0)},
+		{"cos(0)", types.Float(1)},
+		{"tan(0)", types.Float(0)},
+		{"asin(1)", types.Float(gomath.Pi / 2)},
+		{"acos(1)", types.Float(0)},
+		{"atan(inf)", types.Float(gomath.Pi / 2)},
+		{"atan2(1, 1)", types.Float(gomath.Pi / 4)},
+		{"round(sin(pi / 6), 10)", types.Float(0.5)},
+		{"round(2.345, digits: 2)", types.Float(2.35)},
+		{"round(digits: -2, x: 1250)", types.Integer(1300)},
+	}
+
+	ctx := newContext(t)
+	for _, test := range tests {
+		t.Run(test.expr, func(t *testing.T) {
+			res, err := goexp.EvalString(test.expr, ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(res, test.expected) {
+				t.Errorf("Expected %#v but got %#v", test.expected, res)
+			}
+		})
+	}
+}
+
+func TestMathErrors(t *testing.T) {
+	tests := []struct {
+		expr string
+		err  string
+	}{
+		{"abs(small)", "Integer overflow in abs"},
+		{"abs('a')", "Invalid argument for abs: expected a number but got types.String"},
+		{"abs(nil)", "Invalid argument for abs: expected a number but got *types.NullType"},
+		{"floor(nan)", "Invalid argument for floor: NaN"},
+		{"floor(inf)", "Result of floor is not a finite number"},
+		{"round(1.5, 1.5)", "Cannot use Float as Integer in argument 2 of round"},
+		{"round(1.5, 1, 2)", "Invalid number of arguments for round: expected 1 to 2 but got 3"},
+		{"round(1.5, places: 2)", "Unknown argument places of round"},
+		{"round(digits: 2)", "Missing argument x of round"},
+		{"round(big, -1)", "Integer overflow in round"},
+		{"min()", "Invalid number of arguments: expected at least 1 but got 0"},
+		{"max(1, nan)", "Invalid argument for max: NaN"},
+		{"clamp(1, 3, 2)", "Invalid arguments for clamp: lower bound 3 is greater than upper bound 2"},
+		{"sum(big, 1)", "Integer overflow in sum"},
+		{"sum(small, -1)", "Integer overflow in sum"},
+		{"sum(1, true)", "Invalid argument for sum: expected a number but got types.Boolean"},
+		{"avg(1, nan)", "Invalid argument for avg: NaN"},
+		{"sqrt(-1)", "Invalid argument for sqrt: expected a non-negative number but got -1"},
+		{"log(0)", "Invalid argument for log: expected a positive number but got 0"},
+		{"log10(-2.5)", "Invalid argument for log10: expected a positive number but got -2.5"},
+		{"exp(1000)", "Result of exp is not a finite number"},
+		{"sin(inf)", "Invalid argument for sin: expected a finite number but got +Inf"},
+		{"asin(2)", "Invalid argument for asin: expected a number between -1 and 1 but got 2"},
+		{"acos(-1.5)", "Invalid argument for acos: expected a number between -1 and 1 but got -1.5"},
+		{"atan2(nan, 1)", "Invalid argument for atan2: NaN"},
+	}
+
+	ctx := newContext(t)
+	for _, test := range tests {
+		t.Run(test.expr, func(t *testing.T) {
+			res, err := goexp.EvalString(test.expr, ctx)
+			if err == nil {
+				t.Fatalf("Expected error %q but got %v", test.err, res)
+			}
+			if err.Error() != test.err {
+				t.Errorf("Expected error %q but got %q", test.err, err)
+			}
+		})
+	}
+}
+
+func TestInstall(t *testing.T) {
+	ctx := goexp.NewEvalContext(nil)
+	ctx.AddMethod("max", func() {})
+	if err := Install(ctx); err == nil || err.Error() != "Method max already exists" {
+		t.Errorf("Expected the existing method to be reported but got %v", err)
+	}
+}
+
+func TestOptimize(t *testing.T) {
+	ctx := newContext(t)
+	expr, _ := goexp.Parse("x * round(sqrt(2), 2)")
+	opt := goexp.OptimizeWith(expr, ctx)
+	if s, _ := goexp.Print(opt); s != "x * 1.41" {
+		t.Errorf("Expected the calls to be folded but got %s", s)
+	}
+}
+
+func TestSchema(t *testing.T) {
+	schema := Schema()
+	schema.Names["x"] = types.IntegerType
+
+	tests := []struct {
+		expr     string
+		expected string
+		err      string
+	}{
+		{"round(x * pi, 2)", "Number", ""},
+		{"sqrt(x) > 1", "Boolean", ""},
+		{"max(x)", "Number", ""},
+		{"min()", "Any", "Type Error: Invalid number of arguments for min: expected at least 1 but got 0"},
+		{"abs('a')", "Number", "Type Error: Cannot use String as Number in argument 1 of abs"},
+	}
+
+	for _, test := range tests {
+		expr, _ := goexp.Parse(test.expr)
+		res, err := goexp.Check(expr, schema)
+		if res.String() != test.expected {
+			t.Errorf("%s: expected %s but got %s", test.expr, test.expected, res)
+		}
+		if s := errString(err); s != test.err {
+			t.Errorf("%s: expected error %q but got %q", test.expr, test.err, s)
+		}
+	}
+}
+
+func errString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
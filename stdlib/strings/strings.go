@@ -0,0 +1,344 @@
+/*
+Package strings is an opt-in library of string functions for goexp expressions.
+
+Install adds the following methods to a context:
+
+	len(s)  upper(s)  lower(s)  trim(s[, cutset])  repeat(s, n)
+	startsWith(s, prefix)  endsWith(s, suffix)  contains(s, sub)  indexOf(s, sub)
+	replace(s, old, new)  split(s, sep)  join(list, sep)  substr(s, start[, length])
+	padLeft(s, width[, pad])  padRight(s, width[, pad])  format(layout, args...)
+	fold(s)  equalFold(a, b)  compare(a, b)  compareFold(a, b)
+
+Since x.m(args) is m(x, args) for values that are not contexts, all of them may
+be called as methods of strings as well, e.g. name.trim().upper().
+
+Lengths and positions are in runes, not bytes. fold returns the case folded
+string, which is the same for strings that differ only by case, and may be
+used as a key; equalFold and compareFold compare strings ignoring case.
+*/
+package strings
+
+import (
+	"fmt"
+	"reflect"
+	gostrings "strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/svstanev/goexp"
+	"github.com/svstanev/goexp/internal/stdlib"
+	"github.com/svstanev/goexp/types"
+)
+
+// maxLength is the maximum length (in runes) of the strings built by repeat, padLeft and padRight
+const maxLength = 1 << 20
+
+var (
+	str     = types.StringType
+	integer = types.IntegerType
+	boolean = types.BooleanType
+)
+
+var functions = []stdlib.Function{
+	{Name: "len", Fn: length, Sig: stdlib.Signature(integer, str)},
+	{Name: "upper", Fn: mapper("upper", gostrings.ToUpper), Sig: stdlib.Signature(str, str)},
+	{Name: "lower", Fn: mapper("lower", gostrings.ToLower), Sig: stdlib.Signature(str, str)},
+	{Name: "fold", Fn: mapper("fold", fold), Sig: stdlib.Signature(str, str)},
+	{Name: "trim", Fn: trim, Sig: stdlib.Optional(str, str, str)},
+	{Name: "repeat", Fn: repeat, Sig: stdlib.Signature(str, str, integer)},
+	{Name: "startsWith", Fn: predicate("startsWith", gostrings.HasPrefix), Sig: stdlib.Signature(boolean, str, str)},
+	{Name: "endsWith", Fn: predicate("endsWith", gostrings.HasSuffix), Sig: stdlib.Signature(boolean, str, str)},
+	{Name: "contains", Fn: predicate("contains", gostrings.Contains), Sig: stdlib.Signature(boolean, str, str)},
+	{Name: "equalFold", Fn: predicate("equalFold", gostrings.EqualFold), Sig: stdlib.Signature(boolean, str, str)},
+	{Name: "indexOf", Fn: indexOf, Sig: stdlib.Signature(integer, str, str)},
+	{Name: "compare", Fn: comparer("compare", gostrings.Compare), Sig: stdlib.Signature(integer, str, str)},
+	{Name: "compareFold", Fn: comparer("compareFold", func(a, b string) int { return gostrings.Compare(fold(a), fold(b)) }), Sig: stdlib.Signature(integer, str, str)},
+	{Name: "replace", Fn: replace, Sig: stdlib.Signature(str, str, str, str)},
+	{Name: "split", Fn: split, Sig: stdlib.Signature(types.ListOf(str), str, str)},
+	{Name: "join", Fn: join, Sig: stdlib.Signature(str, types.ListOf(str), str)},
+	{Name: "substr", Fn: substr, Sig: stdlib.Optional(str, str, integer, integer)},
+	{Name: "padLeft", Fn: pad("padLeft", true), Sig: stdlib.Optional(str, str, integer, str)},
+	{Name: "padRight", Fn: pad("padRight", false), Sig: stdlib.Optional(str, str, integer, str)},
+	{Name: "format", Fn: format, Sig: stdlib.Optional(str, str, types.AnyType)},
+}
+
+// Install adds the methods of the package to the context; it fails if any of
+// their names is already defined by the context
+func Install(ctx goexp.EvalContext) error {
+	return stdlib.Install(ctx, functions)
+}
+
+// Schema returns the signatures of the methods added by Install
+func Schema() *types.Schema {
+	return stdlib.Schema(functions)
+}
+
+// fold maps every rune to the lower case of its upper case, so that e.g. "Straße"
+// and "STRAßE", or the long s "ſ" and "S", fold to the same string
+func fold(s string) string {
+	return gostrings.Map(func(r rune) rune {
+		return unicode.ToLower(unicode.ToUpper(r))
+	}, s)
+}
+
+func length(s interface{}) (interface{}, error) {
+	x, err := stdlib.ToString("len", s)
+	if err != nil {
+		return nil, err
+	}
+	return types.Integer(utf8.RuneCountInString(x)), nil
+}
+
+// mapper returns a method returning f of its argument
+func mapper(name string, f func(string) string) func(s interface{}) (interface{}, error) {
+	return func(s interface{}) (interface{}, error) {
+		x, err := stdlib.ToString(name, s)
+		if err != nil {
+			return nil, err
+		}
+		return types.String(f(x)), nil
+	}
+}
+
+// predicate returns a method returning f of its two arguments as a Boolean
+func predicate(name string, f func(a, b string) bool) func(a, b interface{}) (interface{}, error) {
+	return func(a, b interface{}) (interface{}, error) {
+		x, y, err := toStrings(name, a, b)
+		if err != nil {
+			return nil, err
+		}
+		return types.Boolean(f(x, y)), nil
+	}
+}
+
+// comparer returns a method returning f of its two arguments as an Integer
+func comparer(name string, f func(a, b string) int) func(a, b interface{}) (interface{}, error) {
+	return func(a, b interface{}) (interface{}, error) {
+		x, y, err := toStrings(name, a, b)
+		if err != nil {
+			return nil, err
+		}
+		return types.Integer(f(x, y)), nil
+	}
+}
+
+func trim(s interface{}, cutset ...interface{}) (interface{}, error) {
+	x, err := stdlib.ToString("trim", s)
+	if err != nil {
+		return nil, err
+	}
+	switch len(cutset) {
+	case 0:
+		return types.String(gostrings.TrimSpace(x)), nil
+	case 1:
+		c, err := stdlib.ToString("trim", cutset[0])
+		if err != nil {
+			return nil, err
+		}
+		return types.String(gostrings.Trim(x, c)), nil
+	}
+	return nil, stdlib.TooManyArguments(2, len(cutset)+1)
+}
+
+func repeat(s, n interface{}) (interface{}, error) {
+	x, err := stdlib.ToString("repeat", s)
+	if err != nil {
+		return nil, err
+	}
+	count, err := toInt("repeat", n)
+	if err != nil {
+		return nil, err
+	}
+	if count < 0 {
+		return nil, fmt.Errorf("Invalid argument for repeat: negative count %d", count)
+	}
+	if l := int64(utf8.RuneCountInString(x)); l > 0 && count > maxLength/l {
+		return nil, fmt.Errorf("Result of repeat is longer than %d", maxLength)
+	}
+	return types.String(gostrings.Repeat(x, int(count))), nil
+}
+
+// indexOf returns the index (in runes) of the first occurrence of sub in s, or -1
+func indexOf(s, sub interface{}) (interface{}, error) {
+	x, y, err := toStrings("indexOf", s, sub)
+	if err != nil {
+		return nil, err
+	}
+	i := gostrings.Index(x, y)
+	if i < 0 {
+		return types.Integer(-1), nil
+	}
+	return types.Integer(utf8.RuneCountInString(x[:i])), nil
+}
+
+// replace replaces all occurrences of old in s with the given string
+func replace(s, old, with interface{}) (interface{}, error) {
+	x, o, err := toStrings("replace", s, old)
+	if err != nil {
+		return nil, err
+	}
+	n, err := stdlib.ToString("replace", with)
+	if err != nil {
+		return nil, err
+	}
+	return types.String(gostrings.ReplaceAll(x, o, n)), nil
+}
+
+// split returns the list of the substrings of s separated by sep; an empty sep splits s into runes
+func split(s, sep interface{}) (interface{}, error) {
+	x, y, err := toStrings("split", s, sep)
+	if err != nil {
+		return nil, err
+	}
+	parts := gostrings.Split(x, y)
+	res := make([]interface{}, len(parts))
+	for i, part := range parts {
+		res[i] = types.String(part)
+	}
+	return res, nil
+}
+
+// join concatenates the strings of a list (any Go slice or array) separated by sep
+func join(list, sep interface{}) (interface{}, error) {
+	y, err := stdlib.ToString("join", sep)
+	if err != nil {
+		return nil, err
+	}
+	v := reflect.ValueOf(list)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return nil, fmt.Errorf("Invalid argument for join: expected a list but got %T", list)
+	}
+	parts := make([]string, v.Len())
+	for i := range parts {
+		if parts[i], err = stdlib.ToString("join", v.Index(i).Interface()); err != nil {
+			return nil, err
+		}
+	}
+	return types.String(gostrings.Join(parts, y)), nil
+}
+
+// substr returns length runes of s from start, or all of them till the end of s if length is omitted
+func substr(s, start interface{}, length ...interface{}) (interface{}, error) {
+	if len(length) > 1 {
+		return nil, stdlib.TooManyArguments(3, len(length)+2)
+	}
+	x, err := stdlib.ToString("substr", s)
+	if err != nil {
+		return nil, err
+	}
+	runes := []rune(x)
+	from, err := toInt("substr", start)
+	if err != nil {
+		return nil, err
+	}
+	if from < 0 || from > int64(len(runes)) {
+		return nil, fmt.Errorf("Invalid argument for substr: start %d out of range [0, %d]", from, len(runes))
+	}
+	to := int64(len(runes))
+	if len(length) == 1 {
+		n, err := toInt("substr", length[0])
+		if err != nil {
+			return nil, err
+		}
+		if n < 0 {
+			return nil, fmt.Errorf("Invalid argument for substr: negative length %d", n)
+		}
+		if n < to-from {
+			to = from + n
+		}
+	}
+	return types.String(string(runes[from:to])), nil
+}
+
+// pad returns a method padding a string to the given width with a pad string (a space by default)
+func pad(name string, left bool) func(s, width interface{}, padding ...interface{}) (interface{}, error) {
+	return func(s, width interface{}, padding ...interface{}) (interface{}, error) {
+		if len(padding) > 1 {
+			return nil, stdlib.TooManyArguments(3, len(padding)+2)
+		}
+		x, err := stdlib.ToString(name, s)
+		if err != nil {
+			return nil, err
+		}
+		w, err := toInt(name, width)
+		if err != nil {
+			return nil, err
+		}
+		p := " "
+		if len(padding) == 1 {
+			if p, err = stdlib.ToString(name, padding[0]); err != nil {
+				return nil, err
+			}
+			if p == "" {
+				return nil, fmt.Errorf("Invalid argument for %s: empty pad", name)
+			}
+		}
+		if w > maxLength {
+			return nil, fmt.Errorf("Result of %s is longer than %d", name, maxLength)
+		}
+
+		n := int(w) - utf8.RuneCountInString(x)
+		if n <= 0 {
+			return types.String(x), nil
+		}
+		padRunes := []rune(p)
+		fill := make([]rune, n)
+		for i := range fill {
+			fill[i] = padRunes[i%len(padRunes)]
+		}
+		if left {
+			return types.String(string(fill) + x), nil
+		}
+		return types.String(x + string(fill)), nil
+	}
+}
+
+// format formats the arguments like fmt.Sprintf; the arguments are converted
+// to the corresponding Go values first, so that e.g. %d may be used with Integers
+func format(layout interface{}, args ...interface{}) (interface{}, error) {
+	f, err := stdlib.ToString("format", layout)
+	if err != nil {
+		return nil, err
+	}
+	values := make([]interface{}, len(args))
+	for i, arg := range args {
+		values[i] = native(arg)
+	}
+	return types.String(fmt.Sprintf(f, values...)), nil
+}
+
+func native(value interface{}) interface{} {
+	switch v := value.(type) {
+	case types.Integer:
+		return int64(v)
+	case types.Float:
+		return float64(v)
+	case types.String:
+		return string(v)
+	case types.Boolean:
+		return bool(v)
+	case types.Date:
+		return time.Time(v)
+	}
+	if types.IsNull(value) {
+		return nil
+	}
+	return value
+}
+
+func toStrings(name string, a, b interface{}) (string, string, error) {
+	x, err := stdlib.ToString(name, a)
+	if err != nil {
+		return "", "", err
+	}
+	y, err := stdlib.ToString(name, b)
+	return x, y, err
+}
+
+func toInt(name string, value interface{}) (int64, error) {
+	if n, ok := value.(types.Integer); ok {
+		return int64(n), nil
+	}
+	return 0, fmt.Errorf("Invalid argument for %s: expected an Integer but got %T", name, value)
+}
@@ -0,0 +1,166 @@
+package strings
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/svstanev/goexp"
+	"github.com/svstanev/goexp/types"
+)
+
+func newContext(t *testing.T) goexp.EvalContext {
+	ctx := goexp.NewEvalContext(nil)
+	if err := Install(ctx); err != nil {
+		t.Fatal(err)
+	}
+	ctx.AddName("name", types.String("  Ива Петрова "))
+	ctx.AddName("tags", []types.String{"a", "b", "c"})
+	ctx.AddName("words", []string{"x", "y"})
+	ctx.AddName("mixed", []interface{}{types.String("a"), types.Integer(1)})
+	ctx.AddName("n", types.Integer(42))
+	return ctx
+}
+
+func TestStrings(t *testing.T) {
+	tests := []struct {
+		expr     string
+		expected interface{}
+	}{
+		{"len('abc')", types.Integer(3)},
+		{"len('')", types.Integer(0)},
+		{"len('Ива')", types.Integer(3)},
+		{"'😀'.len()", types.Integer(1)},
+		{"upper('ива')", types.String("ИВА")},
+		{"lower('ÀÉÎ')", types.String("àéî")},
+		{"name.trim()", types.String("Ива Петрова")},
+		{"name.trim().upper()", types.String("ИВА ПЕТРОВА")},
+		{"trim('--a-b--', '-')", types.String("a-b")},
+		{"repeat('ab', 3)", types.String("ababab")},
+		{"repeat('ab', 0)", types.String("")},
+		{"repeat('', 1000000000)", types.String("")},
+
+		{"startsWith('abc', 'ab')", types.Boolean(true)},
+		{"'abc'.startsWith('b')", types.Boolean(false)},
+		{"endsWith('abc', 'bc')", types.Boolean(true)},
+		{"contains('abc', 'b')", types.Boolean(true)},
+		{"contains('abc', '')", types.Boolean(true)},
+		{"indexOf('Ива Петрова', 'Петрова')", types.Integer(4)},
+		{"indexOf('abc', 'x')", types.Integer(-1)},
+
+		{"replace('a-b-c', '-', '+')", types.String("a+b+c")},
+		{"split('a,b,,c', ',')", []interface{}{types.String("a"), types.String("b"), types.String(""), types.String("c")}},
+		{"split('аб', '')", []interface{}{types.String("а"), types.String("б")}},
+		{"join(tags, ', ')", types.String("a, b, c")},
+		{"words.join('')", types.String("xy")},
+		{"join(split('a b c', ' '), '-')", types.String("a-b-c")},
+
+		{"substr('Петрова', 1, 3)", types.String("етр")},
+		{"substr('abc', 1)", types.String("bc")},
+		{"substr('abc', 3)", types.String("")},
+		{"substr('abc', 1, 10)", types.String("bc")},
+		{"'7'.padLeft(3, '0')", types.String("007")},
+		{"padLeft('ab', 5, 'xy')", types.String("xyxab")},
+		{"padLeft('abcdef', 3)", types.String("abcdef")},
+		{"padRight('ы', 3)", types.String("ы  ")},
+
+		{"format('%s is %d (%.1f%%)', 'x', n, 0.25)", types.String("x is 42 (0.2%)")},
+		{"format('%v|%v|%t', nil, 'a', true)", types.String("<nil>|a|true")},
+		{"format('plain')", types.String("plain")},
+
+		{"fold('Straße') == fold('STRAßE')", types.Boolean(true)},
+		{"fold('ſ') == fold('S')", types.Boolean(true)},
+		{"equalFold('Σίσυφος', 'ΣΊΣΥΦΟΣ')", types.Boolean(true)},
+		{"equalFold('a', 'b')", types.Boolean(false)},
+		{"compare('a', 'b')", types.Integer(-1)},
+		{"compare('b', 'B')", types.Integer(1)},
+		{"compareFold('b', 'B')", types.Integer(0)},
+		{"compareFold('a', 'B')", types.Integer(-1)},
+	}
+
+	ctx := newContext(t)
+	for _, test := range tests {
+		t.Run(test.expr, func(t *testing.T) {
+			res, err := goexp.EvalString(test.expr, ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(res, test.expected) {
+				t.Errorf("Expected %#v but got %#v", test.expected, res)
+			}
+		})
+	}
+}
+
+func TestStringsErrors(t *testing.T) {
+	tests := []struct {
+		expr string
+		err  string
+	}{
+		{"len(1)", "Invalid argument for len: expected a String but got types.Integer"},
+		{"nil.upper()", "Invalid argument for upper: expected a String but got *types.NullType"},
+		{"trim('a', 'b', 'c')", "Invalid number of arguments: expected at most 2 but got 3"},
+		{"repeat('a', -1)", "Invalid argument for repeat: negative count -1"},
+		{"repeat('ab', 1000000)", "Result of repeat is longer than 1048576"},
+		{"repeat('a', '1')", "Invalid argument for repeat: expected an Integer but got types.String"},
+		{"startsWith('a', 1)", "Invalid argument for startsWith: expected a String but got types.Integer"},
+		{"join('abc', ',')", "Invalid argument for join: expected a list but got types.String"},
+		{"join(mixed, ',')", "Invalid argument for join: expected a String but got types.Integer"},
+		{"substr('abc', 4)", "Invalid argument for substr: start 4 out of range [0, 3]"},
+		{"substr('abc', -1)", "Invalid argument for substr: start -1 out of range [0, 3]"},
+		{"substr('abc', 0, -1)", "Invalid argument for substr: negative length -1"},
+		{"substr('abc', 0, 1, 2)", "Invalid number of arguments: expected at most 3 but got 4"},
+		{"padLeft('a', 3, '')", "Invalid argument for padLeft: empty pad"},
+		{"padRight('a', 2000000)", "Result of padRight is longer than 1048576"},
+		{"format(1)", "Invalid argument for format: expected a String but got types.Integer"},
+	}
+
+	ctx := newContext(t)
+	for _, test := range tests {
+		t.Run(test.expr, func(t *testing.T) {
+			res, err := goexp.EvalString(test.expr, ctx)
+			if err == nil {
+				t.Fatalf("Expected error %q but got %v", test.err, res)
+			}
+			if err.Error() != test.err {
+				t.Errorf("Expected error %q but got %q", test.err, err)
+			}
+		})
+	}
+}
+
+func TestSchema(t *testing.T) {
+	schema := Schema()
+	schema.Names = map[string]*types.Type{"name": types.StringType, "tags": types.ListOf(types.StringType)}
+
+	tests := []struct {
+		expr     string
+		expected string
+		err      string
+	}{
+		{"name.trim().len() > 0", "Boolean", ""},
+		{"tags.join(',').upper()", "String", ""},
+		{"split(name, ' ')", "List<String>", ""},
+		{"name.substr(1)", "String", ""},
+		{"format('%d', 1, 'a')", "String", ""},
+		{"name.len(1)", "Any", "Type Error: Invalid number of arguments for len: expected 1 but got 2"},
+		{"name.join(',')", "String", "Type Error: Cannot use String as List<String> in argument 1 of join"},
+	}
+
+	for _, test := range tests {
+		expr, _ := goexp.Parse(test.expr)
+		res, err := goexp.Check(expr, schema)
+		if res.String() != test.expected {
+			t.Errorf("%s: expected %s but got %s", test.expr, test.expected, res)
+		}
+		if s := errString(err); s != test.err {
+			t.Errorf("%s: expected error %q but got %q", test.expr, test.err, s)
+		}
+	}
+}
+
+func errString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
@@ -0,0 +1,393 @@
+/*
+Package time is an opt-in library of date and time functions for goexp expressions.
+
+Install adds the following methods to a context:
+
+	now()  parseDate(s[, layout])  formatDate(d[, layout])
+	addDays(d, n)  addMonths(d, n)  addYears(d, n)
+	startOf(d, unit)  diff(a, b, unit)  isWeekend(d)  age(birthDate)  inZone(d, zone)
+
+The dates are types.Date values. A layout is either a Go layout, e.g.
+"02.01.2006", or one of the names "date" (2006-01-02), "datetime"
+(2006-01-02T15:04:05), "time" (15:04:05), "RFC3339" and "RFC1123". Dates
+without an offset are parsed as UTC; parseDate without a layout accepts RFC3339,
+"datetime" and "date" dates and formatDate without a layout uses RFC3339.
+
+The units of startOf and diff are year, quarter (startOf only), month, week,
+day, hour, minute and second, also in plural, and millisecond (diff only).
+Weeks start on Monday. diff(a, b, unit) is the number of whole units from b to
+a, negative if a is before b; years, months, weeks and days are counted in the
+calendar of the location of b, so they do not depend on daylight saving time.
+addMonths and addYears keep the day of the month unless the resulting month
+is shorter, e.g. adding a month to January 31 results in the last day of February.
+
+inZone converts a date to a location of the IANA time zone database. The
+zones are loaded from a copy of the database embedded in the package, never
+from the host, so that the results don't depend on the host's database or
+$ZONEINFO. The copy is zoneinfo.zip of the Go distribution, built from the
+IANA release 2026c; go generate copies it from the installed Go distribution
+if it has the release of the go:generate directive (see zoneinfo.sh).
+*/
+package time
+
+import (
+	"archive/zip"
+	_ "embed" // the time zone database used by inZone
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	gotime "time"
+
+	"github.com/svstanev/goexp"
+	"github.com/svstanev/goexp/internal/stdlib"
+	"github.com/svstanev/goexp/types"
+)
+
+// Option configures Install
+type Option func(*library)
+
+// WithNow makes now and age use the given clock instead of the current time
+func WithNow(now func() gotime.Time) Option {
+	return func(l *library) {
+		l.now = now
+	}
+}
+
+type library struct {
+	now func() gotime.Time
+}
+
+func (l *library) functions() []stdlib.Function {
+	date, str, integer := types.DateType, types.StringType, types.IntegerType
+	sig, optional := stdlib.Signature, stdlib.Optional
+
+	return []stdlib.Function{
+		{Name: "now", Fn: stdlib.Impure(l.nowDate), Sig: sig(date)},
+		{Name: "parseDate", Fn: parseDate, Sig: optional(date, str, str)},
+		{Name: "formatDate", Fn: formatDate, Sig: optional(str, date, str)},
+		{Name: "addDays", Fn: adder("addDays", addDays), Sig: sig(date, date, integer)},
+		{Name: "addMonths", Fn: adder("addMonths", addMonths), Sig: sig(date, date, integer)},
+		{Name: "addYears", Fn: adder("addYears", func(t gotime.Time, n int) gotime.Time { return addMonths(t, 12*n) }), Sig: sig(date, date, integer)},
+		{Name: "startOf", Fn: startOf, Sig: sig(date, date, str)},
+		{Name: "diff", Fn: diff, Sig: sig(integer, date, date, str)},
+		{Name: "isWeekend", Fn: isWeekend, Sig: sig(types.BooleanType, date)},
+		{Name: "age", Fn: stdlib.Impure(l.age), Sig: sig(integer, date)},
+		{Name: "inZone", Fn: inZone, Sig: sig(date, date, str)},
+	}
+}
+
+// Install adds the methods of the package to the context; it fails if any of
+// their names is already defined by the context
+func Install(ctx goexp.EvalContext, options ...Option) error {
+	l := &library{now: gotime.Now}
+	for _, option := range options {
+		option(l)
+	}
+	return stdlib.Install(ctx, l.functions())
+}
+
+// Schema returns the signatures of the methods added by Install
+func Schema() *types.Schema {
+	return stdlib.Schema((&library{}).functions())
+}
+
+var layouts = map[string]string{
+	"date":     "2006-01-02",
+	"datetime": "2006-01-02T15:04:05",
+	"time":     "15:04:05",
+	"RFC3339":  gotime.RFC3339,
+	"RFC1123":  gotime.RFC1123,
+}
+
+func layout(name string, args []interface{}) (string, bool, error) {
+	switch len(args) {
+	case 0:
+		return "", false, nil
+	case 1:
+		s, err := stdlib.ToString(name, args[0])
+		if l, ok := layouts[s]; ok {
+			s = l
+		}
+		return s, true, err
+	}
+	return "", false, stdlib.TooManyArguments(2, len(args)+1)
+}
+
+func parseDate(s interface{}, args ...interface{}) (interface{}, error) {
+	value, err := stdlib.ToString("parseDate", s)
+	if err != nil {
+		return nil, err
+	}
+	l, ok, err := layout("parseDate", args)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		t, err := gotime.Parse(l, value)
+		if err != nil {
+			return nil, fmt.Errorf("Cannot parse %q as a date in layout %q", value, l)
+		}
+		return types.Date(t), nil
+	}
+	for _, l := range []string{gotime.RFC3339Nano, layouts["datetime"], layouts["date"]} {
+		if t, err := gotime.Parse(l, value); err == nil {
+			return types.Date(t), nil
+		}
+	}
+	return nil, fmt.Errorf("Cannot parse %q as a date", value)
+}
+
+func formatDate(d interface{}, args ...interface{}) (interface{}, error) {
+	t, err := toTime("formatDate", d)
+	if err != nil {
+		return nil, err
+	}
+	l, ok, err := layout("formatDate", args)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		l = gotime.RFC3339
+	}
+	return types.String(t.Format(l)), nil
+}
+
+// adder returns a method moving a date by an Integer number of units with add
+func adder(name string, add func(t gotime.Time, n int) gotime.Time) func(d, n interface{}) (interface{}, error) {
+	return func(d, n interface{}) (interface{}, error) {
+		t, err := toTime(name, d)
+		if err != nil {
+			return nil, err
+		}
+		count, ok := n.(types.Integer)
+		if !ok {
+			return nil, fmt.Errorf("Invalid argument for %s: expected an Integer but got %T", name, n)
+		}
+		return types.Date(add(t, int(count))), nil
+	}
+}
+
+// addMonths adds n months to t, keeping the day unless the month is shorter
+func addMonths(t gotime.Time, n int) gotime.Time {
+	y, m, d := t.Date()
+	first := gotime.Date(y, m+gotime.Month(n), 1, 0, 0, 0, 0, t.Location())
+	if last := daysIn(first); d > last {
+		d = last
+	}
+	return gotime.Date(first.Year(), first.Month(), d, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+}
+
+func daysIn(t gotime.Time) int {
+	return gotime.Date(t.Year(), t.Month()+1, 0, 0, 0, 0, 0, gotime.UTC).Day()
+}
+
+func startOf(d, unit interface{}) (interface{}, error) {
+	t, err := toTime("startOf", d)
+	if err != nil {
+		return nil, err
+	}
+	u, err := toUnit("startOf", unit)
+	if err != nil {
+		return nil, err
+	}
+
+	y, m, day := t.Date()
+	loc := t.Location()
+	switch u {
+	case "year":
+		return types.Date(gotime.Date(y, 1, 1, 0, 0, 0, 0, loc)), nil
+	case "quarter":
+		return types.Date(gotime.Date(y, m-(m-1)%3, 1, 0, 0, 0, 0, loc)), nil
+	case "month":
+		return types.Date(gotime.Date(y, m, 1, 0, 0, 0, 0, loc)), nil
+	case "week":
+		offset := (int(t.Weekday()) + 6) % 7
+		return types.Date(gotime.Date(y, m, day-offset, 0, 0, 0, 0, loc)), nil
+	case "day":
+		return types.Date(gotime.Date(y, m, day, 0, 0, 0, 0, loc)), nil
+	case "hour":
+		return types.Date(gotime.Date(y, m, day, t.Hour(), 0, 0, 0, loc)), nil
+	case "minute":
+		return types.Date(gotime.Date(y, m, day, t.Hour(), t.Minute(), 0, 0, loc)), nil
+	case "second":
+		return types.Date(gotime.Date(y, m, day, t.Hour(), t.Minute(), t.Second(), 0, loc)), nil
+	}
+	return nil, fmt.Errorf("Invalid argument for startOf: unknown unit %q", u)
+}
+
+var durations = map[string]gotime.Duration{
+	"hour":        gotime.Hour,
+	"minute":      gotime.Minute,
+	"second":      gotime.Second,
+	"millisecond": gotime.Millisecond,
+}
+
+func diff(a, b, unit interface{}) (interface{}, error) {
+	ta, err := toTime("diff", a)
+	if err != nil {
+		return nil, err
+	}
+	tb, err := toTime("diff", b)
+	if err != nil {
+		return nil, err
+	}
+	u, err := toUnit("diff", unit)
+	if err != nil {
+		return nil, err
+	}
+
+	if d, ok := durations[u]; ok {
+		return types.Integer(ta.Sub(tb) / d), nil
+	}
+	switch u {
+	case "year":
+		return types.Integer(calendarDiff(ta, tb, addMonths, monthsBetween) / 12), nil
+	case "month":
+		return types.Integer(calendarDiff(ta, tb, addMonths, monthsBetween)), nil
+	case "week":
+		return types.Integer(calendarDiff(ta, tb, addDays, daysBetween) / 7), nil
+	case "day":
+		return types.Integer(calendarDiff(ta, tb, addDays, daysBetween)), nil
+	}
+	return nil, fmt.Errorf("Invalid argument for diff: unknown unit %q", u)
+}
+
+func addDays(t gotime.Time, n int) gotime.Time {
+	return t.AddDate(0, 0, n)
+}
+
+func monthsBetween(a, b gotime.Time) int {
+	return (a.Year()-b.Year())*12 + int(a.Month()-b.Month())
+}
+
+func daysBetween(a, b gotime.Time) int {
+	return int(a.Sub(b).Hours() / 24)
+}
+
+// calendarDiff returns the largest n for which add(b, n) is not after a, or the
+// opposite of the largest n for which add(a, n) is not after b if a is before b;
+// estimate is the approximate n the search starts from
+func calendarDiff(a, b gotime.Time, add func(gotime.Time, int) gotime.Time, estimate func(a, b gotime.Time) int) int {
+	if a.Before(b) {
+		return -calendarDiff(b, a, add, estimate)
+	}
+	a = a.In(b.Location())
+	n := estimate(a, b)
+	for n > 0 && add(b, n).After(a) {
+		n--
+	}
+	for !add(b, n+1).After(a) {
+		n++
+	}
+	return n
+}
+
+func isWeekend(d interface{}) (interface{}, error) {
+	t, err := toTime("isWeekend", d)
+	if err != nil {
+		return nil, err
+	}
+	day := t.Weekday()
+	return types.Boolean(day == gotime.Saturday || day == gotime.Sunday), nil
+}
+
+func (l *library) nowDate() (interface{}, error) {
+	return types.Date(l.now()), nil
+}
+
+// age returns the number of whole years since the birth date
+func (l *library) age(d interface{}) (interface{}, error) {
+	birth, err := toTime("age", d)
+	if err != nil {
+		return nil, err
+	}
+	now := l.now()
+	if now.Before(birth) {
+		return nil, fmt.Errorf("Invalid argument for age: %s is in the future", birth.Format(gotime.RFC3339))
+	}
+	return types.Integer(calendarDiff(now, birth, addMonths, monthsBetween) / 12), nil
+}
+
+var zones sync.Map
+
+func inZone(d, zone interface{}) (interface{}, error) {
+	t, err := toTime("inZone", d)
+	if err != nil {
+		return nil, err
+	}
+	name, err := stdlib.ToString("inZone", zone)
+	if err != nil {
+		return nil, err
+	}
+	if loc, ok := zones.Load(name); ok {
+		return types.Date(t.In(loc.(*gotime.Location))), nil
+	}
+
+	// the local zone depends on the host
+	if name == "Local" {
+		return nil, fmt.Errorf("Unknown time zone %q", name)
+	}
+	loc, err := loadLocation(name)
+	if err != nil {
+		return nil, err
+	}
+	zones.Store(name, loc)
+	return types.Date(t.In(loc)), nil
+}
+
+//go:generate sh zoneinfo.sh 2026c
+//go:embed zoneinfo.zip
+var zoneinfo string
+
+var (
+	zoneinfoOnce sync.Once
+	zoneinfoZip  *zip.Reader
+	zoneinfoErr  error
+)
+
+// loadLocation loads a location from the embedded time zone database; "" and
+// "UTC" are UTC as for time.LoadLocation
+func loadLocation(name string) (*gotime.Location, error) {
+	if name == "" || name == "UTC" {
+		return gotime.UTC, nil
+	}
+	zoneinfoOnce.Do(func() {
+		zoneinfoZip, zoneinfoErr = zip.NewReader(strings.NewReader(zoneinfo), int64(len(zoneinfo)))
+	})
+	if zoneinfoErr != nil {
+		return nil, zoneinfoErr
+	}
+	for _, f := range zoneinfoZip.File {
+		if f.Name != name {
+			continue
+		}
+		r, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return nil, err
+		}
+		return gotime.LoadLocationFromTZData(name, data)
+	}
+	return nil, fmt.Errorf("Unknown time zone %q", name)
+}
+
+func toTime(name string, value interface{}) (gotime.Time, error) {
+	switch v := value.(type) {
+	case types.Date:
+		return gotime.Time(v), nil
+	case gotime.Time:
+		return v, nil
+	}
+	return gotime.Time{}, fmt.Errorf("Invalid argument for %s: expected a Date but got %T", name, value)
+}
+
+// toUnit returns the singular of the unit, e.g. "day" for "days"
+func toUnit(name string, value interface{}) (string, error) {
+	s, err := stdlib.ToString(name, value)
+	return strings.TrimSuffix(s, "s"), err
+}
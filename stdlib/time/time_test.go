@@ -0,0 +1,193 @@
+package time
+
+import (
+	"reflect"
+	"testing"
+	gotime "time"
+
+	"github.com/svstanev/goexp"
+	"github.com/svstanev/goexp/types"
+)
+
+var sofia, _ = gotime.LoadLocation("Europe/Sofia")
+
+func newContext(t *testing.T) goexp.EvalContext {
+	ctx := goexp.NewEvalContext(nil)
+	now := gotime.Date(2024, 3, 15, 10, 30, 0, 0, gotime.UTC)
+	if err := Install(ctx, WithNow(func() gotime.Time { return now })); err != nil {
+		t.Fatal(err)
+	}
+	ctx.AddName("d", types.Date(gotime.Date(2024, 1, 31, 18, 45, 30, 0, gotime.UTC)))
+	ctx.AddName("goTime", gotime.Date(2024, 3, 16, 0, 0, 0, 0, gotime.UTC))
+	return ctx
+}
+
+func date(y int, m gotime.Month, d, h, min int, loc *gotime.Location) types.Date {
+	return types.Date(gotime.Date(y, m, d, h, min, 0, 0, loc))
+}
+
+func TestTime(t *testing.T) {
+	tests := []struct {
+		expr     string
+		expected interface{}
+	}{
+		{"now()", date(2024, 3, 15, 10, 30, gotime.UTC)},
+		{"parseDate('2024-02-29')", date(2024, 2, 29, 0, 0, gotime.UTC)},
+		{"parseDate('2024-02-29T13:15:00')", date(2024, 2, 29, 13, 15, gotime.UTC)},
+		{"parseDate('2024-02-29T13:15:00Z')", date(2024, 2, 29, 13, 15, gotime.UTC)},
+		{"parseDate('29.02.2024', '02.01.2006')", date(2024, 2, 29, 0, 0, gotime.UTC)},
+		{"parseDate('2024-02-29', 'date')", date(2024, 2, 29, 0, 0, gotime.UTC)},
+		{"formatDate(d)", types.String("2024-01-31T18:45:30Z")},
+		{"d.formatDate('date')", types.String("2024-01-31")},
+		{"formatDate(d, 'Mon, 02 Jan 2006')", types.String("Wed, 31 Jan 2024")},
+		{"formatDate(goTime, 'date')", types.String("2024-03-16")},
+
+		{"addDays(d, 1)", types.Date(gotime.Date(2024, 2, 1, 18, 45, 30, 0, gotime.UTC))},
+		{"addDays(d, -31)", types.Date(gotime.Date(2023, 12, 31, 18, 45, 30, 0, gotime.UTC))},
+		{"addMonths(d, 1)", types.Date(gotime.Date(2024, 2, 29, 18, 45, 30, 0, gotime.UTC))},
+		{"addMonths(d, 2)", types.Date(gotime.Date(2024, 3, 31, 18, 45, 30, 0, gotime.UTC))},
+		{"addMonths(d, -2)", types.Date(gotime.Date(2023, 11, 30, 18, 45, 30, 0, gotime.UTC))},
+		{"addYears(parseDate('2024-02-29'), 1)", date(2025, 2, 28, 0, 0, gotime.UTC)},
+
+		{"startOf(d, 'year')", date(2024, 1, 1, 0, 0, gotime.UTC)},
+		{"startOf(parseDate('2024-05-20'), 'quarter')", date(2024, 4, 1, 0, 0, gotime.UTC)},
+		{"startOf(d, 'month')", date(2024, 1, 1, 0, 0, gotime.UTC)},
+		{"startOf(d, 'week')", date(2024, 1, 29, 0, 0, gotime.UTC)},
+		{"startOf(parseDate('2024-02-04'), 'week')", date(2024, 1, 29, 0, 0, gotime.UTC)},
+		{"startOf(d, 'day')", date(2024, 1, 31, 0, 0, gotime.UTC)},
+		{"startOf(d, 'hours')", date(2024, 1, 31, 18, 0, gotime.UTC)},
+		{"startOf(d, 'minute')", date(2024, 1, 31, 18, 45, gotime.UTC)},
+		{"startOf(inZone(d, 'Europe/Sofia'), 'day')", date(2024, 1, 31, 0, 0, sofia)},
+
+		{"diff(now(), d, 'days')", types.Integer(43)},
+		{"diff(d, now(), 'days')", types.Integer(-43)},
+		{"diff(now(), d, 'weeks')", types.Integer(6)},
+		{"diff(now(), d, 'months')", types.Integer(1)},
+		{"diff(parseDate('2024-02-29'), parseDate('2024-01-31'), 'month')", types.Integer(1)},
+		{"diff(parseDate('2024-02-28'), parseDate('2024-01-31'), 'month')", types.Integer(0)},
+		{"diff(parseDate('2024-02-29T00:00:01Z'), parseDate('2024-01-29'), 'month')", types.Integer(1)},
+		{"diff(now(), d, 'years')", types.Integer(0)},
+		{"diff(now(), d, 'hours')", types.Integer(1047)},
+		{"diff(d, d, 'seconds')", types.Integer(0)},
+		{"diff(parseDate('2024-01-01T00:00:01Z'), parseDate('2024-01-01'), 'milliseconds')", types.Integer(1000)},
+		// March 31 in Sofia has 23 hours
+		{"diff(inZone(parseDate('2024-03-31T21:00:00Z'), 'Europe/Sofia'), inZone(parseDate('2024-03-30T22:00:00Z'), 'Europe/Sofia'), 'days')", types.Integer(1)},
+		{"diff(parseDate('2024-03-31T21:00:00Z'), parseDate('2024-03-30T22:00:00Z'), 'hours')", types.Integer(23)},
+
+		{"isWeekend(d)", types.Boolean(false)},
+		{"isWeekend(goTime)", types.Boolean(true)},
+		{"isWeekend(inZone(parseDate('2024-03-15T22:30:00Z'), 'Europe/Sofia'))", types.Boolean(true)},
+
+		{"age(parseDate('2006-03-15'))", types.Integer(18)},
+		{"age(parseDate('2006-03-16'))", types.Integer(17)},
+		{"age(parseDate('2024-03-15'))", types.Integer(0)},
+
+		{"inZone(d, 'Europe/Sofia')", types.Date(gotime.Date(2024, 1, 31, 20, 45, 30, 0, sofia))},
+		{"formatDate(inZone(d, 'America/New_York'), 'datetime')", types.String("2024-01-31T13:45:30")},
+		{"inZone(d, 'Asia/Tokyo') == d", types.Boolean(true)},
+		{"formatDate(inZone(d, 'Asia/Kolkata'), 'RFC3339')", types.String("2024-02-01T00:15:30+05:30")},
+		{"formatDate(inZone(d, 'UTC'), 'RFC3339')", types.String("2024-01-31T18:45:30Z")},
+		{"d + (now() - d) == now()", types.Boolean(true)},
+		{"parseDate('2024-01-01') < d", types.Boolean(true)},
+	}
+
+	ctx := newContext(t)
+	for _, test := range tests {
+		t.Run(test.expr, func(t *testing.T) {
+			res, err := goexp.EvalString(test.expr, ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if x, ok := res.(types.Date); ok {
+				// compare the locations by name, as they are loaded separately
+				expected, ok := test.expected.(types.Date)
+				if !ok || !gotime.Time(x).Equal(gotime.Time(expected)) || gotime.Time(x).Location().String() != gotime.Time(expected).Location().String() {
+					t.Errorf("Expected %v but got %v", test.expected, res)
+				}
+				return
+			}
+			if !reflect.DeepEqual(res, test.expected) {
+				t.Errorf("Expected %#v but got %#v", test.expected, res)
+			}
+		})
+	}
+}
+
+func TestTimeErrors(t *testing.T) {
+	tests := []struct {
+		expr string
+		err  string
+	}{
+		{"parseDate('yesterday')", `Cannot parse "yesterday" as a date`},
+		{"parseDate('2024-13-01', 'date')", `Cannot parse "2024-13-01" as a date in layout "2006-01-02"`},
+		{"parseDate(1)", "Invalid argument for parseDate: expected a String but got types.Integer"},
+		{"parseDate('x', 'date', 1)", "Invalid number of arguments: expected at most 2 but got 3"},
+		{"formatDate('2024-01-01')", "Invalid argument for formatDate: expected a Date but got types.String"},
+		{"addDays(d, 1.5)", "Invalid argument for addDays: expected an Integer but got types.Float"},
+		{"startOf(d, 'fortnight')", `Invalid argument for startOf: unknown unit "fortnight"`},
+		{"diff(d, d, 'quarter')", `Invalid argument for diff: unknown unit "quarter"`},
+		{"diff(d, nil, 'days')", "Invalid argument for diff: expected a Date but got *types.NullType"},
+		{"age(parseDate('2030-01-01'))", "Invalid argument for age: 2030-01-01T00:00:00Z is in the future"},
+		{"inZone(d, 'Mars/Olympus')", `Unknown time zone "Mars/Olympus"`},
+		{"inZone(d, 'Local')", `Unknown time zone "Local"`},
+		{"inZone(d, '../zoneinfo/UTC')", `Unknown time zone "../zoneinfo/UTC"`},
+	}
+
+	ctx := newContext(t)
+	for _, test := range tests {
+		t.Run(test.expr, func(t *testing.T) {
+			res, err := goexp.EvalString(test.expr, ctx)
+			if err == nil {
+				t.Fatalf("Expected error %q but got %v", test.err, res)
+			}
+			if err.Error() != test.err {
+				t.Errorf("Expected error %q but got %q", test.err, err)
+			}
+		})
+	}
+}
+
+// TestOptimize checks that now and age, which depend on the clock, are not folded
+func TestOptimize(t *testing.T) {
+	ctx := newContext(t)
+	expr, _ := goexp.Parse("diff(now(), parseDate('2024-01-01'), 'days') > age(parseDate('2006-03-15'))")
+	opt := goexp.OptimizeWith(expr, ctx)
+	expected := "diff(now(), parseDate(\"2024-01-01\"), \"days\") > age(parseDate(\"2006-03-15\"))"
+	if s, _ := goexp.Print(opt); s != expected {
+		t.Errorf("Expected %s but got %s", expected, s)
+	}
+}
+
+func TestSchema(t *testing.T) {
+	schema := Schema()
+	schema.Names = map[string]*types.Type{"birthDate": types.DateType}
+
+	tests := []struct {
+		expr     string
+		expected string
+		err      string
+	}{
+		{"birthDate.age() >= 18", "Boolean", ""},
+		{"diff(now(), birthDate, 'days') / 7", "Integer", ""},
+		{"now() - birthDate", "Duration", ""},
+		{"addDays(birthDate, '1')", "Date", "Type Error: Cannot use String as Integer in argument 2 of addDays"},
+	}
+
+	for _, test := range tests {
+		expr, _ := goexp.Parse(test.expr)
+		res, err := goexp.Check(expr, schema)
+		if res.String() != test.expected {
+			t.Errorf("%s: expected %s but got %s", test.expr, test.expected, res)
+		}
+		if s := errString(err); s != test.err {
+			t.Errorf("%s: expected error %q but got %q", test.expr, test.err, s)
+		}
+	}
+}
+
+func errString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
@@ -0,0 +1,14 @@
+#!/bin/sh
+# Copies zoneinfo.zip, the time zone database embedded by the package, from
+# the Go distribution, which builds it from the IANA release in DATA of
+# $GOROOT/lib/time/update.bash. Usage: zoneinfo.sh <tzdata version>, e.g. 2026c.
+set -e
+
+version=$1
+goroot=$(go env GOROOT)
+data=$(sed -n 's/^DATA=//p' "$goroot/lib/time/update.bash")
+if [ "$data" != "$version" ]; then
+	echo "zoneinfo.sh: $goroot has tzdata $data, expected $version" >&2
+	exit 1
+fi
+cp "$goroot/lib/time/zoneinfo.zip" zoneinfo.zip
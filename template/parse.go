@@ -0,0 +1,342 @@
+package template
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/svstanev/goexp"
+)
+
+type node interface{}
+
+// text is a literal part of the template
+type text string
+
+// output writes the value of the expression, {{ expr }}
+type output struct {
+	loc  location
+	expr goexp.Expr
+}
+
+// ifBlock is {% if cond %}then{% else %}els{% end %}
+type ifBlock struct {
+	loc  location
+	cond goexp.Expr
+	then []node
+	els  []node
+}
+
+// forBlock is {% for name in list %}body{% end %}
+type forBlock struct {
+	loc  location
+	name string
+	list goexp.Expr
+	body []node
+}
+
+// location is a 1-based line and column (in runes) of the source
+type location struct {
+	line   int
+	column int
+}
+
+type itemKind int
+
+const (
+	itemText itemKind = iota
+	itemOutput
+	itemTag
+)
+
+// item is a text, the expression of a {{ }} tag or the content of a {% %} tag;
+// pos is the byte offset of the text or of the opening "{{" or "{%" of the tag
+type item struct {
+	kind itemKind
+	text string
+	pos  int
+}
+
+type parser struct {
+	name  string
+	src   string
+	items []item
+	i     int
+}
+
+func (p *parser) parse() ([]node, error) {
+	var err error
+	if p.items, err = p.lex(); err != nil {
+		return nil, err
+	}
+	nodes, end, err := p.parseNodes()
+	if err != nil {
+		return nil, err
+	}
+	if end != nil {
+		return nil, p.errorf(end.pos, "Unexpected {%% %s %%}", tagKeyword(*end))
+	}
+	return nodes, nil
+}
+
+// lex splits the source into items; a {% %} tag that is alone on its line
+// takes the whole line with it, so that blocks don't leave empty lines
+func (p *parser) lex() ([]item, error) {
+	var items []item
+	src, start := p.src, 0
+	for i := 0; i < len(src); {
+		j := strings.IndexByte(src[i:], '{')
+		if j < 0 {
+			break
+		}
+		i += j
+
+		var kind itemKind
+		var closer string
+		switch {
+		case strings.HasPrefix(src[i:], "{{"):
+			kind, closer = itemOutput, "}}"
+		case strings.HasPrefix(src[i:], "{%"):
+			kind, closer = itemTag, "%}"
+		default:
+			i++
+			continue
+		}
+
+		end := exprEnd(src, i+2, closer)
+		if end < 0 {
+			return nil, p.errorf(i, "Unterminated tag %s", src[i:i+2])
+		}
+		textEnd, next := i, end+2
+		if kind == itemTag {
+			if lineStart, lineEnd, ok := standalone(src, i, next); ok {
+				textEnd, next = lineStart, lineEnd
+			}
+		}
+		if textEnd > start {
+			items = append(items, item{itemText, src[start:textEnd], start})
+		}
+		items = append(items, item{kind, src[i+2 : end], i})
+		i, start = next, next
+	}
+	if start < len(src) {
+		items = append(items, item{itemText, src[start:], start})
+	}
+	return items, nil
+}
+
+// standalone returns the bounds of the line of the tag from start to end,
+// including the line break, if there is nothing else but whitespace on it
+func standalone(src string, start, end int) (int, int, bool) {
+	lineStart := strings.LastIndexByte(src[:start], '\n') + 1
+	if strings.Trim(src[lineStart:start], " \t") != "" {
+		return 0, 0, false
+	}
+	lineEnd := len(src)
+	if n := strings.IndexByte(src[end:], '\n'); n >= 0 {
+		lineEnd = end + n + 1
+	}
+	if strings.TrimSpace(src[end:lineEnd]) != "" {
+		return 0, 0, false
+	}
+	return lineStart, lineEnd, true
+}
+
+// exprEnd returns the offset of the closer ending the expression that starts
+// at i, skipping the closers in string literals and template strings
+func exprEnd(src string, i int, closer string) int {
+	depth := 0
+	for i < len(src) {
+		switch c := src[i]; {
+		case c == '\'' || c == '"' || c == '`':
+			if i = skipQuoted(src, i); i < 0 {
+				return -1
+			}
+			continue
+		case depth == 0 && strings.HasPrefix(src[i:], closer):
+			return i
+		case c == '{':
+			depth++
+		case c == '}' && depth > 0:
+			depth--
+		}
+		i++
+	}
+	return -1
+}
+
+// skipQuoted returns the offset after the string literal or template string starting at i
+func skipQuoted(src string, i int) int {
+	quote := src[i]
+	for i++; i < len(src); i++ {
+		switch {
+		case src[i] == '\\':
+			i++
+		case src[i] == quote:
+			return i + 1
+		case quote == '`' && strings.HasPrefix(src[i:], "${"):
+			if i = exprEnd(src, i+2, "}"); i < 0 {
+				return -1
+			}
+		}
+	}
+	return -1
+}
+
+// parseNodes parses the items up to an {% else %} or {% end %} tag, which is
+// returned, or up to the end of the template
+func (p *parser) parseNodes() ([]node, *item, error) {
+	var nodes []node
+	for p.i < len(p.items) {
+		it := p.items[p.i]
+		p.i++
+		switch it.kind {
+		case itemText:
+			nodes = append(nodes, text(it.text))
+		case itemOutput:
+			expr, loc, err := p.parseExpr(it.text, it.pos+2)
+			if err != nil {
+				return nil, nil, err
+			}
+			nodes = append(nodes, output{loc, expr})
+		case itemTag:
+			keyword := tagKeyword(it)
+			rest, pos := tagArgs(it)
+			var n node
+			var err error
+			switch keyword {
+			case "else", "end":
+				if rest != "" {
+					return nil, nil, p.errorf(pos, "Unexpected %q after %s", rest, keyword)
+				}
+				return nodes, &it, nil
+			case "if":
+				n, err = p.parseIf(it, rest, pos)
+			case "for":
+				n, err = p.parseFor(it, rest, pos)
+			case "":
+				err = p.errorf(it.pos, "Missing tag name")
+			default:
+				err = p.errorf(it.pos, "Unknown tag %s", keyword)
+			}
+			if err != nil {
+				return nil, nil, err
+			}
+			nodes = append(nodes, n)
+		}
+	}
+	return nodes, nil, nil
+}
+
+func (p *parser) parseIf(tag item, cond string, pos int) (node, error) {
+	var n ifBlock
+	var err error
+	if n.cond, n.loc, err = p.parseExpr(cond, pos); err != nil {
+		return nil, err
+	}
+	then, end, err := p.parseBlock(tag)
+	if err != nil {
+		return nil, err
+	}
+	n.then = then
+	if tagKeyword(*end) == "else" {
+		if n.els, end, err = p.parseBlock(tag); err != nil {
+			return nil, err
+		}
+		if tagKeyword(*end) == "else" {
+			return nil, p.errorf(end.pos, "Unexpected {%% else %%}")
+		}
+	}
+	return n, nil
+}
+
+var forArgs = regexp.MustCompile(`^([A-Za-z_][A-Za-z0-9_]*)\s+in\s`)
+
+func (p *parser) parseFor(tag item, args string, pos int) (node, error) {
+	m := forArgs.FindStringSubmatch(args)
+	if m == nil {
+		return nil, p.errorf(pos, "Expected {%% for <name> in <list> %%}")
+	}
+	n := forBlock{name: m[1]}
+	var err error
+	if n.list, n.loc, err = p.parseExpr(args[len(m[0]):], pos+len(m[0])); err != nil {
+		return nil, err
+	}
+	body, end, err := p.parseBlock(tag)
+	if err != nil {
+		return nil, err
+	}
+	if tagKeyword(*end) == "else" {
+		return nil, p.errorf(end.pos, "Unexpected {%% else %%}")
+	}
+	n.body = body
+	return n, nil
+}
+
+// parseBlock parses the body of the block started by the tag up to an else or end tag
+func (p *parser) parseBlock(tag item) ([]node, *item, error) {
+	nodes, end, err := p.parseNodes()
+	if err != nil {
+		return nil, nil, err
+	}
+	if end == nil {
+		return nil, nil, p.errorf(tag.pos, "Unclosed {%% %s %%}", tagKeyword(tag))
+	}
+	return nodes, end, nil
+}
+
+// parseExpr parses the expression src found at the byte offset pos; the
+// errors are located at the position of the syntax error in the template
+func (p *parser) parseExpr(src string, pos int) (goexp.Expr, location, error) {
+	trimmed := strings.TrimLeft(src, " \t\r\n")
+	pos += len(src) - len(trimmed)
+	expr, err := goexp.Parse(trimmed)
+	if err != nil {
+		if n, ok := goexp.ErrorPosition(err); ok {
+			runes := []rune(trimmed)
+			if n > len(runes) {
+				n = len(runes)
+			}
+			pos += len(string(runes[:n]))
+		}
+		return nil, location{}, p.error(pos, err)
+	}
+	return expr, p.location(pos), nil
+}
+
+// tagKeyword returns the first word of the content of a {% %} tag
+func tagKeyword(tag item) string {
+	s := strings.TrimLeft(tag.text, " \t\r\n")
+	n := strings.IndexFunc(s, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z')
+	})
+	if n < 0 {
+		return s
+	}
+	return s[:n]
+}
+
+// tagArgs returns the content of a {% %} tag after the keyword and its offset in the source
+func tagArgs(tag item) (string, int) {
+	s := strings.TrimLeft(tag.text, " \t\r\n")
+	s = strings.TrimLeft(s[len(tagKeyword(tag)):], " \t\r\n")
+	return strings.TrimRight(s, " \t\r\n"), tag.pos + 2 + len(tag.text) - len(s)
+}
+
+func (p *parser) location(pos int) location {
+	lineStart := strings.LastIndexByte(p.src[:pos], '\n') + 1
+	return location{
+		line:   strings.Count(p.src[:lineStart], "\n") + 1,
+		column: utf8.RuneCountInString(p.src[lineStart:pos]) + 1,
+	}
+}
+
+func (p *parser) error(pos int, err error) error {
+	loc := p.location(pos)
+	return Error{Name: p.name, Line: loc.line, Column: loc.column, Err: err}
+}
+
+func (p *parser) errorf(pos int, format string, args ...interface{}) error {
+	return p.error(pos, fmt.Errorf(format, args...))
+}
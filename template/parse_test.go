@@ -0,0 +1,89 @@
+package template
+
+import (
+	"testing"
+)
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		text string
+		err  string
+	}{
+		{"Hello {{ name", "t:1:7: Unterminated tag {{"},
+		{"a\n  {% if x", "t:2:3: Unterminated tag {%"},
+		{"{{ '}}' + x", "t:1:1: Unterminated tag {{"},
+		{"a\nb {{ x + }} c", "t:2:10: Parse Error at pos 4: Unexpected end of expression"},
+		{"{{ f(1,, 2) }}", `t:1:8: Parse Error at pos 4: Unexpected token ","`},
+		{"ж {{ 'ж' + }}", "t:1:12: Parse Error at pos 6: Unexpected end of expression"},
+		{"{% if %}{% end %}", "t:1:7: Parse Error at pos 0: Unexpected end of expression"},
+		{"{% if x %}\n{% else %}\n{% else %}\n{% end %}", "t:3:1: Unexpected {% else %}"},
+		{"x\n{% if x %}\n{{ y }}", "t:2:1: Unclosed {% if %}"},
+		{"{% for x in xs %}", "t:1:1: Unclosed {% for %}"},
+		{"{% for x in xs %}{% else %}{% end %}", "t:1:18: Unexpected {% else %}"},
+		{"{% for x xs %}{% end %}", "t:1:8: Expected {% for <name> in <list> %}"},
+		{"{% for x in %}{% end %}", "t:1:8: Expected {% for <name> in <list> %}"},
+		{"{% for x in 1 + %}{% end %}", "t:1:16: Parse Error at pos 3: Unexpected end of expression"},
+		{"a {% end %}", "t:1:3: Unexpected {% end %}"},
+		{"{% else %}", "t:1:1: Unexpected {% else %}"},
+		{"{% if x %}{% end x %}", "t:1:18: Unexpected \"x\" after end"},
+		{"{% include 'x' %}", "t:1:1: Unknown tag include"},
+		{"{% %}", "t:1:1: Missing tag name"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.text, func(t *testing.T) {
+			_, err := Parse("t", test.text, None)
+			if err == nil {
+				t.Fatalf("Expected error %q", test.err)
+			}
+			if err.Error() != test.err {
+				t.Errorf("Expected error %q but got %q", test.err, err)
+			}
+		})
+	}
+}
+
+func TestLex(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected []item
+	}{
+		{"", nil},
+		{"abc", []item{{itemText, "abc", 0}}},
+		{"a {b} {{x}}", []item{{itemText, "a {b} ", 0}, {itemOutput, "x", 6}}},
+		{"{{ '}}' + `${'}}'}` }}!", []item{{itemOutput, " '}}' + `${'}}'}` ", 0}, {itemText, "!", 22}}},
+		{"a\n  {% if x %}  \nb\n{% end %}", []item{
+			{itemText, "a\n", 0},
+			{itemTag, " if x ", 4},
+			{itemText, "b\n", 17},
+			{itemTag, " end ", 19},
+		}},
+		{"a {% if x %}\nb{% end %}\n", []item{
+			{itemText, "a ", 0},
+			{itemTag, " if x ", 2},
+			{itemText, "\nb", 12},
+			{itemTag, " end ", 14},
+			{itemText, "\n", 23},
+		}},
+		{"{{ x }}\n", []item{{itemOutput, " x ", 0}, {itemText, "\n", 7}}},
+	}
+
+	for _, test := range tests {
+		p := &parser{name: "t", src: test.text}
+		items, err := p.lex()
+		if err != nil {
+			t.Errorf("%q: %v", test.text, err)
+			continue
+		}
+		if len(items) != len(test.expected) {
+			t.Errorf("%q: expected %v but got %v", test.text, test.expected, items)
+			continue
+		}
+		for i := range items {
+			if items[i] != test.expected[i] {
+				t.Errorf("%q: expected %v but got %v", test.text, test.expected, items)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,182 @@
+/*
+Package template renders text templates with embedded goexp expressions,
+e.g. emails and configuration files.
+
+	{{ expr }}                              the value of the expression
+	{% if expr %}...{% else %}...{% end %}  a condition; the else branch is optional
+	{% for x in list %}...{% end %}         the body for each element x of the list
+
+The values are converted to text with types.ToString and escaped by the
+Escaper of the template, e.g. HTML. A {% %} tag that is alone on its line is
+removed with the line, so that the blocks don't leave empty lines.
+
+	t, err := template.Parse("welcome", "Hello {{ user.name }}!", template.HTML)
+	...
+	s, err := t.ExecuteString(ctx)
+
+The errors are located at the line and column of the template where they
+occurred, e.g. "welcome:1:10: user not defined".
+*/
+package template
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"reflect"
+	"strings"
+
+	"github.com/svstanev/goexp"
+	"github.com/svstanev/goexp/types"
+)
+
+// Escaper escapes the text of the values written by {{ }} tags
+type Escaper func(s string) string
+
+var (
+	// HTML escapes <, >, &, ' and "
+	HTML Escaper = html.EscapeString
+
+	// None writes the values as they are
+	None Escaper = func(s string) string { return s }
+)
+
+// Error is an error of a template located at a line and column (1-based, in runes)
+type Error struct {
+	Name   string
+	Line   int
+	Column int
+	Err    error
+}
+
+func (e Error) Error() string {
+	return fmt.Sprintf("%s:%d:%d: %v", e.Name, e.Line, e.Column, e.Err)
+}
+
+func (e Error) Unwrap() error {
+	return e.Err
+}
+
+// Template is a parsed template. It is immutable and safe for concurrent use
+// by multiple goroutines.
+type Template struct {
+	name   string
+	nodes  []node
+	escape Escaper
+}
+
+// Parse parses the text of a template; the name is used in the errors. A nil
+// escaper is None.
+func Parse(name, text string, escape Escaper) (*Template, error) {
+	p := &parser{name: name, src: text}
+	nodes, err := p.parse()
+	if err != nil {
+		return nil, err
+	}
+	if escape == nil {
+		escape = None
+	}
+	return &Template{name: name, nodes: nodes, escape: escape}, nil
+}
+
+// Name returns the name of the template
+func (t *Template) Name() string {
+	return t.name
+}
+
+// Execute writes the template evaluated against the context to w
+func (t *Template) Execute(w io.Writer, ctx goexp.Context) error {
+	return t.execute(w, t.nodes, ctx)
+}
+
+// ExecuteString returns the template evaluated against the context
+func (t *Template) ExecuteString(ctx goexp.Context) (string, error) {
+	var sb strings.Builder
+	if err := t.Execute(&sb, ctx); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
+
+func (t *Template) execute(w io.Writer, nodes []node, ctx goexp.Context) error {
+	for _, n := range nodes {
+		var err error
+		switch n := n.(type) {
+		case text:
+			_, err = io.WriteString(w, string(n))
+		case output:
+			err = t.output(w, n, ctx)
+		case ifBlock:
+			err = t.ifBlock(w, n, ctx)
+		case forBlock:
+			err = t.forBlock(w, n, ctx)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *Template) output(w io.Writer, n output, ctx goexp.Context) error {
+	value, err := goexp.Eval(n.expr, ctx)
+	if err != nil {
+		return t.error(n.loc, err)
+	}
+	s, err := types.ToString(value)
+	if err != nil {
+		return t.error(n.loc, err)
+	}
+	_, err = io.WriteString(w, t.escape(s))
+	return err
+}
+
+func (t *Template) ifBlock(w io.Writer, n ifBlock, ctx goexp.Context) error {
+	value, err := goexp.Eval(n.cond, ctx)
+	if err != nil {
+		return t.error(n.loc, err)
+	}
+	var cond bool
+	switch v := value.(type) {
+	case types.Boolean:
+		cond = bool(v)
+	case bool:
+		cond = v
+	default:
+		return t.error(n.loc, fmt.Errorf("Invalid condition: expected a Boolean but got %T", value))
+	}
+	if cond {
+		return t.execute(w, n.then, ctx)
+	}
+	return t.execute(w, n.els, ctx)
+}
+
+// forBlock executes the body in a child of the context with the name bound
+// to each element of the list; nil is an empty list
+func (t *Template) forBlock(w io.Writer, n forBlock, ctx goexp.Context) error {
+	list, err := goexp.Eval(n.list, ctx)
+	if err != nil {
+		return t.error(n.loc, err)
+	}
+	if types.IsNull(list) {
+		return nil
+	}
+	v := reflect.ValueOf(list)
+	if k := v.Kind(); k != reflect.Slice && k != reflect.Array {
+		return t.error(n.loc, fmt.Errorf("Cannot iterate over %T", list))
+	}
+	for i := 0; i < v.Len(); i++ {
+		scope := goexp.NewEvalContext(ctx)
+		if err := scope.AddName(n.name, types.FromGo(v.Index(i).Interface())); err != nil {
+			return t.error(n.loc, err)
+		}
+		if err := t.execute(w, n.body, scope); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *Template) error(loc location, err error) error {
+	return Error{Name: t.name, Line: loc.line, Column: loc.column, Err: err}
+}
@@ -0,0 +1,167 @@
+package template
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/svstanev/goexp"
+	"github.com/svstanev/goexp/types"
+)
+
+func newContext() goexp.EvalContext {
+	ctx := goexp.NewEvalContext(nil)
+	user := goexp.NewEvalContext(nil)
+	user.AddName("name", types.String("Ana <ana@example.com>"))
+	user.AddName("admin", types.Boolean(true))
+	ctx.AddName("user", user)
+
+	items := make([]goexp.Context, 2)
+	for i, name := range []string{"pen", "book"} {
+		item := goexp.NewEvalContext(nil)
+		item.AddName("name", types.String(name))
+		item.AddName("price", types.Float(2.5*float64(i+1)))
+		items[i] = item
+	}
+	ctx.AddName("items", items)
+	ctx.AddName("ports", []int{80, 443})
+	ctx.AddName("empty", []string{})
+	ctx.AddName("none", types.Null())
+	ctx.AddName("count", types.Integer(2))
+	return ctx
+}
+
+func TestExecute(t *testing.T) {
+	tests := []struct {
+		text     string
+		escape   Escaper
+		expected string
+	}{
+		{"Hello {{ user.name }}!", None, "Hello Ana <ana@example.com>!"},
+		{"Hello {{ user.name }}!", HTML, "Hello Ana &lt;ana@example.com&gt;!"},
+		{"Hello {{ user.name }}!", nil, "Hello Ana <ana@example.com>!"},
+		{"<b>{{ '\"x\" & \\'y\\'' }}</b>", HTML, "<b>&#34;x&#34; &amp; &#39;y&#39;</b>"},
+		{"{{ count }} items, {{ count * 1.5 }} kg, {{ none }}.", None, "2 items, 3 kg, ."},
+		{"{{ `${count}}}` }}", None, "2}}"},
+		{"{% if user.admin %}admin{% else %}user{% end %}", None, "admin"},
+		{"{% if count > 5 %}many{% end %}", None, ""},
+		{"{% if !user.admin %}user{% else %}{% if count == 2 %}two{% end %}{% end %}", None, "two"},
+		{"{% for i in items %}{{ i.name }}: {{ i.price }}; {% end %}", None, "pen: 2.5; book: 5; "},
+		{"{% for p in ports %}{% for q in ports %}{{ p + q }} {% end %}{% end %}", None, "160 523 523 886 "},
+		{"{% for x in empty %}x{% end %}{% for x in none %}x{% end %}", None, ""},
+		{"{% for count in ports %}{{ count }} {% end %}{{ count }}", None, "80 443 2"},
+		{
+			"server {\n{% for p in ports %}\n  {% if p > 100 %}\n  listen {{ p }} ssl;\n  {% else %}\n  listen {{ p }};\n  {% end %}\n{% end %}\n}\n",
+			None,
+			"server {\n  listen 80;\n  listen 443 ssl;\n}\n",
+		},
+		{"<ul>\n  {% for i in items %}\n  <li>{{ i.name }}</li>\n  {% end %}\n</ul>", HTML, "<ul>\n  <li>pen</li>\n  <li>book</li>\n</ul>"},
+		{"a {% if true %}b{% end %} c", None, "a b c"},
+	}
+
+	ctx := newContext()
+	for _, test := range tests {
+		t.Run(test.text, func(t *testing.T) {
+			tmpl, err := Parse("t", test.text, test.escape)
+			if err != nil {
+				t.Fatal(err)
+			}
+			res, err := tmpl.ExecuteString(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res != test.expected {
+				t.Errorf("Expected %q but got %q", test.expected, res)
+			}
+		})
+	}
+}
+
+func TestExecuteErrors(t *testing.T) {
+	tests := []struct {
+		text string
+		err  string
+	}{
+		{"Hello\n  {{ user.email }}", "t:2:6: email not defined"},
+		{"{{ user }}", "t:1:4: Cannot convert *goexp.context to String"},
+		{"{% if count %}x{% end %}", "t:1:7: Invalid condition: expected a Boolean but got types.Integer"},
+		{"{% if nope %}x{% end %}", "t:1:7: nope not defined"},
+		{"{% for x in count %}x{% end %}", "t:1:13: Cannot iterate over types.Integer"},
+		{"{% for i in items %}\n{{ i.name }} {{ i.name - 1 }}\n{% end %}", `t:2:17: Operation "-" not supported for types types.String and types.Integer`},
+	}
+
+	ctx := newContext()
+	for _, test := range tests {
+		t.Run(test.text, func(t *testing.T) {
+			tmpl, err := Parse("t", test.text, None)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var sb strings.Builder
+			err = tmpl.Execute(&sb, ctx)
+			if err == nil {
+				t.Fatalf("Expected error %q but got %q", test.err, sb.String())
+			}
+			if err.Error() != test.err {
+				t.Errorf("Expected error %q but got %q", test.err, err)
+			}
+			var e Error
+			if !errors.As(err, &e) || e.Name != "t" || e.Err == nil {
+				t.Errorf("Expected an Error but got %#v", err)
+			}
+		})
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, fmt.Errorf("disk full")
+}
+
+func TestExecuteWriteError(t *testing.T) {
+	tmpl, err := Parse("t", "a{{ 1 }}", None)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tmpl.Execute(failingWriter{}, newContext()); err == nil || err.Error() != "disk full" {
+		t.Errorf("Expected the error of the writer but got %v", err)
+	}
+}
+
+// TestConcurrentExecute executes a shared template from several goroutines
+func TestConcurrentExecute(t *testing.T) {
+	tmpl, err := Parse("t", "{% for i in items %}{{ i.name }} x {{ n }}; {% end %}", HTML)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	errs := make([]error, 8)
+	for g := range errs {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			ctx := goexp.NewEvalContext(newContext())
+			ctx.AddName("n", types.Integer(g))
+			for i := 0; i < 100; i++ {
+				res, err := tmpl.ExecuteString(ctx)
+				if err == nil && res != fmt.Sprintf("pen x %d; book x %d; ", g, g) {
+					err = fmt.Errorf("Unexpected result %q", res)
+				}
+				if err != nil {
+					errs[g] = err
+					return
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			t.Error(err)
+		}
+	}
+}
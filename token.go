@@ -1,5 +1,7 @@
 package goexp
 
+import "fmt"
+
 type TokenType int
 
 const (
@@ -14,12 +16,16 @@ const (
 	RightBrace   // }
 	Comma        // ,
 	Period       // .
+	Colon        // :
+	Semicolon    // ;
+	Assign       // =
 	Add          // +
 	Sub          // -
 	Mul          // *
 	Div          // /
 	Modulo       // %
 	Power        // **
+	Arrow        // =>
 
 	And // &&
 	Or  // ||
@@ -37,12 +43,63 @@ const (
 	String     // "abc"
 	Integer    // 123
 	Float      // 12.34
+	Template   // `Hello ${name}`
 
 	True
 	False
 	Nil
+	Let
 )
 
+var tokenNames = [...]string{
+	Unknown:      "Unknown",
+	EOF:          "EOF",
+	LeftParen:    "LeftParen",
+	RightParen:   "RightParen",
+	LeftBracket:  "LeftBracket",
+	RightBracket: "RightBracket",
+	LeftBrace:    "LeftBrace",
+	RightBrace:   "RightBrace",
+	Comma:        "Comma",
+	Period:       "Period",
+	Colon:        "Colon",
+	Semicolon:    "Semicolon",
+	Assign:       "Assign",
+	Add:          "Add",
+	Sub:          "Sub",
+	Mul:          "Mul",
+	Div:          "Div",
+	Modulo:       "Modulo",
+	Power:        "Power",
+	Arrow:        "Arrow",
+	And:          "And",
+	Or:           "Or",
+	Not:          "Not",
+	Xor:          "Xor",
+	NotEqual:     "NotEqual",
+	Equal:        "Equal",
+	Greater:      "Greater",
+	GreaterEqual: "GreaterEqual",
+	Less:         "Less",
+	LessEqual:    "LessEqual",
+	Identifier:   "Identifier",
+	String:       "String",
+	Integer:      "Integer",
+	Float:        "Float",
+	Template:     "Template",
+	True:         "True",
+	False:        "False",
+	Nil:          "Nil",
+	Let:          "Let",
+}
+
+func (t TokenType) String() string {
+	if t >= 0 && int(t) < len(tokenNames) {
+		return tokenNames[t]
+	}
+	return fmt.Sprintf("TokenType(%d)", int(t))
+}
+
 type Token struct {
 	Type    TokenType
 	Lexeme  string
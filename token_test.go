@@ -1 +1,18 @@
 package goexp
+
+import "testing"
+
+func TestTokenTypeString(t *testing.T) {
+	tests := map[TokenType]string{
+		EOF:            "EOF",
+		GreaterEqual:   "GreaterEqual",
+		Nil:            "Nil",
+		TokenType(-1):  "TokenType(-1)",
+		TokenType(999): "TokenType(999)",
+	}
+	for tt, expected := range tests {
+		if actual := tt.String(); actual != expected {
+			t.Errorf("Expected %s but got %s", expected, actual)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package goexp
+
+import (
+	"strconv"
+	"strings"
+)
+
+/*
+Tracer is notified before and after the evaluation of every node of an expression.
+
+The nodes are entered in evaluation order and every Enter is matched by an Exit
+with the value of the node or the error of its evaluation, so the calls nest
+like the tree of the expression.
+*/
+type Tracer interface {
+	Enter(expr Expr)
+	Exit(expr Expr, value interface{}, err error)
+}
+
+// EvalWithTracer evaluates the expression and reports every evaluated node to the tracer
+func EvalWithTracer(expr Expr, context Context, tracer Tracer) (interface{}, error) {
+	in := newInterpreter(context)
+	in.tracer = tracer
+	return in.eval(expr)
+}
+
+// MultiTracer returns a tracer that notifies all the given tracers in order
+func MultiTracer(tracers ...Tracer) Tracer {
+	return multiTracer(tracers)
+}
+
+type multiTracer []Tracer
+
+func (m multiTracer) Enter(expr Expr) {
+	for _, t := range m {
+		t.Enter(expr)
+	}
+}
+
+func (m multiTracer) Exit(expr Expr, value interface{}, err error) {
+	for _, t := range m {
+		t.Exit(expr, value, err)
+	}
+}
+
+// Trace holds the values of the nodes of an expression recorded by EvalTrace
+type Trace struct {
+	values map[string]traceValue
+	path   []int
+	next   []int
+}
+
+type traceValue struct {
+	value interface{}
+	err   error
+}
+
+// EvalTrace evaluates the expression and records the value of every evaluated node
+func EvalTrace(expr Expr, context Context) (interface{}, *Trace, error) {
+	t := NewTrace()
+	res, err := EvalWithTracer(expr, context, t)
+	return res, t, err
+}
+
+// NewTrace returns an empty trace to be used as a Tracer
+func NewTrace() *Trace {
+	return &Trace{values: map[string]traceValue{}, next: []int{0}}
+}
+
+// Enter implements Tracer
+func (t *Trace) Enter(expr Expr) {
+	// nodes are identified by the indices of the children on the path from the root
+	level := len(t.next) - 1
+	t.path = append(t.path, t.next[level])
+	t.next[level]++
+	t.next = append(t.next, 0)
+}
+
+// Exit implements Tracer
+func (t *Trace) Exit(expr Expr, value interface{}, err error) {
+	t.values[pathKey(t.path)] = traceValue{value, err}
+	t.path = t.path[:len(t.path)-1]
+	t.next = t.next[:len(t.next)-1]
+}
+
+// lookup returns the value recorded for the node at the given path
+func (t *Trace) lookup(path []int) (traceValue, bool) {
+	if t == nil {
+		return traceValue{}, false
+	}
+	v, ok := t.values[pathKey(path)]
+	return v, ok
+}
+
+func pathKey(path []int) string {
+	parts := make([]string, len(path))
+	for i, x := range path {
+		parts[i] = strconv.Itoa(x)
+	}
+	return strings.Join(parts, ".")
+}
@@ -17,3 +17,14 @@ func (b Boolean) Or(other Boolean) Boolean {
 func (b Boolean) Not() Boolean {
 	return Boolean(!bool(b))
 }
+
+// Equals returns true if the other value is the same Boolean
+func (b Boolean) Equals(other interface{}) (bool, error) {
+	if x, ok := other.(Boolean); ok {
+		return b == x, nil
+	}
+	if IsNull(other) {
+		return false, nil
+	}
+	return false, notSupportedOperationError("==", b, other)
+}
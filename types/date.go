@@ -1,13 +1,13 @@
 package types
 
 import (
-	"fmt"
 	"time"
 )
 
-// Date in ms since 1.1.1970 00:00.00
+// Date is a point in time
 type Date time.Time
 
+// Add returns the date moved forward by a Duration or an Integer number of nanoseconds
 func (date Date) Add(other interface{}) (interface{}, error) {
 	switch other.(type) {
 	case Integer:
@@ -19,6 +19,50 @@ func (date Date) Add(other interface{}) (interface{}, error) {
 		return Date(time.Time(date).Add(time.Duration(n))), nil
 
 	default:
-		return nil, fmt.Errorf("Add operation not supported for types %T and %T", date, other)
+		return nil, notSupportedOperationError("+", date, other)
 	}
 }
+
+// Sub returns the Duration between the current and the other date, or the date
+// moved back by a Duration or an Integer number of nanoseconds
+func (date Date) Sub(other interface{}) (interface{}, error) {
+	switch x := other.(type) {
+	case Date:
+		return Duration(time.Time(date).Sub(time.Time(x))), nil
+	case Integer:
+		return Date(time.Time(date).Add(-time.Duration(x))), nil
+	case Duration:
+		return Date(time.Time(date).Add(-time.Duration(x))), nil
+	default:
+		return nil, notSupportedOperationError("-", date, other)
+	}
+}
+
+// Equals returns true if the other value is the same point in time, regardless of its location
+func (date Date) Equals(other interface{}) (bool, error) {
+	if x, ok := other.(Date); ok {
+		return time.Time(date).Equal(time.Time(x)), nil
+	}
+	if IsNull(other) {
+		return false, nil
+	}
+	return false, notSupportedOperationError("==", date, other)
+}
+
+// Compare returns -1, 0 or 1 if the current date is before, the same as or after the other date
+func (date Date) Compare(other interface{}) (int, error) {
+	if x, ok := other.(Date); ok {
+		switch t, u := time.Time(date), time.Time(x); {
+		case t.Before(u):
+			return -1, nil
+		case t.After(u):
+			return 1, nil
+		}
+		return 0, nil
+	}
+	return 0, notSupportedOperationError("cmp", date, other)
+}
+
+func (date Date) String() string {
+	return time.Time(date).Format(time.RFC3339Nano)
+}
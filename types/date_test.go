@@ -1 +1,58 @@
 package types
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDateOps(t *testing.T) {
+	d := Date(time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC))
+	tests := []struct {
+		name     string
+		op       func() (interface{}, error)
+		expected interface{}
+		err      error
+	}{
+		{"d + 1h", func() (interface{}, error) { return d.Add(Duration(time.Hour)) }, Date(time.Date(2024, 3, 1, 13, 0, 0, 0, time.UTC)), nil},
+		{"d + 1", func() (interface{}, error) { return d.Add(Integer(1)) }, Date(time.Date(2024, 3, 1, 12, 0, 0, 1, time.UTC)), nil},
+		{"d - 24h", func() (interface{}, error) { return d.Sub(Duration(24 * time.Hour)) }, Date(time.Date(2024, 2, 29, 12, 0, 0, 0, time.UTC)), nil},
+		{"d - d", func() (interface{}, error) {
+			return d.Sub(Date(time.Date(2024, 3, 1, 11, 30, 0, 0, time.UTC)))
+		}, Duration(30 * time.Minute), nil},
+		{"d + 'a'", func() (interface{}, error) { return d.Add(String("a")) }, nil, NotSupportedError{"+", d, String("a")}},
+		{"d - 1.5", func() (interface{}, error) { return d.Sub(Float(1.5)) }, nil, NotSupportedError{"-", d, Float(1.5)}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			res, err := test.op()
+			if !reflect.DeepEqual(err, test.err) {
+				t.Fatalf("Expected error %v but got %v", test.err, err)
+			}
+			if !reflect.DeepEqual(res, test.expected) {
+				t.Errorf("Expected %v but got %v", test.expected, res)
+			}
+		})
+	}
+}
+
+func TestDateCompare(t *testing.T) {
+	utc := Date(time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC))
+	local := Date(time.Date(2024, 3, 1, 14, 0, 0, 0, time.FixedZone("EET", 2*60*60)))
+	if equals, err := utc.Equals(local); !equals || err != nil {
+		t.Errorf("Expected true but got %t, %v", equals, err)
+	}
+	if equals, err := utc.Equals(Null()); equals || err != nil {
+		t.Errorf("Expected false but got %t, %v", equals, err)
+	}
+	if cmp, err := utc.Compare(Date(time.Time(utc).Add(time.Second))); cmp != -1 || err != nil {
+		t.Errorf("Expected -1 but got %d, %v", cmp, err)
+	}
+	if _, err := utc.Compare(Integer(1)); err == nil {
+		t.Error("Expected an error")
+	}
+	if s := local.String(); s != "2024-03-01T14:00:00+02:00" {
+		t.Errorf("Unexpected string %s", s)
+	}
+}
@@ -1,4 +1,54 @@
 package types
 
-// Duration in ms
+import "time"
+
+// Duration is the time elapsed between two dates in nanoseconds, like time.Duration
 type Duration int64
+
+// Add returns the sum of the current and the other Duration, or the other Date moved forward by the current Duration
+func (d Duration) Add(other interface{}) (interface{}, error) {
+	switch x := other.(type) {
+	case Duration:
+		return d + x, nil
+	case Date:
+		return x.Add(d)
+	default:
+		return nil, notSupportedOperationError("+", d, other)
+	}
+}
+
+// Sub returns the difference of the current and the other Duration
+func (d Duration) Sub(other interface{}) (interface{}, error) {
+	if x, ok := other.(Duration); ok {
+		return d - x, nil
+	}
+	return nil, notSupportedOperationError("-", d, other)
+}
+
+// Negate returns the current Duration with the opposite sign
+func (d Duration) Negate() (interface{}, error) {
+	return -d, nil
+}
+
+// Equals returns true if the other value is an equal Duration
+func (d Duration) Equals(other interface{}) (bool, error) {
+	if x, ok := other.(Duration); ok {
+		return d == x, nil
+	}
+	if IsNull(other) {
+		return false, nil
+	}
+	return false, notSupportedOperationError("==", d, other)
+}
+
+// Compare returns -1, 0 or 1 if the current Duration is shorter than, equal to or longer than the other one
+func (d Duration) Compare(other interface{}) (int, error) {
+	if x, ok := other.(Duration); ok {
+		return compareInt64(int64(d), int64(x)), nil
+	}
+	return 0, notSupportedOperationError("cmp", d, other)
+}
+
+func (d Duration) String() string {
+	return time.Duration(d).String()
+}
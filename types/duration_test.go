@@ -1 +1,51 @@
 package types
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDurationOps(t *testing.T) {
+	d := Duration(time.Hour)
+	tests := []struct {
+		name     string
+		op       func() (interface{}, error)
+		expected interface{}
+		err      error
+	}{
+		{"1h + 30m", func() (interface{}, error) { return d.Add(Duration(30 * time.Minute)) }, Duration(90 * time.Minute), nil},
+		{"1h + date", func() (interface{}, error) { return d.Add(Date(time.Unix(0, 0).UTC())) }, Date(time.Unix(3600, 0).UTC()), nil},
+		{"1h - 2h", func() (interface{}, error) { return d.Sub(Duration(2 * time.Hour)) }, Duration(-time.Hour), nil},
+		{"-1h", func() (interface{}, error) { return d.Negate() }, Duration(-time.Hour), nil},
+		{"1h + 1", func() (interface{}, error) { return d.Add(Integer(1)) }, nil, NotSupportedError{"+", d, Integer(1)}},
+		{"1h - 1", func() (interface{}, error) { return d.Sub(Integer(1)) }, nil, NotSupportedError{"-", d, Integer(1)}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			res, err := test.op()
+			if !reflect.DeepEqual(err, test.err) {
+				t.Fatalf("Expected error %v but got %v", test.err, err)
+			}
+			if !reflect.DeepEqual(res, test.expected) {
+				t.Errorf("Expected %v but got %v", test.expected, res)
+			}
+		})
+	}
+}
+
+func TestDurationCompare(t *testing.T) {
+	if cmp, err := Duration(1).Compare(Duration(2)); cmp != -1 || err != nil {
+		t.Errorf("Expected -1 but got %d, %v", cmp, err)
+	}
+	if equals, err := Duration(2).Equals(Duration(2)); !equals || err != nil {
+		t.Errorf("Expected true but got %t, %v", equals, err)
+	}
+	if _, err := Duration(2).Equals(Integer(2)); err == nil {
+		t.Error("Expected an error")
+	}
+	if s := Duration(90 * time.Minute).String(); s != "1h30m0s" {
+		t.Errorf("Unexpected string %s", s)
+	}
+}
@@ -2,6 +2,16 @@ package types
 
 import "fmt"
 
+// NotSupportedError is returned when an operation is not supported for the types of its operands
+type NotSupportedError struct {
+	Op   string
+	X, Y interface{}
+}
+
+func (err NotSupportedError) Error() string {
+	return fmt.Sprintf(`Operation "%s" not supported for %T and %T`, err.Op, err.X, err.Y)
+}
+
 func notSupportedOperationError(op string, x, y interface{}) error {
-	return fmt.Errorf(`Operation "%s" not supported for %T and %T`, op, x, y)
+	return NotSupportedError{op, x, y}
 }
@@ -8,6 +8,58 @@ func NewFloat(value float64) Float {
 	return Float(value)
 }
 
+// toFloat64 converts Integer and Float values to float64
+func toFloat64(value interface{}) (float64, bool) {
+	switch value.(type) {
+	case Integer:
+		return float64(value.(Integer)), true
+	case Float:
+		return float64(value.(Float)), true
+	default:
+		return 0, false
+	}
+}
+
+// Add returns the sum of the current and the other value
+func (f Float) Add(other interface{}) (interface{}, error) {
+	if y, ok := toFloat64(other); ok {
+		return Float(float64(f) + y), nil
+	}
+	return nil, notSupportedOperationError("+", f, other)
+}
+
+// Sub returns the difference of the current and the other value
+func (f Float) Sub(other interface{}) (interface{}, error) {
+	if y, ok := toFloat64(other); ok {
+		return Float(float64(f) - y), nil
+	}
+	return nil, notSupportedOperationError("-", f, other)
+}
+
+// Mul returns the product of the current and the other value
+func (f Float) Mul(other interface{}) (interface{}, error) {
+	if y, ok := toFloat64(other); ok {
+		return Float(float64(f) * y), nil
+	}
+	return nil, notSupportedOperationError("*", f, other)
+}
+
+// Div returns the quotient of the current and the other value
+func (f Float) Div(other interface{}) (interface{}, error) {
+	if y, ok := toFloat64(other); ok {
+		return Float(float64(f) / y), nil
+	}
+	return nil, notSupportedOperationError("/", f, other)
+}
+
+// Mod returns the floating-point remainder of the current and the other value
+func (f Float) Mod(other interface{}) (interface{}, error) {
+	if y, ok := toFloat64(other); ok {
+		return Float(math.Mod(float64(f), y)), nil
+	}
+	return nil, notSupportedOperationError("%", f, other)
+}
+
 func (f Float) Power(other interface{}) (interface{}, error) {
 	x := float64(f)
 	switch other.(type) {
@@ -23,3 +75,38 @@ func (f Float) Power(other interface{}) (interface{}, error) {
 		return nil, notSupportedOperationError("**", x, other)
 	}
 }
+
+// Negate returns the current value with the opposite sign
+func (f Float) Negate() (interface{}, error) {
+	return Float(-float64(f)), nil
+}
+
+// Equals returns true if the other value is a number equal to the current value
+func (f Float) Equals(other interface{}) (bool, error) {
+	if y, ok := toFloat64(other); ok {
+		return float64(f) == y, nil
+	}
+	if IsNull(other) {
+		return false, nil
+	}
+	return false, notSupportedOperationError("==", f, other)
+}
+
+// Compare returns -1, 0 or 1 if the current value is less than, equal to or greater than the other value
+func (f Float) Compare(other interface{}) (int, error) {
+	if y, ok := toFloat64(other); ok {
+		return compareFloat64(float64(f), y), nil
+	}
+	return 0, notSupportedOperationError("cmp", f, other)
+}
+
+func compareFloat64(x, y float64) int {
+	switch {
+	case x < y:
+		return -1
+	case x > y:
+		return 1
+	default:
+		return 0
+	}
+}
@@ -1 +1,51 @@
 package types
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFloatOps(t *testing.T) {
+	tests := []struct {
+		name     string
+		op       func() (interface{}, error)
+		expected interface{}
+		err      error
+	}{
+		{"1.5 + 2", func() (interface{}, error) { return Float(1.5).Add(Integer(2)) }, Float(3.5), nil},
+		{"1.5 + 0.5", func() (interface{}, error) { return Float(1.5).Add(Float(0.5)) }, Float(2), nil},
+		{"1.5 - 2", func() (interface{}, error) { return Float(1.5).Sub(Integer(2)) }, Float(-0.5), nil},
+		{"1.5 * 2", func() (interface{}, error) { return Float(1.5).Mul(Integer(2)) }, Float(3), nil},
+		{"1.5 / 0.5", func() (interface{}, error) { return Float(1.5).Div(Float(0.5)) }, Float(3), nil},
+		{"5.5 % 2", func() (interface{}, error) { return Float(5.5).Mod(Integer(2)) }, Float(1.5), nil},
+		{"-1.5", func() (interface{}, error) { return Float(1.5).Negate() }, Float(-1.5), nil},
+		{"1.5 + 'a'", func() (interface{}, error) { return Float(1.5).Add(String("a")) }, nil, NotSupportedError{"+", Float(1.5), String("a")}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			res, err := test.op()
+			if !reflect.DeepEqual(err, test.err) {
+				t.Fatalf("Expected error %v but got %v", test.err, err)
+			}
+			if !reflect.DeepEqual(res, test.expected) {
+				t.Errorf("Expected %#v but got %#v", test.expected, res)
+			}
+		})
+	}
+}
+
+func TestFloatCompare(t *testing.T) {
+	if cmp, err := Float(1.5).Compare(Integer(2)); cmp != -1 || err != nil {
+		t.Errorf("Expected -1 but got %d, %v", cmp, err)
+	}
+	if cmp, err := Float(2).Compare(Float(2)); cmp != 0 || err != nil {
+		t.Errorf("Expected 0 but got %d, %v", cmp, err)
+	}
+	if equals, err := Float(2).Equals(Integer(2)); !equals || err != nil {
+		t.Errorf("Expected true but got %t, %v", equals, err)
+	}
+	if _, err := Float(2).Compare(String("2")); err == nil {
+		t.Error("Expected an error")
+	}
+}
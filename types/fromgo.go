@@ -0,0 +1,51 @@
+package types
+
+import (
+	"math"
+	"reflect"
+	"time"
+)
+
+/*
+FromGo converts a Go value to the corresponding value of the types package:
+
+	nil                        Null
+	int, int8, ..., int64      Integer
+	uint, uint8, ..., uintptr  Integer, or Float above math.MaxInt64
+	float32, float64           Float
+	string                     String
+	bool                       Boolean
+	time.Time                  Date
+	time.Duration              Duration
+
+Types with one of these kinds as underlying kind are converted by their kind.
+The values of the types package and the other values are returned as they are.
+*/
+func FromGo(x interface{}) interface{} {
+	switch v := x.(type) {
+	case nil:
+		return Null()
+	case Integer, Float, String, Boolean, Date, Duration:
+		return x
+	case time.Time:
+		return Date(v)
+	case time.Duration:
+		return Duration(v)
+	}
+	switch rv := reflect.ValueOf(x); rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return Integer(rv.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if n := rv.Uint(); n <= math.MaxInt64 {
+			return Integer(n)
+		}
+		return Float(rv.Uint())
+	case reflect.Float32, reflect.Float64:
+		return Float(rv.Float())
+	case reflect.String:
+		return String(rv.String())
+	case reflect.Bool:
+		return Boolean(rv.Bool())
+	}
+	return x
+}
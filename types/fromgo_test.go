@@ -0,0 +1,45 @@
+package types
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type level int
+
+func TestFromGo(t *testing.T) {
+	now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	list := []int{1}
+	tests := []struct {
+		value    interface{}
+		expected interface{}
+	}{
+		{nil, Null()},
+		{Null(), Null()},
+		{42, Integer(42)},
+		{int8(-8), Integer(-8)},
+		{level(3), Integer(3)},
+		{uint16(7), Integer(7)},
+		{uint64(math.MaxUint64), Float(math.MaxUint64)},
+		{float32(0.5), Float(0.5)},
+		{"go", String("go")},
+		{true, Boolean(true)},
+		{now, Date(now)},
+		{time.Minute, Duration(time.Minute)},
+		{Integer(1), Integer(1)},
+		{Duration(time.Second), Duration(time.Second)},
+		{Date(now), Date(now)},
+		{list, list},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("%T %v", test.value, test.value), func(t *testing.T) {
+			if res := FromGo(test.value); !reflect.DeepEqual(res, test.expected) {
+				t.Errorf("Expected %#v but got %#v", test.expected, res)
+			}
+		})
+	}
+}
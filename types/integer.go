@@ -1,12 +1,15 @@
 package types
 
 import (
+	"errors"
 	"fmt"
 	"math"
 )
 
 type Integer int64
 
+var errDivisionByZero = errors.New("Division by zero")
+
 func NewInteger(value int64) Integer {
 	return Integer(value)
 }
@@ -22,12 +25,27 @@ func (n Integer) Add(other interface{}) (interface{}, error) {
 		value := other.(Float)
 		return Float(float64(x) + float64(value)), nil
 	case String:
-		return fmt.Sprintf("%d%s", n, other), nil
+		return String(fmt.Sprintf("%d%s", x, other)), nil
 	default:
 		return nil, notSupportedOperationError("+", n, other)
 	}
 }
 
+// Sub returns the difference of the current and the other value
+func (n Integer) Sub(other interface{}) (interface{}, error) {
+	x := int64(n)
+	switch other.(type) {
+	case Integer:
+		y := int64(other.(Integer))
+		return Integer(x - y), nil
+	case Float:
+		y := float64(other.(Float))
+		return Float(float64(x) - y), nil
+	default:
+		return nil, notSupportedOperationError("-", n, other)
+	}
+}
+
 // Mul returns the product of the current and the other value
 func (n Integer) Mul(other interface{}) (interface{}, error) {
 	x := int64(n)
@@ -36,7 +54,7 @@ func (n Integer) Mul(other interface{}) (interface{}, error) {
 		y := int64(other.(Integer))
 		return Integer(x * y), nil
 	case Float:
-		y := float64(other.(float64))
+		y := float64(other.(Float))
 		return Float(float64(x) * y), nil
 	default:
 		return nil, notSupportedOperationError("*", n, other)
@@ -48,15 +66,36 @@ func (n Integer) Div(other interface{}) (interface{}, error) {
 	switch other.(type) {
 	case Integer:
 		y := int64(other.(Integer))
+		if y == 0 {
+			return nil, errDivisionByZero
+		}
 		return Integer(x / y), nil
 	case Float:
-		y := float64(other.(float64))
+		y := float64(other.(Float))
 		return Float(float64(x) / y), nil
 	default:
 		return nil, notSupportedOperationError("/", n, other)
 	}
 }
 
+// Mod returns the remainder of the division of the current and the other value
+func (n Integer) Mod(other interface{}) (interface{}, error) {
+	x := int64(n)
+	switch other.(type) {
+	case Integer:
+		y := int64(other.(Integer))
+		if y == 0 {
+			return nil, errDivisionByZero
+		}
+		return Integer(x % y), nil
+	case Float:
+		y := float64(other.(Float))
+		return Float(math.Mod(float64(x), y)), nil
+	default:
+		return nil, notSupportedOperationError("%", n, other)
+	}
+}
+
 func (n Integer) Power(other interface{}) (interface{}, error) {
 	x := float64(int64(n))
 	switch other.(type) {
@@ -71,6 +110,49 @@ func (n Integer) Power(other interface{}) (interface{}, error) {
 	}
 }
 
+// Negate returns the current value with the opposite sign
+func (n Integer) Negate() (interface{}, error) {
+	return Integer(-int64(n)), nil
+}
+
+// Equals returns true if the other value is a number equal to the current value
+func (n Integer) Equals(other interface{}) (bool, error) {
+	switch other.(type) {
+	case Integer:
+		return n == other.(Integer), nil
+	case Float:
+		return float64(n) == float64(other.(Float)), nil
+	default:
+		if IsNull(other) {
+			return false, nil
+		}
+		return false, notSupportedOperationError("==", n, other)
+	}
+}
+
+// Compare returns -1, 0 or 1 if the current value is less than, equal to or greater than the other value
+func (n Integer) Compare(other interface{}) (int, error) {
+	switch other.(type) {
+	case Integer:
+		return compareInt64(int64(n), int64(other.(Integer))), nil
+	case Float:
+		return compareFloat64(float64(n), float64(other.(Float))), nil
+	default:
+		return 0, notSupportedOperationError("cmp", n, other)
+	}
+}
+
 func (n Integer) String() string {
 	return fmt.Sprintf("Integer(%d)", int64(n))
 }
+
+func compareInt64(x, y int64) int {
+	switch {
+	case x < y:
+		return -1
+	case x > y:
+		return 1
+	default:
+		return 0
+	}
+}
@@ -1 +1,84 @@
 package types
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestIntegerOps(t *testing.T) {
+	tests := []struct {
+		name     string
+		op       func() (interface{}, error)
+		expected interface{}
+		err      error
+	}{
+		{"7 + 2", func() (interface{}, error) { return Integer(7).Add(Integer(2)) }, Integer(9), nil},
+		{"7 + 0.5", func() (interface{}, error) { return Integer(7).Add(Float(0.5)) }, Float(7.5), nil},
+		{"7 + 'a'", func() (interface{}, error) { return Integer(7).Add(String("a")) }, String("7a"), nil},
+		{"7 - 2", func() (interface{}, error) { return Integer(7).Sub(Integer(2)) }, Integer(5), nil},
+		{"7 - 0.5", func() (interface{}, error) { return Integer(7).Sub(Float(0.5)) }, Float(6.5), nil},
+		{"7 * 2", func() (interface{}, error) { return Integer(7).Mul(Integer(2)) }, Integer(14), nil},
+		{"7 * 0.5", func() (interface{}, error) { return Integer(7).Mul(Float(0.5)) }, Float(3.5), nil},
+		{"7 / 2", func() (interface{}, error) { return Integer(7).Div(Integer(2)) }, Integer(3), nil},
+		{"7 / 0.5", func() (interface{}, error) { return Integer(7).Div(Float(0.5)) }, Float(14), nil},
+		{"7 / 0", func() (interface{}, error) { return Integer(7).Div(Integer(0)) }, nil, errDivisionByZero},
+		{"7 % 2", func() (interface{}, error) { return Integer(7).Mod(Integer(2)) }, Integer(1), nil},
+		{"7 % 0", func() (interface{}, error) { return Integer(7).Mod(Integer(0)) }, nil, errDivisionByZero},
+		{"7 % 2.5", func() (interface{}, error) { return Integer(7).Mod(Float(2.5)) }, Float(2), nil},
+		{"-7", func() (interface{}, error) { return Integer(7).Negate() }, Integer(-7), nil},
+		{"7 - true", func() (interface{}, error) { return Integer(7).Sub(Boolean(true)) }, nil, NotSupportedError{"-", Integer(7), Boolean(true)}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			res, err := test.op()
+			if !reflect.DeepEqual(err, test.err) {
+				t.Fatalf("Expected error %v but got %v", test.err, err)
+			}
+			if !reflect.DeepEqual(res, test.expected) {
+				t.Errorf("Expected %#v but got %#v", test.expected, res)
+			}
+		})
+	}
+}
+
+func TestIntegerCompare(t *testing.T) {
+	tests := []struct {
+		x        Integer
+		y        interface{}
+		cmp      int
+		equals   bool
+		hasError bool
+	}{
+		{1, Integer(2), -1, false, false},
+		{2, Integer(2), 0, true, false},
+		{3, Integer(2), 1, false, false},
+		{2, Float(2.5), -1, false, false},
+		{2, Float(2), 0, true, false},
+		{2, String("2"), 0, false, true},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("%d cmp %v", test.x, test.y), func(t *testing.T) {
+			cmp, err := test.x.Compare(test.y)
+			if (err != nil) != test.hasError {
+				t.Fatalf("Unexpected error %v", err)
+			}
+			if cmp != test.cmp {
+				t.Errorf("Expected %d but got %d", test.cmp, cmp)
+			}
+			equals, err := test.x.Equals(test.y)
+			if (err != nil) != test.hasError {
+				t.Fatalf("Unexpected error %v", err)
+			}
+			if equals != test.equals {
+				t.Errorf("Expected %t but got %t", test.equals, equals)
+			}
+		})
+	}
+
+	if equals, err := Integer(1).Equals(Null()); equals || err != nil {
+		t.Errorf("Expected Integer not to equal null but got %t, %v", equals, err)
+	}
+}
@@ -11,3 +11,8 @@ func Null() interface{} {
 func IsNull(value interface{}) bool {
 	return value == null
 }
+
+// Equals returns true if the other value is null as well
+func (n *NullType) Equals(other interface{}) (bool, error) {
+	return IsNull(other), nil
+}
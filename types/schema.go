@@ -0,0 +1,342 @@
+package types
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Kind is the kind of a Type
+type Kind int
+
+const (
+	// AnyKind is the kind of values whose type is not known until evaluation
+	AnyKind Kind = iota
+	IntegerKind
+	FloatKind
+	// NumberKind is either an Integer or a Float
+	NumberKind
+	StringKind
+	BooleanKind
+	NilKind
+	DateKind
+	DurationKind
+	ObjectKind
+	ListKind
+	MapKind
+)
+
+var kindNames = [...]string{
+	AnyKind:      "Any",
+	IntegerKind:  "Integer",
+	FloatKind:    "Float",
+	NumberKind:   "Number",
+	StringKind:   "String",
+	BooleanKind:  "Boolean",
+	NilKind:      "Nil",
+	DateKind:     "Date",
+	DurationKind: "Duration",
+	ObjectKind:   "Object",
+	ListKind:     "List",
+	MapKind:      "Map",
+}
+
+func (k Kind) String() string {
+	if k >= 0 && int(k) < len(kindNames) {
+		return kindNames[k]
+	}
+	return fmt.Sprintf("Kind(%d)", int(k))
+}
+
+/*
+Type describes the values a name, a method or an expression may have.
+
+Objects have named fields and methods, lists have elements of type Elem and
+maps have string keys and values of type Elem.
+*/
+type Type struct {
+	Kind    Kind
+	Elem    *Type
+	Fields  map[string]*Type
+	Methods map[string]*Signature
+}
+
+// Predefined scalar types
+var (
+	AnyType      = &Type{Kind: AnyKind}
+	IntegerType  = &Type{Kind: IntegerKind}
+	FloatType    = &Type{Kind: FloatKind}
+	NumberType   = &Type{Kind: NumberKind}
+	StringType   = &Type{Kind: StringKind}
+	BooleanType  = &Type{Kind: BooleanKind}
+	NilType      = &Type{Kind: NilKind}
+	DateType     = &Type{Kind: DateKind}
+	DurationType = &Type{Kind: DurationKind}
+)
+
+// ListOf returns the type of lists of elem
+func ListOf(elem *Type) *Type {
+	return &Type{Kind: ListKind, Elem: elem}
+}
+
+// MapOf returns the type of maps with string keys and values of type elem
+func MapOf(elem *Type) *Type {
+	return &Type{Kind: MapKind, Elem: elem}
+}
+
+// ObjectOf returns the type of objects with the given fields and methods
+func ObjectOf(fields map[string]*Type, methods map[string]*Signature) *Type {
+	return &Type{Kind: ObjectKind, Fields: fields, Methods: methods}
+}
+
+// String returns the name of the type, e.g. "List<Integer>" or "Object{age, name}"
+func (t *Type) String() string {
+	if t == nil {
+		return AnyKind.String()
+	}
+	switch t.Kind {
+	case ListKind, MapKind:
+		return t.Kind.String() + "<" + t.Elem.String() + ">"
+	case ObjectKind:
+		names := make([]string, 0, len(t.Fields))
+		for name := range t.Fields {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return "Object{" + strings.Join(names, ", ") + "}"
+	}
+	return t.Kind.String()
+}
+
+// AssignableTo reports whether a value of type t may be used where a value of type u is expected
+func (t *Type) AssignableTo(u *Type) bool {
+	if t == nil || u == nil || t.Kind == AnyKind || u.Kind == AnyKind {
+		return true
+	}
+	switch u.Kind {
+	case NumberKind:
+		return t.Kind == IntegerKind || t.Kind == FloatKind || t.Kind == NumberKind
+	case ListKind, MapKind:
+		return t.Kind == u.Kind && t.Elem.AssignableTo(u.Elem)
+	case ObjectKind:
+		if t.Kind != ObjectKind {
+			return false
+		}
+		for name, field := range u.Fields {
+			if f, ok := t.Fields[name]; !ok || !f.AssignableTo(field) {
+				return false
+			}
+		}
+		return true
+	}
+	return t.Kind == u.Kind
+}
+
+// Field returns the type of the member name of objects and maps; the member
+// of a list is the list of the members of its elements
+func (t *Type) Field(name string) (*Type, bool) {
+	switch {
+	case t == nil || t.Kind == AnyKind:
+		return AnyType, true
+	case t.Kind == MapKind:
+		return orAny(t.Elem), true
+	case t.Kind == ListKind:
+		f, ok := t.Elem.Field(name)
+		if !ok {
+			return nil, false
+		}
+		return ListOf(f), true
+	case t.Kind == ObjectKind:
+		f, ok := t.Fields[name]
+		return orAny(f), ok
+	}
+	return nil, false
+}
+
+// Method returns the signature of the method name of objects
+func (t *Type) Method(name string) (*Signature, bool) {
+	if t == nil || t.Kind != ObjectKind {
+		return nil, false
+	}
+	m, ok := t.Methods[name]
+	return m, ok && m != nil
+}
+
+/*
+Samples returns a value of every scalar type t may have; the values are used
+to infer the result of operations by applying the operations of this package.
+Any has samples of all scalar types; objects, lists and maps have none.
+*/
+func (t *Type) Samples() []interface{} {
+	switch kind := t.kindOrAny(); kind {
+	case AnyKind:
+		var res []interface{}
+		for _, k := range []Kind{IntegerKind, FloatKind, StringKind, BooleanKind, NilKind, DateKind, DurationKind} {
+			res = append(res, sample(k))
+		}
+		return res
+	case NumberKind:
+		return []interface{}{sample(IntegerKind), sample(FloatKind)}
+	case ObjectKind, ListKind, MapKind:
+		return nil
+	default:
+		return []interface{}{sample(kind)}
+	}
+}
+
+func (t *Type) kindOrAny() Kind {
+	if t == nil {
+		return AnyKind
+	}
+	return t.Kind
+}
+
+func sample(kind Kind) interface{} {
+	switch kind {
+	case IntegerKind:
+		return Integer(1)
+	case FloatKind:
+		return Float(1)
+	case StringKind:
+		return String("a")
+	case BooleanKind:
+		return Boolean(true)
+	case NilKind:
+		return Null()
+	case DateKind:
+		return Date(time.Unix(0, 0).UTC())
+	case DurationKind:
+		return Duration(1)
+	}
+	return nil
+}
+
+// TypeOf returns the type of the given value; values of unknown types are Any
+func TypeOf(value interface{}) *Type {
+	switch value.(type) {
+	case Integer:
+		return IntegerType
+	case Float:
+		return FloatType
+	case String:
+		return StringType
+	case Boolean, bool:
+		return BooleanType
+	case *NullType:
+		return NilType
+	case Date:
+		return DateType
+	case Duration:
+		return DurationType
+	}
+	return AnyType
+}
+
+// Union returns the narrowest type that is a supertype of all given types
+func Union(ts ...*Type) *Type {
+	var res *Type
+	for _, t := range ts {
+		switch {
+		case res == nil:
+			res = t
+		case t.AssignableTo(res) && t.kindOrAny() != AnyKind:
+			// res already covers t
+		case res.AssignableTo(t) && res.kindOrAny() != AnyKind:
+			res = t
+		case isNumeric(res) && isNumeric(t):
+			res = NumberType
+		default:
+			return AnyType
+		}
+	}
+	if res == nil {
+		return AnyType
+	}
+	return res
+}
+
+func isNumeric(t *Type) bool {
+	k := t.kindOrAny()
+	return k == IntegerKind || k == FloatKind || k == NumberKind
+}
+
+func orAny(t *Type) *Type {
+	if t == nil {
+		return AnyType
+	}
+	return t
+}
+
+// Signature describes the parameters and the result of a method.
+// If Variadic is set the last parameter may be repeated any number of times.
+type Signature struct {
+	Params   []*Type
+	Variadic bool
+	Returns  *Type
+
+	// Optional is the number of the trailing parameters that may be omitted
+	Optional int
+
+	// Names are the names of the parameters for named arguments; named
+	// arguments of methods without names are not checked
+	Names []string
+
+	// Overloads are the alternative signatures of the method; a call is
+	// checked against the first signature that accepts its arguments
+	Overloads []*Signature
+}
+
+// MinArgs returns the minimum number of arguments of the method
+func (s *Signature) MinArgs() int {
+	n := len(s.Params) - s.Optional
+	if s.Variadic {
+		n--
+	}
+	if n < 0 {
+		return 0
+	}
+	return n
+}
+
+// Accepts reports whether the method may be called with n arguments
+func (s *Signature) Accepts(n int) bool {
+	if s.Variadic {
+		return n >= s.MinArgs()
+	}
+	return n >= s.MinArgs() && n <= len(s.Params)
+}
+
+// Param returns the type of the i-th argument
+func (s *Signature) Param(i int) *Type {
+	if s.Variadic && i >= len(s.Params)-1 && len(s.Params) > 0 {
+		return orAny(s.Params[len(s.Params)-1])
+	}
+	if i < len(s.Params) {
+		return orAny(s.Params[i])
+	}
+	return AnyType
+}
+
+// Result returns the type of the result of the method; methods without a declared result return Any
+func (s *Signature) Result() *Type {
+	return orAny(s.Returns)
+}
+
+// Schema declares the names and the methods available to expressions
+type Schema struct {
+	Names   map[string]*Type
+	Methods map[string]*Signature
+
+	// Docs are the descriptions of the names and the methods by their dotted
+	// paths, e.g. "user.address" or "len"; they are not used by Check
+	Docs map[string]string
+}
+
+// Type returns the schema as the type of the object whose fields are the names
+func (s *Schema) Type() *Type {
+	if s == nil {
+		return ObjectOf(nil, nil)
+	}
+	return ObjectOf(s.Names, s.Methods)
+}
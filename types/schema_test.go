@@ -0,0 +1,103 @@
+package types
+
+import "testing"
+
+func TestTypeString(t *testing.T) {
+	tests := []struct {
+		t        *Type
+		expected string
+	}{
+		{IntegerType, "Integer"},
+		{nil, "Any"},
+		{ListOf(StringType), "List<String>"},
+		{MapOf(ListOf(FloatType)), "Map<List<Float>>"},
+		{ObjectOf(map[string]*Type{"name": StringType, "age": IntegerType}, nil), "Object{age, name}"},
+		{&Type{Kind: 42}, "Kind(42)"},
+	}
+
+	for _, test := range tests {
+		if s := test.t.String(); s != test.expected {
+			t.Errorf("Expected %s but got %s", test.expected, s)
+		}
+	}
+}
+
+func TestAssignableTo(t *testing.T) {
+	user := ObjectOf(map[string]*Type{"name": StringType, "age": IntegerType}, nil)
+	tests := []struct {
+		x, y     *Type
+		expected bool
+	}{
+		{IntegerType, IntegerType, true},
+		{IntegerType, FloatType, false},
+		{IntegerType, NumberType, true},
+		{FloatType, NumberType, true},
+		{NumberType, IntegerType, false},
+		{StringType, AnyType, true},
+		{AnyType, BooleanType, true},
+		{ListOf(IntegerType), ListOf(NumberType), true},
+		{ListOf(StringType), ListOf(NumberType), false},
+		{MapOf(StringType), ListOf(StringType), false},
+		{user, ObjectOf(map[string]*Type{"name": StringType}, nil), true},
+		{ObjectOf(map[string]*Type{"name": StringType}, nil), user, false},
+		{user, StringType, false},
+	}
+
+	for _, test := range tests {
+		if res := test.x.AssignableTo(test.y); res != test.expected {
+			t.Errorf("%s assignable to %s: expected %v but got %v", test.x, test.y, test.expected, res)
+		}
+	}
+}
+
+func TestUnion(t *testing.T) {
+	tests := []struct {
+		ts       []*Type
+		expected *Type
+	}{
+		{nil, AnyType},
+		{[]*Type{IntegerType, IntegerType}, IntegerType},
+		{[]*Type{IntegerType, FloatType}, NumberType},
+		{[]*Type{FloatType, NumberType}, NumberType},
+		{[]*Type{IntegerType, StringType}, AnyType},
+		{[]*Type{BooleanType, AnyType}, AnyType},
+	}
+
+	for _, test := range tests {
+		if res := Union(test.ts...); res.String() != test.expected.String() {
+			t.Errorf("Union(%v): expected %s but got %s", test.ts, test.expected, res)
+		}
+	}
+}
+
+func TestTypeOfSamples(t *testing.T) {
+	for _, kind := range []Kind{IntegerKind, FloatKind, StringKind, BooleanKind, NilKind, DateKind, DurationKind} {
+		samples := (&Type{Kind: kind}).Samples()
+		if len(samples) != 1 || TypeOf(samples[0]).Kind != kind {
+			t.Errorf("Expected a sample of %s but got %v", kind, samples)
+		}
+	}
+	if n := len(NumberType.Samples()); n != 2 {
+		t.Errorf("Expected 2 samples of Number but got %d", n)
+	}
+	if samples := ListOf(IntegerType).Samples(); samples != nil {
+		t.Errorf("Expected no samples of lists but got %v", samples)
+	}
+}
+
+func TestSignature(t *testing.T) {
+	max := &Signature{Params: []*Type{NumberType, NumberType}, Variadic: true, Returns: NumberType}
+	for n, expected := range []bool{false, true, true, true} {
+		if max.Accepts(n) != expected {
+			t.Errorf("Accepts(%d): expected %v", n, expected)
+		}
+	}
+	if p := max.Param(5); p != NumberType {
+		t.Errorf("Expected Number but got %s", p)
+	}
+
+	now := &Signature{}
+	if !now.Accepts(0) || now.Accepts(1) || now.Result() != AnyType {
+		t.Errorf("Unexpected signature %v", now)
+	}
+}
@@ -18,7 +18,10 @@ func (this String) String() string {
 func (this String) Add(other interface{}) (res interface{}, err error) {
 	switch other.(type) {
 	case Integer, Float, String, Boolean:
-		res = String(fmt.Sprintf("%s%s", this, other))
+		var str string
+		if str, err = ToString(other); err == nil {
+			res = this + String(str)
+		}
 	default:
 		if IsNull(other) {
 			res = this
@@ -48,7 +51,9 @@ func (s String) Equals(other interface{}) (res bool, err error) {
 		str := other.(String)
 		res = compare(s, str) == 0
 	default:
-		err = fmt.Errorf("Cannot compare String and %T", other)
+		if !IsNull(other) {
+			err = fmt.Errorf("Cannot compare String and %T", other)
+		}
 	}
 	return
 }
@@ -0,0 +1,93 @@
+package types
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Stringer is implemented by values that define their own text representation
+// for ToString, e.g. in template strings
+type Stringer interface {
+	ToString() (string, error)
+}
+
+/*
+ToString returns the text representation of a value, as opposed to String()
+which describes the value for debugging, e.g. "Integer(5)":
+
+	Integer   5
+	Float     2.5, 1e+21
+	String    the string itself
+	Boolean   true, false
+	nil       the empty string
+	Date      2024-05-01T10:00:00Z
+	Duration  1h30m0s
+	List      [1, 2, 3]
+
+Values implementing Stringer or fmt.Stringer are converted by their methods,
+other Go numbers, strings, booleans, times and slices like the corresponding values.
+*/
+func ToString(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case Stringer:
+		return v.ToString()
+	case Integer:
+		return strconv.FormatInt(int64(v), 10), nil
+	case Float:
+		return formatFloat(float64(v)), nil
+	case String:
+		return string(v), nil
+	case Boolean:
+		return strconv.FormatBool(bool(v)), nil
+	case Date:
+		return v.String(), nil
+	case Duration:
+		return v.String(), nil
+	case time.Time:
+		return Date(v).String(), nil
+	case nil:
+		return "", nil
+	}
+	if IsNull(value) {
+		return "", nil
+	}
+	if s, ok := value.(fmt.Stringer); ok {
+		return s.String(), nil
+	}
+
+	switch v := reflect.ValueOf(value); v.Kind() {
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return formatFloat(v.Float()), nil
+	case reflect.Slice, reflect.Array:
+		items := make([]string, v.Len())
+		for i := range items {
+			s, err := ToString(v.Index(i).Interface())
+			if err != nil {
+				return "", err
+			}
+			items[i] = s
+		}
+		return "[" + strings.Join(items, ", ") + "]", nil
+	}
+	return "", fmt.Errorf("Cannot convert %T to String", value)
+}
+
+// formatFloat formats the number without an exponent unless it is very large or very small
+func formatFloat(f float64) string {
+	if abs := math.Abs(f); abs != 0 && (abs < 1e-6 || abs >= 1e21) {
+		return strconv.FormatFloat(f, 'g', -1, 64)
+	}
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
@@ -0,0 +1,81 @@
+package types
+
+import (
+	"fmt"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type celsius float64
+
+func (c celsius) ToString() (string, error) {
+	return fmt.Sprintf("%.1f°C", float64(c)), nil
+}
+
+func TestToString(t *testing.T) {
+	tests := []struct {
+		value    interface{}
+		expected string
+		err      error
+	}{
+		{Integer(-5), "-5", nil},
+		{Float(2.5), "2.5", nil},
+		{Float(1e20), "100000000000000000000", nil},
+		{Float(1e21), "1e+21", nil},
+		{Float(1e-7), "1e-07", nil},
+		{Float(0), "0", nil},
+		{String("abc"), "abc", nil},
+		{Boolean(true), "true", nil},
+		{Null(), "", nil},
+		{nil, "", nil},
+		{Date(time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)), "2024-05-01T10:00:00Z", nil},
+		{time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC), "2024-05-01T10:00:00Z", nil},
+		{Duration(90 * time.Minute), "1h30m0s", nil},
+		{42, "42", nil},
+		{uint8(7), "7", nil},
+		{float32(0.5), "0.5", nil},
+		{"go", "go", nil},
+		{false, "false", nil},
+		{[]interface{}{Integer(1), String("a"), nil}, "[1, a, ]", nil},
+		{[2]int{1, 2}, "[1, 2]", nil},
+		{celsius(21.55), "21.6°C", nil},
+		{net.IPv4(10, 0, 0, 1), "10.0.0.1", nil},
+		{time.Second, "1s", nil},
+		{struct{}{}, "", fmt.Errorf("Cannot convert struct {} to String")},
+		{[]interface{}{map[string]int{}}, "", fmt.Errorf("Cannot convert map[string]int to String")},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("%T %v", test.value, test.value), func(t *testing.T) {
+			res, err := ToString(test.value)
+			if !reflect.DeepEqual(err, test.err) {
+				t.Fatalf("Expected error %v but got %v", test.err, err)
+			}
+			if res != test.expected {
+				t.Errorf("Expected %q but got %q", test.expected, res)
+			}
+		})
+	}
+}
+
+func TestStringAdd(t *testing.T) {
+	tests := []struct {
+		other    interface{}
+		expected interface{}
+	}{
+		{Integer(5), String("n=5")},
+		{Float(0.5), String("n=0.5")},
+		{Boolean(false), String("n=false")},
+		{String("x"), String("n=x")},
+		{Null(), String("n=")},
+	}
+
+	for _, test := range tests {
+		res, err := String("n=").Add(test.other)
+		if err != nil || res != test.expected {
+			t.Errorf("%v: expected %v but got %v, %v", test.other, test.expected, res, err)
+		}
+	}
+}
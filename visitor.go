@@ -20,4 +20,8 @@ type Visitor interface {
 	VisitCallExpr(e CallExpr, context VisitorContext) (interface{}, error)
 	VisitIdentifierExpr(e IdentifierExpr, context VisitorContext) (interface{}, error)
 	VisitGroupingExpr(e GroupingExpr, context VisitorContext) (interface{}, error)
+	VisitLambdaExpr(e LambdaExpr, context VisitorContext) (interface{}, error)
+	VisitNamedArgExpr(e NamedArgExpr, context VisitorContext) (interface{}, error)
+	VisitLetExpr(e LetExpr, context VisitorContext) (interface{}, error)
+	VisitTemplateExpr(e TemplateExpr, context VisitorContext) (interface{}, error)
 }